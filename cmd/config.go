@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	cconfig "subscriptions/internal/config"
+)
+
+// mainUsage describes the command line of the service.
+const mainUsage = `usage: subscriptions [flags] [command]
+
+Without a command, the HTTP server is started.
+
+commands:
+  migrate <command>   manage the database schema, see "subscriptions migrate"
+  config print        print the effective configuration with the secrets redacted
+  rollup rebuild      rebuild the monthly spend rollup, see "subscriptions rollup"
+
+Every setting is read, in order of precedence, from its flag, its environment variable,
+the configuration file or its default. A setting can also be read from the file named
+by the variable with the _FILE suffix, e.g. POSTGRES_PASSWORD_FILE.
+
+flags:
+`
+
+// configUsage describes the config command.
+const configUsage = `usage: subscriptions [flags] config print
+`
+
+// usage prints the usage of the service and its flags.
+func usage() {
+	fmt.Fprint(flag.CommandLine.Output(), mainUsage)
+	flag.PrintDefaults()
+}
+
+// runConfig executes the config command with the given arguments and returns the exit code of the process.
+func runConfig(source *cconfig.Source, args []string) int {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprint(os.Stderr, configUsage)
+		return 2
+	}
+
+	config, err := source.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize config:", err)
+		return 1
+	}
+
+	if err = config.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to print config:", err)
+		return 1
+	}
+
+	return 0
+}
@@ -1,29 +1,47 @@
 // @title Subscriptions API
 // @version 1.0
 // @description This is a service for managing subscriptions.
-// @host localhost:8081
 // @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 package main
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"flag"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
 
-	"subscriptions/internal/api/handlers"
+	aauth "subscriptions/internal/auth"
+	ccache "subscriptions/internal/cache"
 	cconfig "subscriptions/internal/config"
+	ccontract "subscriptions/internal/contract"
+	ddeprecation "subscriptions/internal/deprecation"
+	eevents "subscriptions/internal/events"
+	ggraph "subscriptions/internal/graph"
+	ggrpcserver "subscriptions/internal/grpcserver"
+	hhealth "subscriptions/internal/health"
 	llogger "subscriptions/internal/logger"
+	mmetrics "subscriptions/internal/metrics"
+	rratelimit "subscriptions/internal/ratelimit"
+	rrbac "subscriptions/internal/rbac"
+	rrollup "subscriptions/internal/rollup"
+	sscheduler "subscriptions/internal/scheduler"
+	sserver "subscriptions/internal/server"
+	sservice "subscriptions/internal/service"
 	ppostgresClient "subscriptions/internal/storage/postgresClient"
+	ttracing "subscriptions/internal/tracing"
+	wwebhooks "subscriptions/internal/webhooks"
 )
 
 const (
@@ -40,7 +58,28 @@ func main() {
 	)
 	defer cancel()
 
-	config, err := cconfig.New(pathToConfigFile)
+	source := cconfig.NewSource(pathToConfigFile)
+	source.RegisterFlags(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+
+	if args := flag.Args(); len(args) > 0 {
+		code := 2
+		switch args[0] {
+		case "migrate":
+			code = runMigrate(ctx, source, args[1:])
+		case "config":
+			code = runConfig(source, args[1:])
+		case "rollup":
+			code = runRollup(ctx, source, args[1:])
+		default:
+			flag.Usage()
+		}
+		cancel()
+		os.Exit(code)
+	}
+
+	config, err := source.Load()
 	if err != nil {
 		log.Fatal("failed to initialize config", err)
 	}
@@ -50,54 +89,204 @@ func main() {
 		log.Fatal("failed to initialize logger", err)
 	}
 
+	tracing, err := ttracing.New(ctx, &config.Tracing, logger)
+	if err != nil {
+		log.Fatal("failed to initialize tracing", err)
+	}
+
 	postgresClient, err := ppostgresClient.New(ctx, &config.Postgres, logger, pathToMigrationsFile)
 	if err != nil {
 		log.Fatal("failed to initialize postgres client", err)
 	}
 
-	router := chi.NewRouter()
+	aggregates, err := ccache.New(&config.Cache)
+	if err != nil {
+		log.Fatal("failed to initialize cache", err)
+	}
+
+	// The observers are set before the background workers start, so that none of their queries and changes is missed.
+	postgresClient.SetChangeObserver(aggregates)
+
+	var metrics *mmetrics.Metrics
+	if config.Metrics.Enabled {
+		metrics = mmetrics.New(postgresClient, logger)
+		postgresClient.SetQueryObserver(metrics)
+		aggregates.SetObserver(metrics)
+	}
+
+	var scheduler *sscheduler.Scheduler
+	if config.Scheduler.Enabled {
+		notifier, err := sscheduler.NewNotifier(&config.Scheduler, logger)
+		if err != nil {
+			log.Fatal("failed to initialize notifier", err)
+		}
+
+		scheduler = sscheduler.New(&config.Scheduler, postgresClient, notifier, logger)
+		scheduler.Start(ctx)
+		logger.Info("started reminder scheduler")
+	}
+
+	var dispatcher *wwebhooks.Dispatcher
+	if config.Webhooks.Enabled {
+		dispatcher = wwebhooks.New(&config.Webhooks, postgresClient, logger)
+		dispatcher.Start(ctx)
+		logger.Info("started webhook dispatcher")
+	}
+
+	var rollups ppostgresClient.RollupClient
+	var refresher *rrollup.Refresher
+	if config.Rollup.Enabled {
+		rollups = postgresClient
+		refresher = rrollup.New(&config.Rollup, postgresClient, logger)
+		refresher.Start(ctx)
+		logger.Info("started rollup refresher")
+	}
+
+	broker := eevents.New(&config.Events, postgresClient, logger)
+	broker.Start(ctx)
+
+	// Without the dispatcher nothing fans the events out to the webhooks, so they are pruned all the same.
+	pruner := eevents.NewPruner(&config.Events, postgresClient, !config.Webhooks.Enabled, logger)
+	pruner.Start(ctx)
+
+	deprecations, err := ddeprecation.New(&config.Deprecation)
+	if err != nil {
+		log.Fatal("failed to initialize deprecations", err)
+	}
+
+	validator, err := ccontract.New(&config.OpenAPI, config.HttpServer.MaxBodyBytes, logger)
+	if err != nil {
+		log.Fatal("failed to initialize openapi validator", err)
+	}
+
+	authenticator, err := aauth.New(&config.Auth, postgresClient, logger)
+	if err != nil {
+		log.Fatal("failed to initialize authenticator", err)
+	}
+
+	authorizer, err := rrbac.New(&config.RBAC, postgresClient, logger)
+	if err != nil {
+		log.Fatal("failed to initialize authorizer", err)
+	}
+
+	limiter, err := rratelimit.New(&config.RateLimit, postgresClient, logger)
+	if err != nil {
+		log.Fatal("failed to initialize rate limiter", err)
+	}
 
-	router.Use(middleware.RequestID)
-	router.Use(middleware.RealIP)
-	router.Use(llogger.MiddlewareLogger(logger, &config.Logger))
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
+	health := hhealth.New(&config.Health, postgresClient, logger)
 
-	router.Post("/subscriptions", handlers.AddSubscriptionHandler(logger, postgresClient))
-	router.Delete("/subscriptions/{id}", handlers.DeleteSubscriptionHandler(logger, postgresClient))
-	router.Get("/subscriptions/{id}", handlers.GetSubscriptionHandler(logger, postgresClient))
-	router.Get("/subscriptions", handlers.ListSubscriptionsHandler(logger, postgresClient))
-	router.Put("/subscriptions/{id}", handlers.UpdateSubscriptionHandler(logger, postgresClient))
-	router.Get("/subscriptions/total", handlers.TotalPriceHandler(logger, postgresClient))
+	graphHandler, err := ggraph.New(&config.GraphQL, postgresClient, aggregates, rollups, logger)
+	if err != nil {
+		log.Fatal("failed to initialize graphql handler", err)
+	}
+
+	router := newRouter(config, &routes{
+		postgresClient: postgresClient,
+		aggregates:     aggregates,
+		rollups:        rollups,
+		broker:         broker,
+		metrics:        metrics,
+		health:         health,
+		deprecations:   deprecations,
+		contract:       validator,
+		authenticator:  authenticator,
+		authorizer:     authorizer,
+		limiter:        limiter,
+		graph:          graphHandler,
+	}, logger)
 
-	server := http.Server{
-		Addr:    fmt.Sprintf("%s:%d", config.HttpServer.Host, config.HttpServer.Port),
-		Handler: router,
+	server, err := sserver.New(&config.HttpServer, router, logger)
+	if err != nil {
+		log.Fatal("failed to initialize http server", err)
 	}
 
-	go func() {
-		logger.Info("starting http server", zap.String("addr", server.Addr))
-		if err = server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.Fatal("cannot start http server", zap.Error(err))
+	server.Start()
+
+	var grpcServer *ggrpcserver.Server
+	if config.GRPC.Enabled {
+		guard := &ggrpcserver.Guard{
+			Authenticator: authenticator,
+			Authorizer:    authorizer,
+			Limiter:       limiter,
+			Tenant:        &config.Tenant,
 		}
-	}()
+
+		var observers []ggrpcserver.CallObserver
+		if metrics != nil {
+			observers = append(observers, metrics)
+		}
+
+		grpcServer = ggrpcserver.New(&config.GRPC, sservice.NewSubscriptions(postgresClient).WithCache(aggregates).WithRollups(rollups), guard, logger, observers...)
+		grpcServer.Start()
+	}
 
 	<-ctx.Done()
 
 	logger.Info("received shutdown signal")
 
+	logger.Info("failing readiness to drain traffic")
+	health.Shutdown()
+
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shoutdownTime)
 	defer shutdownCancel()
 
+	logger.Info("closing event streams")
+	broker.Stop()
+
+	// Both servers drain their requests in parallel within the same shutdown timeout.
+	var wg sync.WaitGroup
+	var grpcErr error
+
+	if grpcServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			logger.Info("shutting down grpc server")
+			grpcErr = grpcServer.Shutdown(shutdownCtx)
+		}()
+	}
+
 	logger.Info("shutting down http server")
-	if err = server.Shutdown(shutdownCtx); err != nil {
+	err = server.Shutdown(shutdownCtx)
+
+	wg.Wait()
+
+	if grpcErr != nil {
+		logger.Error("cannot shutdown grpc server gracefully", zap.Error(grpcErr))
+	}
+
+	if err != nil {
 		logger.Error("cannot shutdown http server", zap.Error(err))
 		return
 	}
 
+	if scheduler != nil {
+		logger.Info("stopping reminder scheduler")
+		scheduler.Stop()
+	}
+
+	if dispatcher != nil {
+		logger.Info("stopping webhook dispatcher")
+		dispatcher.Stop()
+	}
+
+	if refresher != nil {
+		logger.Info("stopping rollup refresher")
+		refresher.Stop()
+	}
+
+	logger.Info("stopping event pruner")
+	pruner.Stop()
+
 	postgresClient.Close()
 
-	logger.Info("stopping http server", zap.String("addr", server.Addr))
+	if err = tracing.Shutdown(shutdownCtx); err != nil {
+		logger.Error("cannot flush traces", zap.Error(err))
+	}
+
+	logger.Info("stopping http server", zap.String("addr", server.Addr()))
 
 	logger.Info("application shutdown completed successfully")
 }
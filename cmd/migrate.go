@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	cconfig "subscriptions/internal/config"
+	llogger "subscriptions/internal/logger"
+	ppostgresClient "subscriptions/internal/storage/postgresClient"
+)
+
+// migrateUsage describes the migrate command.
+const migrateUsage = `usage: subscriptions [flags] migrate <command>
+
+commands:
+  up           apply all pending migrations
+  down [N]     roll back the latest N migrations (default 1)
+  to N         migrate up or down to version N
+  version      print the current schema version
+  force N      set the schema version to N without migrating, clearing the dirty flag
+`
+
+// runMigrate executes the migrate command with the given arguments and returns the exit code of the process.
+func runMigrate(ctx context.Context, source *cconfig.Source, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	config, err := source.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize config:", err)
+		return 1
+	}
+
+	logger, err := llogger.New(&config.Logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize logger:", err)
+		return 1
+	}
+
+	migrator, err := ppostgresClient.NewMigrator(ctx, &config.Postgres, pathToMigrationsFile, logger)
+	if err != nil {
+		logger.Error("failed to initialize migrator", zap.Error(err))
+		return 1
+	}
+	defer migrator.Close()
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		err = migrator.Up(ctx)
+	case args[0] == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				break
+			}
+		}
+
+		err = migrator.Down(ctx, steps)
+	case args[0] == "to" && len(args) == 2:
+		var version uint64
+		if version, err = strconv.ParseUint(args[1], 10, 0); err != nil {
+			break
+		}
+
+		err = migrator.To(ctx, uint(version))
+	case args[0] == "force" && len(args) == 2:
+		var version int
+		if version, err = strconv.Atoi(args[1]); err != nil {
+			break
+		}
+
+		err = migrator.Force(ctx, version)
+	case args[0] == "version" && len(args) == 1:
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if err != nil {
+		logger.Error("migration failed", zap.String("command", args[0]), zap.Error(err))
+		return 1
+	}
+
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		logger.Error("failed to read schema version", zap.Error(err))
+		return 1
+	}
+
+	fmt.Printf("version %d", version)
+	if dirty {
+		fmt.Print(" (dirty)")
+	}
+	fmt.Println()
+
+	return 0
+}
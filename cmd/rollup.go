@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	cconfig "subscriptions/internal/config"
+	llogger "subscriptions/internal/logger"
+	rrollup "subscriptions/internal/rollup"
+	ppostgresClient "subscriptions/internal/storage/postgresClient"
+	ttenant "subscriptions/internal/tenant"
+)
+
+// rollupUsage describes the rollup command.
+const rollupUsage = `usage: subscriptions [flags] rollup rebuild [TENANT]
+
+Recomputes the monthly spend rollup from scratch through ROLLUP_HORIZON months after the current one,
+for the given tenant or for all of them. Changes of the subscriptions wait until the rollup is rebuilt.
+`
+
+// runRollup executes the rollup command with the given arguments and returns the exit code of the process.
+func runRollup(ctx context.Context, source *cconfig.Source, args []string) int {
+	if len(args) == 0 || len(args) > 2 || args[0] != "rebuild" {
+		fmt.Fprint(os.Stderr, rollupUsage)
+		return 2
+	}
+
+	tenantID := ttenant.All
+	if len(args) == 2 {
+		tenantID = args[1]
+		if !ttenant.Valid(tenantID) {
+			fmt.Fprintln(os.Stderr, "invalid tenant id:", tenantID)
+			return 2
+		}
+	}
+
+	config, err := source.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize config:", err)
+		return 1
+	}
+
+	logger, err := llogger.New(&config.Logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize logger:", err)
+		return 1
+	}
+
+	postgresClient, err := ppostgresClient.New(ctx, &config.Postgres, logger, pathToMigrationsFile)
+	if err != nil {
+		logger.Error("failed to initialize postgres client", zap.Error(err))
+		return 1
+	}
+	defer postgresClient.Close()
+
+	refresher := rrollup.New(&config.Rollup, postgresClient, logger)
+
+	rows, err := refresher.Rebuild(ttenant.WithTenant(ctx, tenantID))
+	if err != nil {
+		logger.Error("rollup rebuild failed", zap.String("tenant_id", tenantID), zap.Error(err))
+		return 1
+	}
+
+	fmt.Printf("rebuilt %d rows through %s\n", rows, refresher.Through().Format(api.ISOMonthLayout))
+
+	return 0
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api/handlers"
+	aapidocs "subscriptions/internal/apidocs"
+	aauth "subscriptions/internal/auth"
+	ccache "subscriptions/internal/cache"
+	cconfig "subscriptions/internal/config"
+	ccontract "subscriptions/internal/contract"
+	ddeprecation "subscriptions/internal/deprecation"
+	eevents "subscriptions/internal/events"
+	ggraph "subscriptions/internal/graph"
+	hhealth "subscriptions/internal/health"
+	llogger "subscriptions/internal/logger"
+	mmetrics "subscriptions/internal/metrics"
+	rratelimit "subscriptions/internal/ratelimit"
+	rrbac "subscriptions/internal/rbac"
+	sserver "subscriptions/internal/server"
+	ppostgresClient "subscriptions/internal/storage/postgresClient"
+	ttenant "subscriptions/internal/tenant"
+	ttracing "subscriptions/internal/tracing"
+)
+
+// readYourWritesCookie carries the pin of a client which has written to the primary, see readYourWrites.
+const readYourWritesCookie = "read_your_writes"
+
+// routes holds the components serving the routes of the HTTP API. Metrics and rollups are optional.
+type routes struct {
+	postgresClient *ppostgresClient.PostgresService
+	aggregates     *ccache.Cache
+	rollups        ppostgresClient.RollupClient
+	broker         *eevents.Broker
+	metrics        *mmetrics.Metrics
+	health         *hhealth.Checker
+	deprecations   *ddeprecation.Deprecations
+	contract       *ccontract.Validator
+	authenticator  *aauth.Authenticator
+	authorizer     *rrbac.Authorizer
+	limiter        *rratelimit.Limiter
+	graph          *ggraph.Handler
+}
+
+// newRouter creates the router of the HTTP API.
+// Every route except the probes, the metrics and the API documentation must be documented in the OpenAPI specification,
+// which is checked by TestRoutesMatchSpec.
+func newRouter(config *cconfig.Config, routes *routes, logger *zap.Logger) chi.Router {
+	var observers []llogger.RequestObserver
+	if routes.metrics != nil {
+		observers = append(observers, routes.metrics)
+	}
+
+	router := chi.NewRouter()
+
+	router.Use(middleware.RequestID)
+	router.Use(middleware.RealIP)
+	router.Use(ttracing.Middleware)
+	router.Use(llogger.MiddlewareLogger(logger, &config.Logger, observers...))
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.URLFormat)
+	router.Use(routes.deprecations.Middleware)
+	router.Use(readYourWrites)
+
+	if routes.metrics != nil {
+		router.Method(http.MethodGet, "/metrics", routes.metrics.Handler())
+	}
+
+	router.Get("/healthz", routes.health.LivenessHandler())
+	router.Get("/readyz", routes.health.ReadinessHandler())
+
+	// URLFormat strips the extension of the path, so the specification is routed without it.
+	router.Get(strings.TrimSuffix(aapidocs.SpecPath, ".json"), aapidocs.SpecHandler(logger))
+	router.Get(aapidocs.UIPath, aapidocs.UIHandler())
+	router.Get(aapidocs.UIPath+"/*", aapidocs.UIHandler())
+
+	router.Group(func(r chi.Router) {
+		// Addresses are limited before authentication, so failed authentication attempts are limited too.
+		r.Use(routes.limiter.Limit(rratelimit.GroupAuth))
+		r.Use(routes.authenticator.Middleware)
+		r.Use(ttenant.Middleware(&config.Tenant, logger))
+		// Requests are validated against the specification only once they are limited and authenticated,
+		// so anonymous clients cannot make the service parse and validate bodies.
+		r.Use(routes.contract.Middleware)
+
+		read := routes.authorizer.Require(rrbac.ActionSubscriptionsRead)
+		create := routes.authorizer.Require(rrbac.ActionSubscriptionsCreate)
+		update := routes.authorizer.Require(rrbac.ActionSubscriptionsUpdate)
+		remove := routes.authorizer.Require(rrbac.ActionSubscriptionsDelete)
+		reports := routes.authorizer.Require(rrbac.ActionReportsRead)
+		webhooks := routes.authorizer.Require(rrbac.ActionWebhooksManage)
+		keys := routes.authorizer.Require(rrbac.ActionKeysManage)
+		roles := routes.authorizer.Require(rrbac.ActionRolesManage)
+		body := sserver.LimitBody(config.HttpServer.MaxBodyBytes, logger)
+
+		// Routes shared by every version of the API.
+		shared := func(r chi.Router) {
+			r.With(remove).Delete("/subscriptions/{id}", handlers.DeleteSubscriptionHandler(logger, routes.postgresClient))
+			r.With(read).Get("/subscriptions/events", handlers.SubscriptionEventsHandler(logger, routes.broker, routes.postgresClient))
+
+			r.With(webhooks, body).Post("/webhooks", handlers.AddWebhookHandler(logger, routes.postgresClient))
+			r.With(webhooks).Get("/webhooks", handlers.ListWebhooksHandler(logger, routes.postgresClient))
+			r.With(webhooks).Get("/webhooks/{id}", handlers.GetWebhookHandler(logger, routes.postgresClient))
+			r.With(webhooks, body).Put("/webhooks/{id}", handlers.UpdateWebhookHandler(logger, routes.postgresClient))
+			r.With(webhooks).Delete("/webhooks/{id}", handlers.DeleteWebhookHandler(logger, routes.postgresClient))
+			r.With(webhooks).Get("/webhooks/{id}/deliveries", handlers.ListWebhookDeliveriesHandler(logger, routes.postgresClient))
+
+			r.With(keys, body).Post("/keys", handlers.AddAPIKeyHandler(logger, routes.postgresClient, routes.authorizer))
+			r.With(keys).Get("/keys", handlers.ListAPIKeysHandler(logger, routes.postgresClient))
+			r.With(keys).Get("/keys/{id}", handlers.GetAPIKeyHandler(logger, routes.postgresClient))
+			r.With(keys).Delete("/keys/{id}", handlers.RevokeAPIKeyHandler(logger, routes.postgresClient))
+
+			r.With(roles).Get("/roles", handlers.ListRolesHandler(logger))
+			r.With(roles).Get("/roles/assignments", handlers.ListRoleAssignmentsHandler(logger, routes.postgresClient))
+			r.With(roles, body).Post("/roles/assignments", handlers.AddRoleAssignmentHandler(logger, routes.postgresClient))
+			r.With(roles).Delete("/roles/assignments/{subject}/{role}", handlers.DeleteRoleAssignmentHandler(logger, routes.postgresClient))
+		}
+
+		// GraphQL serves reports across users and services, so it shares the limit of the reports.
+		r.With(routes.limiter.Limit(rratelimit.GroupReports), reports, body).Post("/graphql", routes.graph.ServeHTTP)
+
+		// Reports have a limit of their own, every other route shares the default one.
+		r.Route("/v1", func(r chi.Router) {
+			r.With(routes.limiter.Limit(rratelimit.GroupReports), reports).Get("/subscriptions/total", handlers.TotalPriceHandler(logger, routes.postgresClient, routes.aggregates, routes.rollups))
+
+			r = r.With(routes.limiter.Limit(rratelimit.GroupDefault))
+
+			r.With(create, body).Post("/subscriptions", handlers.AddSubscriptionHandler(logger, routes.postgresClient))
+			r.With(read).Get("/subscriptions/{id}", handlers.GetSubscriptionHandler(logger, routes.postgresClient))
+			r.With(read).Get("/subscriptions", handlers.ListSubscriptionsHandler(logger, routes.postgresClient))
+			r.With(update, body).Put("/subscriptions/{id}", handlers.UpdateSubscriptionHandler(logger, routes.postgresClient))
+			shared(r)
+		})
+
+		r.Route("/v2", func(r chi.Router) {
+			r.With(routes.limiter.Limit(rratelimit.GroupReports), reports).Get("/subscriptions/total", handlers.TotalPriceV2Handler(logger, routes.postgresClient, routes.aggregates, routes.rollups))
+
+			r = r.With(routes.limiter.Limit(rratelimit.GroupDefault))
+
+			r.With(create, body).Post("/subscriptions", handlers.AddSubscriptionV2Handler(logger, routes.postgresClient))
+			r.With(read).Get("/subscriptions/{id}", handlers.GetSubscriptionV2Handler(logger, routes.postgresClient))
+			r.With(read).Get("/subscriptions", handlers.ListSubscriptionsV2Handler(logger, routes.postgresClient))
+			r.With(update, body).Put("/subscriptions/{id}", handlers.UpdateSubscriptionV2Handler(logger, routes.postgresClient))
+			shared(r)
+		})
+	})
+
+	return router
+}
+
+// readYourWrites lets the storage route the reads of a request to the primary once it has written, and those of the next
+// requests of the client while they carry the cookie set on the write, if read-your-writes is enabled.
+func readYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		if cookie, err := r.Cookie(readYourWritesCookie); err == nil {
+			token = cookie.Value
+		}
+
+		ctx := ppostgresClient.WithReadYourWrites(r.Context(), token, func(token string, expires time.Time) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     readYourWritesCookie,
+				Value:    token,
+				Path:     "/",
+				Expires:  expires,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		})
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
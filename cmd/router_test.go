@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"subscriptions/docs"
+	aauth "subscriptions/internal/auth"
+	cconfig "subscriptions/internal/config"
+	ccontract "subscriptions/internal/contract"
+	ddeprecation "subscriptions/internal/deprecation"
+	eevents "subscriptions/internal/events"
+	ggraph "subscriptions/internal/graph"
+	hhealth "subscriptions/internal/health"
+	rratelimit "subscriptions/internal/ratelimit"
+	rrbac "subscriptions/internal/rbac"
+)
+
+// undocumented lists the routes which are not part of the API, so are not in the OpenAPI specification.
+var undocumented = []string{"/metrics", "/openapi", "/docs", "/docs/*"}
+
+// pathParam matches the parameters of chi patterns, with their optional regular expression.
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// spec is the part of the OpenAPI specification the routes are checked against.
+type spec struct {
+	Paths map[string]map[string]struct {
+		Parameters []struct {
+			Name string `json:"name"`
+			In   string `json:"in"`
+		} `json:"parameters"`
+	} `json:"paths"`
+}
+
+// newTestRouter creates the router of the service with every component configured with its defaults and no storage,
+// which none of them uses until a request is served. Like in every test setup, the responses are validated.
+func newTestRouter(t *testing.T) chi.Router {
+	t.Helper()
+
+	config := &cconfig.Config{}
+	config.OpenAPI.Responses = ccontract.ModeLog
+	logger := zap.NewNop()
+
+	deprecations, err := ddeprecation.New(&config.Deprecation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator, err := ccontract.New(&config.OpenAPI, config.HttpServer.MaxBodyBytes, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator, err := aauth.New(&config.Auth, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorizer, err := rrbac.New(&config.RBAC, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limiter, err := rratelimit.New(&config.RateLimit, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph, err := ggraph.New(&config.GraphQL, nil, nil, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newRouter(config, &routes{
+		broker:        eevents.New(&config.Events, nil, logger),
+		health:        hhealth.New(&config.Health, nil, logger),
+		deprecations:  deprecations,
+		contract:      validator,
+		authenticator: authenticator,
+		authorizer:    authorizer,
+		limiter:       limiter,
+		graph:         graph,
+	}, logger)
+}
+
+// TestRoutesMatchSpec checks that every route of the router is documented in the OpenAPI specification
+// with the same path parameters, and that the specification documents no route the router does not serve.
+func TestRoutesMatchSpec(t *testing.T) {
+	var s spec
+	if err := json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &s); err != nil {
+		t.Fatalf("cannot parse specification: %v", err)
+	}
+
+	routed := make(map[string]bool)
+
+	err := chi.Walk(newTestRouter(t), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if slices.Contains(undocumented, route) {
+			return nil
+		}
+
+		path := pathParam.ReplaceAllString(route, "{$1}")
+		method = strings.ToLower(method)
+		routed[method+" "+path] = true
+
+		operation, ok := s.Paths[path][method]
+		if !ok {
+			t.Errorf("%s %s is not in the specification", strings.ToUpper(method), route)
+			return nil
+		}
+
+		var want, got []string
+		for _, match := range pathParam.FindAllStringSubmatch(route, -1) {
+			want = append(want, match[1])
+		}
+
+		for _, parameter := range operation.Parameters {
+			if parameter.In == "path" {
+				got = append(got, parameter.Name)
+			}
+		}
+
+		slices.Sort(want)
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s %s: specification has path parameters %v, want %v", strings.ToUpper(method), route, got, want)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, operations := range s.Paths {
+		for method := range operations {
+			if !routed[method+" "+path] {
+				t.Errorf("%s %s is in the specification but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestDocsRoutes(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+		location    string
+	}{
+		{name: "specification", path: "/openapi.json", status: http.StatusOK, contentType: "application/json"},
+		{name: "other format", path: "/openapi.yaml", status: http.StatusNotFound},
+		{name: "ui", path: "/docs", status: http.StatusMovedPermanently, location: "/docs/index.html"},
+		{name: "ui directory", path: "/docs/", status: http.StatusMovedPermanently, location: "/docs/index.html"},
+		{name: "ui index", path: "/docs/index.html", status: http.StatusOK, contentType: "text/html; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if tt.contentType != "" && w.Header().Get("Content-Type") != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", w.Header().Get("Content-Type"), tt.contentType)
+			}
+
+			if tt.location != "" && w.Header().Get("Location") != tt.location {
+				t.Errorf("Location = %q, want %q", w.Header().Get("Location"), tt.location)
+			}
+		})
+	}
+}
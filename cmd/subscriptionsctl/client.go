@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+	"subscriptions/internal/problem"
+	"subscriptions/internal/tenant"
+)
+
+// client performs the subscription operations, either through the REST API or directly on storage.
+type client interface {
+	List(ctx context.Context, userID string, serviceName string) ([]*api.Subscription, error)
+	Get(ctx context.Context, id int) (*api.Subscription, error)
+	Create(ctx context.Context, subscription *api.Subscription) (int, error)
+	Update(ctx context.Context, id int, subscription *api.Subscription) error
+	Delete(ctx context.Context, id int) error
+	Total(ctx context.Context, userID string, serviceName string, startDate string, endDate string) (int, error)
+	Close()
+}
+
+// apiClient performs the operations through the REST API of the service.
+type apiClient struct {
+	baseURL  string
+	apiKey   string
+	tenantID string
+	http     *http.Client
+}
+
+// envelope is the body of the successful responses of the REST API.
+type envelope struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// newAPIClient creates and returns a new apiClient instance for the service at baseURL.
+func newAPIClient(baseURL string, apiKey string, tenantID string) *apiClient {
+	return &apiClient{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		tenantID: tenantID,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *apiClient) List(ctx context.Context, userID string, serviceName string) ([]*api.Subscription, error) {
+	var res []*api.Subscription
+
+	err := c.do(ctx, http.MethodGet, "/v1/subscriptions", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	// The list endpoint does not filter, so the filters are applied here.
+	filtered := res[:0]
+	for _, subscription := range res {
+		if (userID == "" || subscription.UserID == userID) && (serviceName == "" || subscription.ServiceName == serviceName) {
+			filtered = append(filtered, subscription)
+		}
+	}
+
+	return filtered, nil
+}
+
+func (c *apiClient) Get(ctx context.Context, id int) (*api.Subscription, error) {
+	res := &api.Subscription{}
+
+	err := c.do(ctx, http.MethodGet, "/v1/subscriptions/"+strconv.Itoa(id), nil, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *apiClient) Create(ctx context.Context, subscription *api.Subscription) (int, error) {
+	var id int
+
+	err := c.do(ctx, http.MethodPost, "/v1/subscriptions", subscription, &id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (c *apiClient) Update(ctx context.Context, id int, subscription *api.Subscription) error {
+	return c.do(ctx, http.MethodPut, "/v1/subscriptions/"+strconv.Itoa(id), subscription, nil)
+}
+
+func (c *apiClient) Delete(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/v1/subscriptions/"+strconv.Itoa(id), nil, nil)
+}
+
+func (c *apiClient) Total(ctx context.Context, userID string, serviceName string, startDate string, endDate string) (int, error) {
+	query := url.Values{}
+	query.Set("start_date", startDate)
+	query.Set("end_date", endDate)
+	if userID != "" {
+		query.Set("user_id", userID)
+	}
+	if serviceName != "" {
+		query.Set("service_name", serviceName)
+	}
+
+	var total int
+
+	err := c.do(ctx, http.MethodGet, "/v1/subscriptions/total?"+query.Encode(), nil, &total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (c *apiClient) Close() {}
+
+// do sends the request with the JSON encoded body and decodes the data of the response into res, if it is not nil.
+func (c *apiClient) do(ctx context.Context, method string, path string, body interface{}, res interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("cannot encode request: %w", err)
+		}
+
+		reader = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("cannot create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, c.apiKey)
+	}
+	if c.tenantID != "" {
+		req.Header.Set(tenant.Header, c.tenantID)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(method, path, resp)
+	}
+
+	payload := &envelope{}
+	if err = json.NewDecoder(resp.Body).Decode(payload); err != nil {
+		return fmt.Errorf("%s %s: cannot decode response: %w", method, path, err)
+	}
+
+	if res == nil || len(payload.Data) == 0 {
+		return nil
+	}
+
+	if err = json.Unmarshal(payload.Data, res); err != nil {
+		return fmt.Errorf("%s %s: cannot decode response data: %w", method, path, err)
+	}
+
+	return nil
+}
+
+// responseError returns the error reported by the response, described by its problem details if it carries them.
+func responseError(method string, path string, resp *http.Response) error {
+	p := &problem.Problem{}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != problem.ContentType || json.NewDecoder(resp.Body).Decode(p) != nil || p.Detail == "" {
+		return fmt.Errorf("%s %s: %d %s", method, path, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	return fmt.Errorf("%s %s: %d %s (%s)", method, path, resp.StatusCode, p.Detail, p.Code)
+}
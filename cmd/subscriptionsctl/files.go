@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"subscriptions/internal/api"
+)
+
+// csvHeader is the header row of CSV import and export files.
+var csvHeader = []string{"service_name", "price", "user_id", "start_date", "end_date"}
+
+// readFile reads subscriptions from a JSON array or CSV file, depending on the extension of path.
+func readFile(path string) ([]*api.Subscription, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if isCSV(path) {
+		return readCSV(f)
+	}
+
+	var res []*api.Subscription
+	if err = json.NewDecoder(f).Decode(&res); err != nil {
+		return nil, fmt.Errorf("cannot decode %s: %w", path, err)
+	}
+
+	return res, nil
+}
+
+// writeFile writes subscriptions to a JSON array or CSV file, depending on the extension of path.
+func writeFile(path string, subscriptions []*api.Subscription) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if isCSV(path) {
+		err = writeCSV(f, subscriptions)
+	} else {
+		if subscriptions == nil {
+			subscriptions = []*api.Subscription{}
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(subscriptions)
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func isCSV(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+func readCSV(r io.Reader) ([]*api.Subscription, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	for _, name := range csvHeader[:4] {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	res := make([]*api.Subscription, 0, len(records)-1)
+	for line, record := range records[1:] {
+		price, err := strconv.Atoi(field(record, "price"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid price: %w", line+2, err)
+		}
+
+		res = append(res, &api.Subscription{
+			ServiceName: field(record, "service_name"),
+			Price:       price,
+			UserID:      field(record, "user_id"),
+			StartDate:   field(record, "start_date"),
+			EndDate:     field(record, "end_date"),
+		})
+	}
+
+	return res, nil
+}
+
+func writeCSV(w io.Writer, subscriptions []*api.Subscription) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		err := writer.Write([]string{
+			subscription.ServiceName,
+			strconv.Itoa(subscription.Price),
+			subscription.UserID,
+			subscription.StartDate,
+			subscription.EndDate,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,312 @@
+// Command subscriptionsctl manages subscriptions from the command line, either through the REST API
+// of the service or directly on its storage.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+const (
+	defaultURL            = "http://localhost:8081"
+	defaultConfigFile     = "./config/config.env"
+	defaultMigrationsPath = "file://./database/migrations"
+	defaultTenant         = "default"
+	dateLayout            = "01-2006"
+)
+
+// Connection modes.
+const (
+	modeAPI = "api"
+	modeDB  = "db"
+)
+
+const usage = `Usage: subscriptionsctl [flags] <command> [arguments]
+
+Commands:
+  list [-user ID] [-service NAME]            list subscriptions
+  get ID                                      show a subscription
+  create -service NAME -price N -user ID -start MM-YYYY [-end MM-YYYY]
+                                              create a subscription
+  update ID [-service NAME] [-price N] [-user ID] [-start MM-YYYY] [-end MM-YYYY]
+                                              change fields of a subscription
+  delete ID                                   delete a subscription
+  total -start MM-YYYY -end MM-YYYY [-user ID] [-service NAME]
+                                              total price of subscriptions over a period
+  import FILE                                 create subscriptions from a .json or .csv file
+  export FILE [-user ID] [-service NAME]      write subscriptions to a .json or .csv file
+
+Flags:
+`
+
+// errUsage reports invalid command line arguments.
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:])
+	cancel()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("subscriptionsctl", flag.ContinueOnError)
+	mode := flags.String("mode", modeAPI, "connection mode: api or db")
+	baseURL := flags.String("url", envOr("SUBSCRIPTIONS_URL", defaultURL), "base URL of the REST API (api mode)")
+	apiKey := flags.String("api-key", os.Getenv("SUBSCRIPTIONS_API_KEY"), "API key sent with requests (api mode)")
+	tenantID := flags.String("tenant", envOr("SUBSCRIPTIONS_TENANT", defaultTenant), "tenant to operate on")
+	configPath := flags.String("config", defaultConfigFile, "service configuration file (db mode)")
+	migrationsPath := flags.String("migrations", defaultMigrationsPath, "migrations source (db mode)")
+	output := flags.String("output", outputTable, "output format: table or json")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 || (*output != outputTable && *output != outputJSON) {
+		flags.Usage()
+		return 2
+	}
+
+	if !tenant.Valid(*tenantID) {
+		fmt.Fprintf(os.Stderr, "error: invalid tenant id: %q\n", *tenantID)
+		return 2
+	}
+
+	var c client
+	switch *mode {
+	case modeAPI:
+		c = newAPIClient(*baseURL, *apiKey, *tenantID)
+	case modeDB:
+		storage, err := newStorageClient(ctx, *configPath, *migrationsPath, *tenantID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return 1
+		}
+		c = storage
+	default:
+		flags.Usage()
+		return 2
+	}
+	defer c.Close()
+
+	p := &printer{out: os.Stdout, format: *output}
+
+	err := runCommand(ctx, c, p, flags.Arg(0), flags.Args()[1:])
+	if errors.Is(err, errUsage) {
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+
+	return 0
+}
+
+func runCommand(ctx context.Context, c client, p *printer, command string, args []string) error {
+	switch command {
+	case "list":
+		return runList(ctx, c, p, args)
+	case "get":
+		return runGet(ctx, c, p, args)
+	case "create":
+		return runCreate(ctx, c, p, args)
+	case "update":
+		return runUpdate(ctx, c, args)
+	case "delete":
+		return runDelete(ctx, c, args)
+	case "total":
+		return runTotal(ctx, c, p, args)
+	case "import":
+		return runImport(ctx, c, p, args)
+	case "export":
+		return runExport(ctx, c, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		return errUsage
+	}
+}
+
+func runList(ctx context.Context, c client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	userID := flags.String("user", "", "filter by user id")
+	serviceName := flags.String("service", "", "filter by service name")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	subscriptions, err := c.List(ctx, *userID, *serviceName)
+	if err != nil {
+		return err
+	}
+
+	return p.Subscriptions(subscriptions)
+}
+
+func runGet(ctx context.Context, c client, p *printer, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := c.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return p.Subscriptions([]*api.Subscription{subscription})
+}
+
+func runCreate(ctx context.Context, c client, p *printer, args []string) error {
+	subscription := &api.Subscription{}
+
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	subscriptionFlags(flags, subscription)
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	id, err := c.Create(ctx, subscription)
+	if err != nil {
+		return err
+	}
+
+	return p.Value("id", id)
+}
+
+func runUpdate(ctx context.Context, c client, args []string) error {
+	id, err := parseID(args[:min(len(args), 1)])
+	if err != nil {
+		return err
+	}
+
+	// Only the given fields are changed, the rest is taken from the stored subscription.
+	subscription, err := c.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("update", flag.ContinueOnError)
+	subscriptionFlags(flags, subscription)
+	if err = flags.Parse(args[1:]); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	return c.Update(ctx, id, subscription)
+}
+
+func runDelete(ctx context.Context, c client, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+
+	return c.Delete(ctx, id)
+}
+
+func runTotal(ctx context.Context, c client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("total", flag.ContinueOnError)
+	startDate := flags.String("start", "", "start of the period, MM-YYYY")
+	endDate := flags.String("end", "", "end of the period, MM-YYYY")
+	userID := flags.String("user", "", "filter by user id")
+	serviceName := flags.String("service", "", "filter by service name")
+	if err := flags.Parse(args); err != nil || *startDate == "" || *endDate == "" {
+		return errUsage
+	}
+
+	total, err := c.Total(ctx, *userID, *serviceName, *startDate, *endDate)
+	if err != nil {
+		return err
+	}
+
+	return p.Value("total_price", total)
+}
+
+func runImport(ctx context.Context, c client, p *printer, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	subscriptions, err := readFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int, 0, len(subscriptions))
+	for i, subscription := range subscriptions {
+		id, err := c.Create(ctx, subscription)
+		if err != nil {
+			return fmt.Errorf("subscription %d: %w (%d imported)", i+1, err, len(ids))
+		}
+
+		ids = append(ids, id)
+	}
+
+	return p.Value("ids", ids)
+}
+
+func runExport(ctx context.Context, c client, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	userID := flags.String("user", "", "filter by user id")
+	serviceName := flags.String("service", "", "filter by service name")
+	if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	subscriptions, err := c.List(ctx, *userID, *serviceName)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(args[0], subscriptions)
+}
+
+// subscriptionFlags binds the subscription fields to flags, using their current values as defaults.
+func subscriptionFlags(flags *flag.FlagSet, subscription *api.Subscription) {
+	flags.StringVar(&subscription.ServiceName, "service", subscription.ServiceName, "service name")
+	flags.IntVar(&subscription.Price, "price", subscription.Price, "monthly price")
+	flags.StringVar(&subscription.UserID, "user", subscription.UserID, "user id")
+	flags.StringVar(&subscription.StartDate, "start", subscription.StartDate, "start date, MM-YYYY")
+	flags.StringVar(&subscription.EndDate, "end", subscription.EndDate, "end date, MM-YYYY")
+}
+
+// parseID parses the single subscription id argument.
+func parseID(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errUsage
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid id %q", args[0])
+	}
+
+	return id, nil
+}
+
+func envOr(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+
+	return fallback
+}
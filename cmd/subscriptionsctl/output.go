@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"subscriptions/internal/api"
+)
+
+// Output formats.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer writes command results in the selected output format.
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+// Subscriptions prints the subscriptions.
+func (p *printer) Subscriptions(subscriptions []*api.Subscription) error {
+	if p.format == outputJSON {
+		if subscriptions == nil {
+			subscriptions = []*api.Subscription{}
+		}
+
+		return p.json(subscriptions)
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tPRICE\tUSER\tSTART\tEND")
+	for _, subscription := range subscriptions {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n",
+			subscription.ServiceName,
+			subscription.Price,
+			subscription.UserID,
+			subscription.StartDate,
+			subscription.EndDate,
+		)
+	}
+
+	return w.Flush()
+}
+
+// Value prints a single named value, such as a created id or a total price.
+func (p *printer) Value(name string, value interface{}) error {
+	if p.format == outputJSON {
+		return p.json(map[string]interface{}{name: value})
+	}
+
+	_, err := fmt.Fprintln(p.out, value)
+	return err
+}
+
+func (p *printer) json(v interface{}) error {
+	encoder := json.NewEncoder(p.out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/config"
+	"subscriptions/internal/service"
+	"subscriptions/internal/storage/postgresClient"
+	"subscriptions/internal/tenant"
+)
+
+// storageClient performs the operations directly on the storage of the service, bypassing the REST API
+// and its authorization. Every operation is bound to the tenant the client was created for; the subscriptions
+// are validated like the API does.
+type storageClient struct {
+	storage       *postgresClient.PostgresService
+	subscriptions *service.Subscriptions
+	tenantID      string
+}
+
+// newStorageClient creates and returns a new storageClient instance connected to the database given by the
+// configuration file. Migrations are never run from here, whatever the configuration says.
+func newStorageClient(ctx context.Context, configPath string, migrationsPath string, tenantID string) (*storageClient, error) {
+	cfg, err := config.New(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Postgres.AutoMigrate = false
+
+	storage, err := postgresClient.New(ctx, &cfg.Postgres, zap.NewNop(), migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to storage: %w", err)
+	}
+
+	return &storageClient{storage: storage, subscriptions: service.NewSubscriptions(storage), tenantID: tenantID}, nil
+}
+
+func (c *storageClient) List(ctx context.Context, userID string, serviceName string) ([]*api.Subscription, error) {
+	return c.storage.ListFilteredSubscriptions(c.bind(ctx), userID, serviceName)
+}
+
+func (c *storageClient) Get(ctx context.Context, id int) (*api.Subscription, error) {
+	return c.storage.GetSubscription(c.bind(ctx), id)
+}
+
+func (c *storageClient) Create(ctx context.Context, subscription *api.Subscription) (int, error) {
+	return c.subscriptions.Create(c.bind(ctx), subscription)
+}
+
+func (c *storageClient) Update(ctx context.Context, id int, subscription *api.Subscription) error {
+	return c.subscriptions.Update(c.bind(ctx), id, subscription)
+}
+
+func (c *storageClient) Delete(ctx context.Context, id int) error {
+	return c.storage.DeleteSubscription(c.bind(ctx), id)
+}
+
+func (c *storageClient) Total(ctx context.Context, userID string, serviceName string, startDate string, endDate string) (int, error) {
+	startPeriod, err := time.Parse(dateLayout, startDate)
+	if err != nil {
+		return 0, fmt.Errorf("invalid start date %q, use MM-YYYY", startDate)
+	}
+
+	endPeriod, err := time.Parse(dateLayout, endDate)
+	if err != nil {
+		return 0, fmt.Errorf("invalid end date %q, use MM-YYYY", endDate)
+	}
+
+	subscriptions, err := c.storage.ListFilteredSubscriptions(c.bind(ctx), userID, serviceName)
+	if err != nil {
+		return 0, err
+	}
+
+	return api.TotalPrice(startPeriod, endPeriod, subscriptions), nil
+}
+
+func (c *storageClient) Close() {
+	c.storage.Close()
+}
+
+// bind binds ctx to the tenant of the client.
+func (c *storageClient) bind(ctx context.Context) context.Context {
+	return tenant.WithTenant(ctx, c.tenantID)
+}
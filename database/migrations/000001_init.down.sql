@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS schema_subscriptions.subscriptions;
+DROP SCHEMA IF EXISTS schema_subscriptions;
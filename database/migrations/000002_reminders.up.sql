@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS schema_subscriptions.reminders
+(
+    subscription_id BIGINT NOT NULL REFERENCES schema_subscriptions.subscriptions (id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    due_period TEXT NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (subscription_id, kind, due_period)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS schema_subscriptions.webhook_deliveries;
+DROP TABLE IF EXISTS schema_subscriptions.outbox;
+DROP TABLE IF EXISTS schema_subscriptions.webhooks;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS schema_subscriptions.webhooks
+(
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS schema_subscriptions.outbox
+(
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    subscription_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    processed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS outbox_unprocessed_idx
+    ON schema_subscriptions.outbox (id) WHERE processed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS schema_subscriptions.webhook_deliveries
+(
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    webhook_id BIGINT NOT NULL REFERENCES schema_subscriptions.webhooks (id) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL REFERENCES schema_subscriptions.outbox (id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    status_code INT,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx
+    ON schema_subscriptions.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
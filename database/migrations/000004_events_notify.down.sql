@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS outbox_notify ON schema_subscriptions.outbox;
+DROP FUNCTION IF EXISTS schema_subscriptions.notify_subscription_event();
@@ -0,0 +1,11 @@
+CREATE OR REPLACE FUNCTION schema_subscriptions.notify_subscription_event() RETURNS trigger AS
+$$
+BEGIN
+    PERFORM pg_notify('subscription_events', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_notify
+    AFTER INSERT ON schema_subscriptions.outbox
+    FOR EACH ROW EXECUTE FUNCTION schema_subscriptions.notify_subscription_event();
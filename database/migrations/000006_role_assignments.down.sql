@@ -0,0 +1 @@
+DROP TABLE IF EXISTS schema_subscriptions.role_assignments;
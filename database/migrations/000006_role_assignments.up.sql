@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS schema_subscriptions.role_assignments
+(
+    subject TEXT NOT NULL,
+    role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (subject, role)
+);
@@ -0,0 +1,50 @@
+DROP POLICY IF EXISTS tenant_isolation ON schema_subscriptions.role_assignments;
+ALTER TABLE schema_subscriptions.role_assignments NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.role_assignments DISABLE ROW LEVEL SECURITY;
+
+-- The same role of the same subject may be assigned in several tenants, which the key without the tenant does not allow,
+-- so only the assignment of the first tenant is kept. The key including the tenant is dropped together with the column.
+DELETE FROM schema_subscriptions.role_assignments a
+USING schema_subscriptions.role_assignments b
+WHERE a.subject = b.subject AND a.role = b.role AND a.tenant_id > b.tenant_id;
+ALTER TABLE schema_subscriptions.role_assignments DROP CONSTRAINT role_assignments_pkey;
+ALTER TABLE schema_subscriptions.role_assignments DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE schema_subscriptions.role_assignments ADD PRIMARY KEY (subject, role);
+
+DROP POLICY IF EXISTS tenant_isolation ON schema_subscriptions.api_keys;
+ALTER TABLE schema_subscriptions.api_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.api_keys DISABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.api_keys DROP COLUMN IF EXISTS tenant_id;
+
+DROP POLICY IF EXISTS tenant_isolation ON schema_subscriptions.webhook_deliveries;
+ALTER TABLE schema_subscriptions.webhook_deliveries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.webhook_deliveries DISABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.webhook_deliveries DROP COLUMN IF EXISTS tenant_id;
+
+DROP POLICY IF EXISTS tenant_isolation ON schema_subscriptions.outbox;
+ALTER TABLE schema_subscriptions.outbox NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.outbox DISABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.outbox DROP COLUMN IF EXISTS tenant_id;
+
+DROP POLICY IF EXISTS tenant_isolation ON schema_subscriptions.webhooks;
+ALTER TABLE schema_subscriptions.webhooks NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.webhooks DISABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.webhooks DROP COLUMN IF EXISTS tenant_id;
+
+DROP POLICY IF EXISTS tenant_isolation ON schema_subscriptions.reminders;
+ALTER TABLE schema_subscriptions.reminders NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.reminders DISABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.reminders DROP COLUMN IF EXISTS tenant_id;
+
+DROP POLICY IF EXISTS tenant_isolation ON schema_subscriptions.subscriptions;
+ALTER TABLE schema_subscriptions.subscriptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.subscriptions DISABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.subscriptions DROP COLUMN IF EXISTS tenant_id;
+
+ALTER DEFAULT PRIVILEGES IN SCHEMA schema_subscriptions
+    REVOKE USAGE, SELECT ON SEQUENCES FROM subscriptions_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA schema_subscriptions
+    REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM subscriptions_tenant;
+REVOKE ALL ON ALL SEQUENCES IN SCHEMA schema_subscriptions FROM subscriptions_tenant;
+REVOKE ALL ON ALL TABLES IN SCHEMA schema_subscriptions FROM subscriptions_tenant;
+REVOKE USAGE ON SCHEMA schema_subscriptions FROM subscriptions_tenant;
@@ -0,0 +1,83 @@
+DO
+$$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'subscriptions_tenant') THEN
+        CREATE ROLE subscriptions_tenant NOLOGIN;
+    END IF;
+END
+$$;
+
+GRANT subscriptions_tenant TO CURRENT_USER;
+GRANT USAGE ON SCHEMA schema_subscriptions TO subscriptions_tenant;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA schema_subscriptions TO subscriptions_tenant;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA schema_subscriptions TO subscriptions_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA schema_subscriptions
+    GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO subscriptions_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA schema_subscriptions
+    GRANT USAGE, SELECT ON SEQUENCES TO subscriptions_tenant;
+
+ALTER TABLE schema_subscriptions.subscriptions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE schema_subscriptions.subscriptions ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+CREATE INDEX IF NOT EXISTS subscriptions_tenant_idx ON schema_subscriptions.subscriptions (tenant_id);
+ALTER TABLE schema_subscriptions.subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.subscriptions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON schema_subscriptions.subscriptions
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE schema_subscriptions.reminders ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE schema_subscriptions.reminders ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+CREATE INDEX IF NOT EXISTS reminders_tenant_idx ON schema_subscriptions.reminders (tenant_id);
+ALTER TABLE schema_subscriptions.reminders ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.reminders FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON schema_subscriptions.reminders
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE schema_subscriptions.webhooks ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE schema_subscriptions.webhooks ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+CREATE INDEX IF NOT EXISTS webhooks_tenant_idx ON schema_subscriptions.webhooks (tenant_id);
+ALTER TABLE schema_subscriptions.webhooks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.webhooks FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON schema_subscriptions.webhooks
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE schema_subscriptions.outbox ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE schema_subscriptions.outbox ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+CREATE INDEX IF NOT EXISTS outbox_tenant_idx ON schema_subscriptions.outbox (tenant_id);
+ALTER TABLE schema_subscriptions.outbox ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.outbox FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON schema_subscriptions.outbox
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE schema_subscriptions.webhook_deliveries ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE schema_subscriptions.webhook_deliveries ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+CREATE INDEX IF NOT EXISTS webhook_deliveries_tenant_idx ON schema_subscriptions.webhook_deliveries (tenant_id);
+ALTER TABLE schema_subscriptions.webhook_deliveries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.webhook_deliveries FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON schema_subscriptions.webhook_deliveries
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE schema_subscriptions.api_keys ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE schema_subscriptions.api_keys ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+CREATE INDEX IF NOT EXISTS api_keys_tenant_idx ON schema_subscriptions.api_keys (tenant_id);
+ALTER TABLE schema_subscriptions.api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.api_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON schema_subscriptions.api_keys
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE schema_subscriptions.role_assignments ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE schema_subscriptions.role_assignments ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+CREATE INDEX IF NOT EXISTS role_assignments_tenant_idx ON schema_subscriptions.role_assignments (tenant_id);
+ALTER TABLE schema_subscriptions.role_assignments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.role_assignments FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON schema_subscriptions.role_assignments
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+ALTER TABLE schema_subscriptions.role_assignments DROP CONSTRAINT role_assignments_pkey;
+ALTER TABLE schema_subscriptions.role_assignments ADD PRIMARY KEY (tenant_id, subject, role);
@@ -0,0 +1,13 @@
+-- The token buckets are global on purpose: they are taken before the tenant of a request is known, by its address,
+-- and afterwards by keys which already include the tenant, such as user:<tenant>/<user>, or ids unique across tenants,
+-- such as key:<api key id>. Hence the table has neither a tenant_id column nor row-level security, and it is only
+-- accessed outside the tenant-bound transactions.
+CREATE TABLE IF NOT EXISTS schema_subscriptions.rate_limits
+(
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_updated_at_idx ON schema_subscriptions.rate_limits (updated_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS schema_subscriptions.monthly_spend_coverage;
+DROP TABLE IF EXISTS schema_subscriptions.monthly_spend;
+DROP FUNCTION IF EXISTS schema_subscriptions.subscription_months(TEXT, TEXT, DATE);
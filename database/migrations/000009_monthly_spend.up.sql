@@ -0,0 +1,43 @@
+CREATE OR REPLACE FUNCTION schema_subscriptions.subscription_months(start_date TEXT, end_date TEXT, through DATE)
+RETURNS SETOF DATE
+LANGUAGE sql STABLE AS
+$$
+    SELECT month::date
+    FROM generate_series(
+        CASE WHEN start_date ~ '^(0[1-9]|1[0-2])-[0-9]{4}$' AND (coalesce(end_date, '') = '' OR end_date ~ '^(0[1-9]|1[0-2])-[0-9]{4}$')
+            THEN to_date(start_date, 'MM-YYYY')::timestamp END,
+        CASE WHEN coalesce(end_date, '') ~ '^(0[1-9]|1[0-2])-[0-9]{4}$'
+            THEN least(to_date(end_date, 'MM-YYYY'), through)::timestamp ELSE through::timestamp END,
+        interval '1 month'
+    ) AS month
+$$;
+
+CREATE TABLE IF NOT EXISTS schema_subscriptions.monthly_spend
+(
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.tenant_id'),
+    user_id TEXT NOT NULL,
+    service_name TEXT NOT NULL,
+    month DATE NOT NULL,
+    amount BIGINT NOT NULL,
+    PRIMARY KEY (tenant_id, user_id, service_name, month)
+);
+
+CREATE INDEX IF NOT EXISTS monthly_spend_month_idx ON schema_subscriptions.monthly_spend (tenant_id, month);
+ALTER TABLE schema_subscriptions.monthly_spend ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.monthly_spend FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON schema_subscriptions.monthly_spend
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
+
+CREATE TABLE IF NOT EXISTS schema_subscriptions.monthly_spend_coverage
+(
+    tenant_id TEXT PRIMARY KEY DEFAULT current_setting('app.tenant_id'),
+    through DATE NOT NULL,
+    refreshed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE schema_subscriptions.monthly_spend_coverage ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schema_subscriptions.monthly_spend_coverage FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON schema_subscriptions.monthly_spend_coverage
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*');
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS schema_subscriptions.outbox_created_at_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS outbox_created_at_idx ON schema_subscriptions.outbox (created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS schema_subscriptions.webhook_deliveries_event_idx;
+DROP INDEX IF EXISTS schema_subscriptions.outbox_subscription_idx;
+DROP INDEX IF EXISTS schema_subscriptions.subscriptions_unexpired_idx;
+ALTER TABLE schema_subscriptions.subscriptions DROP COLUMN IF EXISTS expired_end_date;
+ALTER TABLE schema_subscriptions.subscriptions DROP COLUMN IF EXISTS end_month;
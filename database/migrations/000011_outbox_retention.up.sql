@@ -0,0 +1,25 @@
+ALTER TABLE schema_subscriptions.subscriptions
+    ADD COLUMN IF NOT EXISTS end_month DATE GENERATED ALWAYS AS (
+        CASE WHEN end_date ~ '^(0[1-9]|1[0-2])-[0-9]{4}$'
+            THEN make_date(split_part(end_date, '-', 2)::int, split_part(end_date, '-', 1)::int, 1) END
+    ) STORED;
+
+-- expired_end_date is the end_date the subscription.expired event was enqueued for, so that the event is enqueued
+-- once per end_date even after the outbox is pruned.
+ALTER TABLE schema_subscriptions.subscriptions ADD COLUMN IF NOT EXISTS expired_end_date TEXT;
+
+CREATE INDEX IF NOT EXISTS outbox_subscription_idx ON schema_subscriptions.outbox (subscription_id, event_type);
+
+SELECT set_config('app.tenant_id', '*', false);
+
+UPDATE schema_subscriptions.subscriptions s SET expired_end_date = s.end_date
+WHERE EXISTS (
+    SELECT 1 FROM schema_subscriptions.outbox o
+    WHERE o.subscription_id = s.id AND o.event_type = 'subscription.expired' AND o.payload->>'end_date' = s.end_date);
+
+RESET app.tenant_id;
+
+CREATE INDEX IF NOT EXISTS subscriptions_unexpired_idx ON schema_subscriptions.subscriptions (end_month)
+    WHERE end_month IS NOT NULL AND expired_end_date IS DISTINCT FROM end_date;
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_event_idx ON schema_subscriptions.webhook_deliveries (event_id);
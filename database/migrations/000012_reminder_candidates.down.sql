@@ -0,0 +1 @@
+ALTER TABLE schema_subscriptions.subscriptions DROP COLUMN IF EXISTS start_month;
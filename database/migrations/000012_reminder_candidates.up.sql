@@ -0,0 +1,5 @@
+ALTER TABLE schema_subscriptions.subscriptions
+    ADD COLUMN IF NOT EXISTS start_month DATE GENERATED ALWAYS AS (
+        CASE WHEN start_date ~ '^(0[1-9]|1[0-2])-[0-9]{4}$'
+            THEN make_date(split_part(start_date, '-', 2)::int, split_part(start_date, '-', 1)::int, 1) END
+    ) STORED;
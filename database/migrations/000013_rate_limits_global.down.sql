@@ -0,0 +1 @@
+GRANT SELECT, INSERT, UPDATE, DELETE ON schema_subscriptions.rate_limits TO subscriptions_tenant;
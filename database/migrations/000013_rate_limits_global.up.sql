@@ -0,0 +1,2 @@
+-- The token buckets are shared by all tenants, see 000008, so the tenant-bound transactions must not reach them.
+REVOKE ALL ON schema_subscriptions.rate_limits FROM subscriptions_tenant;
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+	"subscriptions/internal/problem"
+	"subscriptions/internal/rbac"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// AddAPIKeyHandler godoc
+// @Summary Create an API key
+// @Description Creates an API key with the given name and scopes. The plaintext key is returned only in this response.
+// @Description Callers may only grant the scopes they hold themselves: API keys their own scopes, users the scopes
+// @Description whose actions their roles permit on the data of all users.
+// @Tags keys
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key body api.APIKey true "API key name and scopes"
+// @Success 201 {object} response{data=api.APIKey}
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/keys [post]
+// @Router /v2/keys [post]
+func AddAPIKeyHandler(logger *zap.Logger, kc postgresClient.APIKeyClient, authorizer *rbac.Authorizer) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := &api.APIKey{}
+
+		err := json.NewDecoder(r.Body).Decode(key)
+		if err != nil {
+			writeError(logger, w, r, bodyError(err))
+			logger.Error("AddAPIKeyHandler: cannot decode body", zap.Error(err))
+			return
+		}
+
+		err = validateAPIKey(key)
+		if err != nil {
+			writeError(logger, w, r, validationFailed(err))
+			logger.Error("AddAPIKeyHandler: invalid api key", zap.Error(err))
+			return
+		}
+
+		granted, err := authorizer.GrantableScopes(r.Context())
+		if err != nil {
+			writeError(logger, w, r, err)
+			return
+		}
+
+		if scope, ok := ungranted(key.Scopes, granted); ok {
+			writeError(logger, w, r, problem.New(problem.CodeForbidden, "cannot grant a scope the caller does not hold: "+scope))
+			logger.Warn("AddAPIKeyHandler: scope escalation rejected", zap.String("scope", scope))
+			return
+		}
+
+		plaintext, prefix, hash, err := auth.GenerateKey()
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("AddAPIKeyHandler:", zap.Error(err))
+			return
+		}
+
+		key.Prefix = prefix
+
+		err = kc.SaveAPIKey(r.Context(), key, hash)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("AddAPIKeyHandler:", zap.Error(err))
+			return
+		}
+
+		key.Key = plaintext
+
+		writeJSONResponse(logger, w, http.StatusCreated, key)
+	}
+}
+
+// validateAPIKey checks that the API key has a name and only known scopes.
+func validateAPIKey(key *api.APIKey) error {
+	if key.Name == "" {
+		return fmt.Errorf("'name' is required")
+	}
+
+	for _, scope := range key.Scopes {
+		if !slices.Contains(auth.Scopes, scope) {
+			return fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+
+	return nil
+}
+
+// ungranted returns the first of the scopes which is not among the granted ones, if any.
+func ungranted(scopes []string, granted []string) (string, bool) {
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return scope, true
+		}
+	}
+
+	return "", false
+}
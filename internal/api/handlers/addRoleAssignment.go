@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/rbac"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// AddRoleAssignmentHandler godoc
+// @Summary Assign a role
+// @Description Grants the role to the subject, which is the user ID carried by the bearer token.
+// @Tags roles
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param assignment body api.RoleAssignment true "Subject and role"
+// @Success 201 {object} response{data=api.RoleAssignment}
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/roles/assignments [post]
+// @Router /v2/roles/assignments [post]
+func AddRoleAssignmentHandler(logger *zap.Logger, rc postgresClient.RoleClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assignment := &api.RoleAssignment{}
+
+		err := json.NewDecoder(r.Body).Decode(assignment)
+		if err != nil {
+			writeError(logger, w, r, bodyError(err))
+			logger.Error("AddRoleAssignmentHandler: cannot decode body", zap.Error(err))
+			return
+		}
+
+		err = validateRoleAssignment(assignment)
+		if err != nil {
+			writeError(logger, w, r, validationFailed(err))
+			logger.Error("AddRoleAssignmentHandler: invalid role assignment", zap.Error(err))
+			return
+		}
+
+		err = rc.SaveRoleAssignment(r.Context(), assignment)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("AddRoleAssignmentHandler:", zap.Error(err))
+			return
+		}
+
+		writeJSONResponse(logger, w, http.StatusCreated, assignment)
+	}
+}
+
+// validateRoleAssignment checks that the assignment has a subject and a known role.
+func validateRoleAssignment(assignment *api.RoleAssignment) error {
+	if assignment.Subject == "" {
+		return fmt.Errorf("'subject' is required")
+	}
+
+	if _, ok := rbac.FindRole(assignment.Role); !ok {
+		return fmt.Errorf("unknown role: %s", assignment.Role)
+	}
+
+	return nil
+}
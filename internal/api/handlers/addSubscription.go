@@ -1,12 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"go.uber.org/zap"
 
-	"subscriptions/internal/api"
+	"subscriptions/internal/service"
 	"subscriptions/internal/storage/postgresClient"
 )
 
@@ -14,27 +13,65 @@ import (
 // @Summary Add a new subscription
 // @Description Adds a new subscription for a user
 // @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param subscription body api.Subscription true "Subscription data"
-// @Success 201 {object} response
-// @Failure 400 {object} response
-// @Failure 500 {object} response
-// @Router /subscriptions [post]
+// @Success 201 {object} response{data=int} "ID of the created resource"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v1/subscriptions [post]
 func AddSubscriptionHandler(logger *zap.Logger, pc postgresClient.PostgresClient) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		subscription := &api.Subscription{}
+	return addSubscriptionHandler(logger, pc, v1)
+}
 
-		err := json.NewDecoder(r.Body).Decode(subscription)
+// AddSubscriptionV2Handler godoc
+// @Summary Add a new subscription
+// @Description Adds a new subscription for a user
+// @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param subscription body api.SubscriptionV2 true "Subscription data"
+// @Success 201 {object} response{data=int} "ID of the created resource"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v2/subscriptions [post]
+func AddSubscriptionV2Handler(logger *zap.Logger, pc postgresClient.PostgresClient) func(http.ResponseWriter, *http.Request) {
+	return addSubscriptionHandler(logger, pc, v2)
+}
+
+// addSubscriptionHandler adds the subscription decoded by the given API version.
+func addSubscriptionHandler(logger *zap.Logger, pc postgresClient.PostgresClient, v *version) func(http.ResponseWriter, *http.Request) {
+	subscriptions := service.NewSubscriptions(pc)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		subscription, err := v.decode(r.Body)
 		if err != nil {
-			writeResponseWithError(logger, w, http.StatusBadRequest, err.Error())
+			writeError(logger, w, r, bodyError(err))
 			logger.Error("AddSubscriptionHandler: cannot decode body", zap.Error(err))
 			return
 		}
 
-		id, err := pc.SaveSubscription(subscription)
+		id, err := subscriptions.Create(r.Context(), subscription)
 		if err != nil {
-			writeResponseWithError(logger, w, http.StatusInternalServerError, err.Error())
+			writeError(logger, w, r, err)
 			logger.Error("AddSubscriptionHandler:", zap.Error(err))
 			return
 		}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// AddWebhookHandler godoc
+// @Summary Register a webhook
+// @Description Registers an endpoint receiving HMAC-signed subscription lifecycle events. An empty events list subscribes to all events.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param webhook body api.Webhook true "Webhook data"
+// @Success 201 {object} response{data=int} "ID of the created resource"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/webhooks [post]
+// @Router /v2/webhooks [post]
+func AddWebhookHandler(logger *zap.Logger, wc postgresClient.WebhookClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhook := &api.Webhook{}
+
+		err := json.NewDecoder(r.Body).Decode(webhook)
+		if err != nil {
+			writeError(logger, w, r, bodyError(err))
+			logger.Error("AddWebhookHandler: cannot decode body", zap.Error(err))
+			return
+		}
+
+		err = validateWebhook(webhook)
+		if err != nil {
+			writeError(logger, w, r, validationFailed(err))
+			logger.Error("AddWebhookHandler: invalid webhook", zap.Error(err))
+			return
+		}
+
+		id, err := wc.SaveWebhook(r.Context(), webhook)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("AddWebhookHandler:", zap.Error(err))
+			return
+		}
+
+		writeJSONResponse(logger, w, http.StatusCreated, id)
+	}
+}
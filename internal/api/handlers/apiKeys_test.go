@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+	"subscriptions/internal/problem"
+	"subscriptions/internal/rbac"
+)
+
+// fakeAPIKeyClient keeps the saved API keys in memory.
+type fakeAPIKeyClient struct {
+	saved []*api.APIKey
+}
+
+func (f *fakeAPIKeyClient) SaveAPIKey(_ context.Context, key *api.APIKey, _ string) error {
+	f.saved = append(f.saved, key)
+	return nil
+}
+
+func (f *fakeAPIKeyClient) AuthenticateAPIKey(context.Context, string) (*api.APIKey, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIKeyClient) GetAPIKey(context.Context, int) (*api.APIKey, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIKeyClient) ListAPIKeys(context.Context) ([]*api.APIKey, error) {
+	return f.saved, nil
+}
+
+func (f *fakeAPIKeyClient) RevokeAPIKey(context.Context, int) error {
+	return nil
+}
+
+// fakeRoleStorage assigns no roles, so users get the default role.
+type fakeRoleStorage struct{}
+
+func (fakeRoleStorage) ListRoleAssignments(context.Context, string) ([]*api.RoleAssignment, error) {
+	return nil, nil
+}
+
+func TestAddAPIKeyHandler(t *testing.T) {
+	authorizer, err := rbac.New(&rbac.Config{}, fakeRoleStorage{}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager := &auth.Principal{Name: "ci", TenantID: "acme", Scopes: []string{auth.ScopeKeysManage, auth.ScopeSubscriptionsRead}}
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		body       string
+		wantStatus int
+		wantCode   problem.Code
+	}{
+		{
+			name:       "own scopes",
+			principal:  manager,
+			body:       `{"name":"reader","scopes":["subscriptions:read"]}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "scope the key does not hold",
+			principal:  manager,
+			body:       `{"name":"root","scopes":["subscriptions:read","roles:manage"]}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   problem.CodeForbidden,
+		},
+		{
+			name:       "user restricted to the own data",
+			principal:  &auth.Principal{Name: "alice", TenantID: "acme", UserID: "alice"},
+			body:       `{"name":"reader","scopes":["subscriptions:read"]}`,
+			wantStatus: http.StatusForbidden,
+			wantCode:   problem.CodeForbidden,
+		},
+		{
+			name:       "admin user",
+			principal:  &auth.Principal{Name: "root", TenantID: "acme", UserID: "root", Roles: []string{rbac.RoleAdmin}},
+			body:       `{"name":"root","scopes":["roles:manage","keys:manage"]}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "unknown scope",
+			principal:  manager,
+			body:       `{"name":"reader","scopes":["subscriptions:admin"]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   problem.CodeValidationFailed,
+		},
+		{
+			name:       "missing name",
+			principal:  manager,
+			body:       `{"scopes":["subscriptions:read"]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   problem.CodeValidationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &fakeAPIKeyClient{}
+
+			req := httptest.NewRequest(http.MethodPost, "/v2/keys", strings.NewReader(tt.body))
+			req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+			rec := httptest.NewRecorder()
+
+			AddAPIKeyHandler(zap.NewNop(), storage, authorizer)(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			if tt.wantCode == "" {
+				if len(storage.saved) != 1 || !strings.HasPrefix(storage.saved[0].Key, "sk_") {
+					t.Errorf("saved = %v, want the created key", storage.saved)
+				}
+				return
+			}
+
+			var p problem.Problem
+			if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+				t.Fatalf("cannot decode problem: %v", err)
+			}
+
+			if p.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", p.Code, tt.wantCode)
+			}
+
+			if len(storage.saved) != 0 {
+				t.Errorf("saved = %v, want no key", storage.saved)
+			}
+		})
+	}
+}
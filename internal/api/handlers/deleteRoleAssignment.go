@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// DeleteRoleAssignmentHandler godoc
+// @Summary Revoke a role
+// @Description Revokes the role from the subject.
+// @Tags roles
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param subject path string true "Subject (user ID)"
+// @Param role path string true "Role"
+// @Success 200 {object} response
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/roles/assignments/{subject}/{role} [delete]
+// @Router /v2/roles/assignments/{subject}/{role} [delete]
+func DeleteRoleAssignmentHandler(logger *zap.Logger, rc postgresClient.RoleClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subject := chi.URLParam(r, "subject")
+		role := chi.URLParam(r, "role")
+
+		err := rc.DeleteRoleAssignment(r.Context(), subject, role)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("DeleteRoleAssignmentHandler:", zap.Error(err))
+			return
+		}
+
+		writeJSONResponse(logger, w, http.StatusOK, nil)
+	}
+}
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"errors"
 	"net/http"
 
 	"go.uber.org/zap"
 
+	"subscriptions/internal/service"
 	"subscriptions/internal/storage/postgresClient"
 )
 
@@ -13,31 +13,34 @@ import (
 // @Summary Delete a subscription
 // @Description Deletes a subscription by ID
 // @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Produce json
 // @Param id path int true "Subscription ID"
 // @Success 200 {object} response
-// @Failure 400 {object} response
-// @Failure 404 {object} response
-// @Failure 500 {object} response
-// @Router /subscriptions/{id} [delete]
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v1/subscriptions/{id} [delete]
+// @Router /v2/subscriptions/{id} [delete]
 func DeleteSubscriptionHandler(logger *zap.Logger, pc postgresClient.PostgresClient) func(http.ResponseWriter, *http.Request) {
+	subscriptions := service.NewSubscriptions(pc)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := parseIdParam(r)
 		if err != nil {
-			writeResponseWithError(logger, w, http.StatusBadRequest, err.Error())
+			writeError(logger, w, r, err)
 			logger.Error("DeleteSubscriptionHandler: cannot get id from URL", zap.Error(err))
 			return
 		}
 
-		err = pc.DeleteSubscription(id)
+		err = subscriptions.Delete(r.Context(), id)
 		if err != nil {
-			switch {
-			case errors.Is(err, postgresClient.ErrSubscriptionNotFound):
-				writeResponseWithError(logger, w, http.StatusNotFound, err.Error())
-			default:
-				writeResponseWithError(logger, w, http.StatusInternalServerError, err.Error())
-			}
-
+			writeError(logger, w, r, err)
 			logger.Error("DeleteSubscriptionHandler:", zap.Error(err))
 			return
 		}
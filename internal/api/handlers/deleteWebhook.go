@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// DeleteWebhookHandler godoc
+// @Summary Delete a webhook
+// @Description Deletes a webhook by ID together with its delivery log
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} response
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/webhooks/{id} [delete]
+// @Router /v2/webhooks/{id} [delete]
+func DeleteWebhookHandler(logger *zap.Logger, wc postgresClient.WebhookClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIdParam(r)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("DeleteWebhookHandler: cannot get id from URL", zap.Error(err))
+			return
+		}
+
+		err = wc.DeleteWebhook(r.Context(), id)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("DeleteWebhookHandler:", zap.Error(err))
+			return
+		}
+
+		writeJSONResponse(logger, w, http.StatusOK, nil)
+	}
+}
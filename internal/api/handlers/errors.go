@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/problem"
+	"subscriptions/internal/service"
+)
+
+// bodyError maps an error decoding the request body to the problem reported to the client.
+func bodyError(err error) *problem.Error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return problem.Wrap(problem.CodeBodyTooLarge, "request body is too large", err)
+	}
+
+	return problem.Wrap(problem.CodeInvalidBody, "cannot decode request body: "+err.Error(), err)
+}
+
+// invalid reports a request rejected by the validation of its parameters or body.
+func invalid(detail string) *problem.Error {
+	return problem.New(problem.CodeInvalidRequest, detail)
+}
+
+// validationFailed reports a request whose content was rejected by the validation with err.
+func validationFailed(err error) *problem.Error {
+	return problem.Wrap(problem.CodeValidationFailed, err.Error(), err)
+}
+
+// writeError writes err to the client as problem details, see service.Classify.
+func writeError(logger *zap.Logger, w http.ResponseWriter, r *http.Request, err error) {
+	problem.Write(logger, w, r, service.Classify(err))
+}
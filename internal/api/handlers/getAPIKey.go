@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// GetAPIKeyHandler godoc
+// @Summary Get API key by ID
+// @Description Returns the API key with the given ID, including its last usage time. The key itself is never returned.
+// @Tags keys
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} response{data=api.APIKey}
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/keys/{id} [get]
+// @Router /v2/keys/{id} [get]
+func GetAPIKeyHandler(logger *zap.Logger, kc postgresClient.APIKeyClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIdParam(r)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("GetAPIKeyHandler: cannot get id from URL", zap.Error(err))
+			return
+		}
+
+		key, err := kc.GetAPIKey(r.Context(), id)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("GetAPIKeyHandler:", zap.Error(err))
+			return
+		}
+
+		writeJSONResponse(logger, w, http.StatusOK, key)
+	}
+}
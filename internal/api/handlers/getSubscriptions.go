@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"errors"
 	"net/http"
 
 	"go.uber.org/zap"
 
+	"subscriptions/internal/service"
 	"subscriptions/internal/storage/postgresClient"
 )
 
@@ -13,35 +13,70 @@ import (
 // @Summary Get subscription by ID
 // @Description Returns subscription details for the given subscription ID.
 // @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Produce json
 // @Param id path int true "Subscription ID"
-// @Success 200 {object} api.Subscription
-// @Failure 400 {object} response
-// @Failure 404 {object} response
-// @Failure 500 {object} response
-// @Router /subscriptions/{id} [get]
+// @Success 200 {object} response{data=api.Subscription}
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v1/subscriptions/{id} [get]
 func GetSubscriptionHandler(logger *zap.Logger, pc postgresClient.PostgresClient) func(http.ResponseWriter, *http.Request) {
+	return getSubscriptionHandler(logger, pc, v1)
+}
+
+// GetSubscriptionV2Handler godoc
+// @Summary Get subscription by ID
+// @Description Returns subscription details for the given subscription ID.
+// @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} response{data=api.SubscriptionV2}
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v2/subscriptions/{id} [get]
+func GetSubscriptionV2Handler(logger *zap.Logger, pc postgresClient.PostgresClient) func(http.ResponseWriter, *http.Request) {
+	return getSubscriptionHandler(logger, pc, v2)
+}
+
+// getSubscriptionHandler returns the subscription in the model of the given API version.
+func getSubscriptionHandler(logger *zap.Logger, pc postgresClient.PostgresClient, v *version) func(http.ResponseWriter, *http.Request) {
+	subscriptions := service.NewSubscriptions(pc)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := parseIdParam(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(logger, w, r, err)
 			logger.Error("GetSubscriptionsHandler: cannot get id from URL", zap.Error(err))
 			return
 		}
 
-		subscription, err := pc.GetSubscription(id)
+		subscription, err := subscriptions.Get(r.Context(), id)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("GetSubscriptionsHandler:", zap.Int("id", id), zap.Error(err))
+			return
+		}
+
+		dto, err := v.encode(subscription)
 		if err != nil {
-			switch {
-			case errors.Is(err, postgresClient.ErrSubscriptionNotFound):
-				writeResponseWithError(logger, w, http.StatusNotFound, err.Error())
-			default:
-				writeResponseWithError(logger, w, http.StatusInternalServerError, err.Error())
-			}
-
-			logger.Error("GetSubscriptionsHandler:", zap.Error(err))
+			writeError(logger, w, r, err)
+			logger.Error("GetSubscriptionsHandler: cannot encode subscription", zap.Int("id", id), zap.Error(err))
 			return
 		}
 
-		writeJSONResponse(logger, w, http.StatusOK, subscription)
+		writeJSONResponse(logger, w, http.StatusOK, dto)
 	}
 }
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// GetWebhookHandler godoc
+// @Summary Get webhook by ID
+// @Description Returns the webhook registered with the given ID. The secret is never returned.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} response{data=api.Webhook}
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/webhooks/{id} [get]
+// @Router /v2/webhooks/{id} [get]
+func GetWebhookHandler(logger *zap.Logger, wc postgresClient.WebhookClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIdParam(r)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("GetWebhookHandler: cannot get id from URL", zap.Error(err))
+			return
+		}
+
+		webhook, err := wc.GetWebhook(r.Context(), id)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("GetWebhookHandler:", zap.Error(err))
+			return
+		}
+
+		webhook.Secret = ""
+
+		writeJSONResponse(logger, w, http.StatusOK, webhook)
+	}
+}
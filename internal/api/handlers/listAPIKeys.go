@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// ListAPIKeysHandler godoc
+// @Summary List all API keys
+// @Description Returns all API keys, including revoked ones, with their last usage time.
+// @Tags keys
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} response{data=[]api.APIKey}
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/keys [get]
+// @Router /v2/keys [get]
+func ListAPIKeysHandler(logger *zap.Logger, kc postgresClient.APIKeyClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := kc.ListAPIKeys(r.Context())
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("ListAPIKeysHandler:", zap.Error(err))
+			return
+		}
+
+		writeJSONResponse(logger, w, http.StatusOK, keys)
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// ListRoleAssignmentsHandler godoc
+// @Summary List role assignments
+// @Description Returns the roles assigned to the given subject, or to all subjects if none is given.
+// @Tags roles
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param subject query string false "Subject (user ID) filter"
+// @Success 200 {object} response{data=[]api.RoleAssignment}
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/roles/assignments [get]
+// @Router /v2/roles/assignments [get]
+func ListRoleAssignmentsHandler(logger *zap.Logger, rc postgresClient.RoleClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assignments, err := rc.ListRoleAssignments(r.Context(), r.URL.Query().Get("subject"))
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("ListRoleAssignmentsHandler:", zap.Error(err))
+			return
+		}
+
+		writeJSONResponse(logger, w, http.StatusOK, assignments)
+	}
+}
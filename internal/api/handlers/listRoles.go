@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/rbac"
+)
+
+// ListRolesHandler godoc
+// @Summary List roles
+// @Description Returns all roles together with the actions they permit.
+// @Tags roles
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} response{data=[]api.Role}
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Router /v1/roles [get]
+// @Router /v2/roles [get]
+func ListRolesHandler(logger *zap.Logger) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(logger, w, http.StatusOK, rbac.Roles)
+	}
+}
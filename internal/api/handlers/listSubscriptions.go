@@ -1,38 +1,66 @@
 package handlers
 
 import (
-	"errors"
 	"net/http"
 
 	"go.uber.org/zap"
 
+	"subscriptions/internal/service"
 	"subscriptions/internal/storage/postgresClient"
 )
 
 // ListSubscriptionsHandler godoc
 // @Summary List all subscriptions
 // @Description Returns a list of all subscriptions stored in the database.
+// @Description Callers authenticated as a regular user only get their own subscriptions.
 // @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Produce json
-// @Success 200 {array} api.Subscription
-// @Failure 404 {object} response
-// @Failure 500 {object} response
-// @Router /subscriptions [get].
+// @Success 200 {object} response{data=[]api.Subscription}
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v1/subscriptions [get]
 func ListSubscriptionsHandler(logger *zap.Logger, pc postgresClient.PostgresClient) func(http.ResponseWriter, *http.Request) {
+	return listSubscriptionsHandler(logger, pc, v1)
+}
+
+// ListSubscriptionsV2Handler godoc
+// @Summary List all subscriptions
+// @Description Returns a list of all subscriptions stored in the database.
+// @Description Callers authenticated as a regular user only get their own subscriptions.
+// @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} response{data=[]api.SubscriptionV2}
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v2/subscriptions [get]
+func ListSubscriptionsV2Handler(logger *zap.Logger, pc postgresClient.PostgresClient) func(http.ResponseWriter, *http.Request) {
+	return listSubscriptionsHandler(logger, pc, v2)
+}
+
+// listSubscriptionsHandler lists the subscriptions in the model of the given API version.
+func listSubscriptionsHandler(logger *zap.Logger, pc postgresClient.PostgresClient, v *version) func(http.ResponseWriter, *http.Request) {
+	subscriptions := service.NewSubscriptions(pc)
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		subscriptions, err := pc.ListSubscriptions()
+		list, err := subscriptions.List(r.Context())
 		if err != nil {
-			switch {
-			case errors.Is(err, postgresClient.ErrSubscriptionNotFound):
-				writeResponseWithError(logger, w, http.StatusNotFound, err.Error())
-			default:
-				writeResponseWithError(logger, w, http.StatusInternalServerError, err.Error())
-			}
-
+			writeError(logger, w, r, err)
 			logger.Error("ListSubscriptionsHandler:", zap.Error(err))
 			return
 		}
 
-		writeJSONResponse(logger, w, http.StatusOK, subscriptions)
+		writeJSONResponse(logger, w, http.StatusOK, v.encodeAll(logger, list))
 	}
 }
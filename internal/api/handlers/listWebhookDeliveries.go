@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// defaultDeliveriesLimit defines how many deliveries are returned when no limit is specified.
+const defaultDeliveriesLimit = 50
+
+// ListWebhookDeliveriesHandler godoc
+// @Summary List webhook deliveries
+// @Description Returns the latest deliveries of the webhook with the given ID, newest first.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "Maximum number of deliveries, 50 by default"
+// @Success 200 {object} response{data=[]api.WebhookDelivery}
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/webhooks/{id}/deliveries [get]
+// @Router /v2/webhooks/{id}/deliveries [get]
+func ListWebhookDeliveriesHandler(logger *zap.Logger, wc postgresClient.WebhookClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIdParam(r)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("ListWebhookDeliveriesHandler: cannot get id from URL", zap.Error(err))
+			return
+		}
+
+		limit := defaultDeliveriesLimit
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				writeError(logger, w, r, invalid("invalid 'limit', must be a positive integer"))
+				logger.Error("ListWebhookDeliveriesHandler: invalid limit in query params", zap.String("limit", limitStr))
+				return
+			}
+		}
+
+		_, err = wc.GetWebhook(r.Context(), id)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("ListWebhookDeliveriesHandler:", zap.Error(err))
+			return
+		}
+
+		deliveries, err := wc.ListWebhookDeliveries(r.Context(), id, limit)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("ListWebhookDeliveriesHandler:", zap.Error(err))
+			return
+		}
+
+		writeJSONResponse(logger, w, http.StatusOK, deliveries)
+	}
+}
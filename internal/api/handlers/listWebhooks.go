@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// ListWebhooksHandler godoc
+// @Summary List all webhooks
+// @Description Returns all registered webhooks. Secrets are never returned.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} response{data=[]api.Webhook}
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/webhooks [get]
+// @Router /v2/webhooks [get]
+func ListWebhooksHandler(logger *zap.Logger, wc postgresClient.WebhookClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhooks, err := wc.ListWebhooks(r.Context())
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("ListWebhooksHandler:", zap.Error(err))
+			return
+		}
+
+		for _, webhook := range webhooks {
+			webhook.Secret = ""
+		}
+
+		writeJSONResponse(logger, w, http.StatusOK, webhooks)
+	}
+}
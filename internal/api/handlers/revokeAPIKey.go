@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// RevokeAPIKeyHandler godoc
+// @Summary Revoke an API key
+// @Description Revokes the API key with the given ID, so it can no longer be used.
+// @Tags keys
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} response
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/keys/{id} [delete]
+// @Router /v2/keys/{id} [delete]
+func RevokeAPIKeyHandler(logger *zap.Logger, kc postgresClient.APIKeyClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIdParam(r)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("RevokeAPIKeyHandler: cannot get id from URL", zap.Error(err))
+			return
+		}
+
+		err = kc.RevokeAPIKey(r.Context(), id)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("RevokeAPIKeyHandler:", zap.Error(err))
+			return
+		}
+
+		writeJSONResponse(logger, w, http.StatusOK, nil)
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+	"subscriptions/internal/events"
+	"subscriptions/internal/problem"
+	"subscriptions/internal/storage/postgresClient"
+	"subscriptions/internal/tenant"
+)
+
+const (
+	// replayBatchSize defines how many missed events are loaded per query when a stream is resumed.
+	replayBatchSize = 500
+
+	// replayWindow defines how long before the last received event a resumed stream replays the events from.
+	// Event ids are taken when the events are inserted rather than committed, so an event with a lower id may be
+	// committed after a higher one; the window outlasts the transactions inserting them, which are bounded
+	// by the query timeout.
+	replayWindow = time.Minute
+)
+
+// sentEvents remembers the ids of the events sent on a stream, so the events both replayed and published live
+// are sent once. It forgets the events older than replayWindow, which are not replayed or published anymore.
+type sentEvents map[int]time.Time
+
+// add records the event as sent, reporting false if it already was.
+func (s sentEvents) add(event *api.Event) bool {
+	if _, ok := s[event.ID]; ok {
+		return false
+	}
+
+	s[event.ID] = event.OccurredAt
+	return true
+}
+
+// forget drops the events which occurred more than replayWindow before now.
+func (s sentEvents) forget(now time.Time) {
+	for id, occurredAt := range s {
+		if now.Sub(occurredAt) > replayWindow {
+			delete(s, id)
+		}
+	}
+}
+
+// SubscriptionEventsHandler godoc
+// @Summary Stream subscription changes
+// @Description Streams subscription create/update/delete/expire events as Server-Sent Events.
+// @Description The id of every event can be sent back in the Last-Event-ID header to resume the stream without losing events.
+// @Description A resumed stream may repeat the events received shortly before the given id, so clients should skip the ids they have already received.
+// @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce text/event-stream
+// @Param user_id query string false "User ID filter"
+// @Param Last-Event-ID header int false "Resume after the event with this id"
+// @Success 200 {object} api.Event
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/subscriptions/events [get]
+// @Router /v2/subscriptions/events [get]
+func SubscriptionEventsHandler(logger *zap.Logger, broker *events.Broker, ec postgresClient.EventClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.URL.Query().Get("user_id")
+
+		if owner := auth.ScopedUserID(r); owner != "" {
+			if userID != "" && userID != owner {
+				writeError(logger, w, r, problem.New(problem.CodeForbidden, "cannot stream events of another user"))
+				logger.Error("SubscriptionEventsHandler: user mismatch", zap.String("owner", owner), zap.String("userID", userID))
+				return
+			}
+
+			userID = owner
+		}
+
+		lastID := -1
+		if lastIDStr := r.Header.Get("Last-Event-ID"); lastIDStr != "" {
+			var err error
+			lastID, err = strconv.Atoi(lastIDStr)
+			if err != nil || lastID < 0 {
+				writeError(logger, w, r, invalid("invalid 'Last-Event-ID', must be a non-negative integer"))
+				logger.Error("SubscriptionEventsHandler: invalid Last-Event-ID header", zap.String("lastEventID", lastIDStr))
+				return
+			}
+		}
+
+		// Subscribe before replaying, so the events published during the replay are not lost.
+		tenantID, _ := tenant.FromContext(r.Context())
+		live, unsubscribe := broker.Subscribe(tenantID, userID)
+		defer unsubscribe()
+
+		rc := http.NewResponseController(w)
+
+		// The stream outlives the read and write timeouts of the server, which are meant for regular requests.
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			logger.Warn("SubscriptionEventsHandler: cannot clear read deadline", zap.Error(err))
+		}
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			logger.Warn("SubscriptionEventsHandler: cannot clear write deadline", zap.Error(err))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		sent := sentEvents{}
+
+		if lastID >= 0 {
+			after, err := ec.EventReplayStart(r.Context(), lastID, replayWindow)
+			if err != nil {
+				logger.Error("SubscriptionEventsHandler: cannot replay events", zap.Error(err))
+				return
+			}
+
+			for {
+				missed, err := ec.ListEventsAfter(r.Context(), after, userID, replayBatchSize)
+				if err != nil {
+					logger.Error("SubscriptionEventsHandler: cannot replay events", zap.Error(err))
+					return
+				}
+
+				for _, event := range missed {
+					sent.add(event)
+					if err = writeEvent(w, event); err != nil {
+						logger.Debug("SubscriptionEventsHandler: client went away", zap.Error(err))
+						return
+					}
+					after = event.ID
+				}
+
+				if len(missed) < replayBatchSize {
+					break
+				}
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			logger.Error("SubscriptionEventsHandler: streaming is not supported", zap.Error(err))
+			return
+		}
+
+		heartbeat := time.NewTicker(broker.Heartbeat())
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case now := <-heartbeat.C:
+				sent.forget(now)
+
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+
+			case event, ok := <-live:
+				if !ok {
+					return
+				}
+
+				if !sent.add(event) {
+					continue
+				}
+
+				if err := writeEvent(w, event); err != nil {
+					logger.Debug("SubscriptionEventsHandler: client went away", zap.Error(err))
+					return
+				}
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes the event in the Server-Sent Events format.
+func writeEvent(w http.ResponseWriter, event *api.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+
+	return err
+}
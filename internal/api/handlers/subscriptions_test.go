@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/problem"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// fakePostgresClient is a PostgresClient returning the configured subscriptions and error from every call.
+// The last saved subscription is kept in saved.
+type fakePostgresClient struct {
+	subscriptions []*api.Subscription
+	err           error
+	saved         *api.Subscription
+}
+
+func (f *fakePostgresClient) SaveSubscription(_ context.Context, subscription *api.Subscription) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+
+	f.saved = subscription
+	return 1, nil
+}
+
+func (f *fakePostgresClient) DeleteSubscription(context.Context, int) error {
+	return f.err
+}
+
+func (f *fakePostgresClient) GetSubscription(context.Context, int) (*api.Subscription, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return f.subscriptions[0], nil
+}
+
+func (f *fakePostgresClient) ListSubscriptions(context.Context) ([]*api.Subscription, error) {
+	return f.subscriptions, f.err
+}
+
+func (f *fakePostgresClient) UpdateSubscription(context.Context, int, *api.Subscription) error {
+	return f.err
+}
+
+func (f *fakePostgresClient) ListFilteredSubscriptions(context.Context, string, string) ([]*api.Subscription, error) {
+	return f.subscriptions, f.err
+}
+
+func (f *fakePostgresClient) ListSubscriptionsAfter(context.Context, int, string, int) ([]*postgresClient.SubscriptionRecord, error) {
+	return nil, f.err
+}
+
+func (f *fakePostgresClient) Close() {}
+
+// newSubscriptionsRouter routes the subscription endpoints of both API versions to the handlers backed by pc.
+func newSubscriptionsRouter(pc postgresClient.PostgresClient) http.Handler {
+	logger := zap.NewNop()
+
+	router := chi.NewRouter()
+	router.Route("/v1", func(r chi.Router) {
+		r.Get("/subscriptions/total", TotalPriceHandler(logger, pc, nil, nil))
+		r.Post("/subscriptions", AddSubscriptionHandler(logger, pc))
+		r.Get("/subscriptions", ListSubscriptionsHandler(logger, pc))
+		r.Get("/subscriptions/{id}", GetSubscriptionHandler(logger, pc))
+		r.Put("/subscriptions/{id}", UpdateSubscriptionHandler(logger, pc))
+		r.Delete("/subscriptions/{id}", DeleteSubscriptionHandler(logger, pc))
+	})
+	router.Route("/v2", func(r chi.Router) {
+		r.Get("/subscriptions/total", TotalPriceV2Handler(logger, pc, nil, nil))
+		r.Post("/subscriptions", AddSubscriptionV2Handler(logger, pc))
+		r.Get("/subscriptions", ListSubscriptionsV2Handler(logger, pc))
+		r.Get("/subscriptions/{id}", GetSubscriptionV2Handler(logger, pc))
+		r.Put("/subscriptions/{id}", UpdateSubscriptionV2Handler(logger, pc))
+		r.Delete("/subscriptions/{id}", DeleteSubscriptionHandler(logger, pc))
+	})
+
+	return router
+}
+
+func TestSubscriptionHandlers(t *testing.T) {
+	stored := &api.Subscription{ServiceName: "Yandex Plus", Price: 400, UserID: "u1", StartDate: "07-2025"}
+	body := `{"service_name":"Yandex Plus","price":400,"user_id":"u1","start_date":"07-2025"}`
+	sqlErr := &pgconn.PgError{Code: "42P01", Message: `relation "schema_subscriptions.subscriptions" does not exist`}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		storage    *fakePostgresClient
+		wantStatus int
+		wantCode   problem.Code
+	}{
+		{
+			name:       "get",
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/1",
+			storage:    &fakePostgresClient{subscriptions: []*api.Subscription{stored}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "get with invalid id",
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/abc",
+			storage:    &fakePostgresClient{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   problem.CodeInvalidRequest,
+		},
+		{
+			name:       "get missing",
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/1",
+			storage:    &fakePostgresClient{err: postgresClient.ErrSubscriptionNotFound},
+			wantStatus: http.StatusNotFound,
+			wantCode:   problem.CodeNotFound,
+		},
+		{
+			name:       "get timed out",
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/1",
+			storage:    &fakePostgresClient{err: fmt.Errorf("GetSubscription: %w", context.DeadlineExceeded)},
+			wantStatus: http.StatusGatewayTimeout,
+			wantCode:   problem.CodeTimeout,
+		},
+		{
+			name:       "get canceled",
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/1",
+			storage:    &fakePostgresClient{err: fmt.Errorf("GetSubscription: %w", context.Canceled)},
+			wantStatus: problem.StatusClientClosedRequest,
+			wantCode:   problem.CodeCanceled,
+		},
+		{
+			name:       "update",
+			method:     http.MethodPut,
+			target:     "/v1/subscriptions/1",
+			body:       body,
+			storage:    &fakePostgresClient{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "update missing",
+			method:     http.MethodPut,
+			target:     "/v1/subscriptions/1",
+			body:       body,
+			storage:    &fakePostgresClient{err: postgresClient.ErrSubscriptionNotFound},
+			wantStatus: http.StatusNotFound,
+			wantCode:   problem.CodeNotFound,
+		},
+		{
+			name:       "update with invalid body",
+			method:     http.MethodPut,
+			target:     "/v1/subscriptions/1",
+			body:       `{"price":"free"}`,
+			storage:    &fakePostgresClient{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   problem.CodeInvalidBody,
+		},
+		{
+			name:       "update violating a check constraint",
+			method:     http.MethodPut,
+			target:     "/v1/subscriptions/1",
+			body:       body,
+			storage:    &fakePostgresClient{err: fmt.Errorf("UpdateSubscription: %w", &pgconn.PgError{Code: "23514"})},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   problem.CodeConstraintViolation,
+		},
+		{
+			name:       "update conflicting with a concurrent change",
+			method:     http.MethodPut,
+			target:     "/v1/subscriptions/1",
+			body:       body,
+			storage:    &fakePostgresClient{err: fmt.Errorf("UpdateSubscription: %w", &pgconn.PgError{Code: "40001"})},
+			wantStatus: http.StatusConflict,
+			wantCode:   problem.CodeConflict,
+		},
+		{
+			name:       "create",
+			method:     http.MethodPost,
+			target:     "/v1/subscriptions",
+			body:       body,
+			storage:    &fakePostgresClient{},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "create duplicate",
+			method:     http.MethodPost,
+			target:     "/v1/subscriptions",
+			body:       body,
+			storage:    &fakePostgresClient{err: fmt.Errorf("SaveSubscription: %w", &pgconn.PgError{Code: "23505"})},
+			wantStatus: http.StatusConflict,
+			wantCode:   problem.CodeConflict,
+		},
+		{
+			name:       "create with invalid value",
+			method:     http.MethodPost,
+			target:     "/v1/subscriptions",
+			body:       body,
+			storage:    &fakePostgresClient{err: fmt.Errorf("SaveSubscription: %w", &pgconn.PgError{Code: "22007"})},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   problem.CodeConstraintViolation,
+		},
+		{
+			name:       "create with a v2 date",
+			method:     http.MethodPost,
+			target:     "/v1/subscriptions",
+			body:       `{"service_name":"Yandex Plus","price":400,"user_id":"u1","start_date":"2025-07"}`,
+			storage:    &fakePostgresClient{},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   problem.CodeValidationFailed,
+		},
+		{
+			name:       "v2 update ending before its start",
+			method:     http.MethodPut,
+			target:     "/v2/subscriptions/1",
+			body:       `{"service_name":"Yandex Plus","price":400,"user_id":"u1","start_date":"2025-07","end_date":"2025-06"}`,
+			storage:    &fakePostgresClient{},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   problem.CodeValidationFailed,
+		},
+		{
+			name:       "delete missing",
+			method:     http.MethodDelete,
+			target:     "/v1/subscriptions/1",
+			storage:    &fakePostgresClient{err: postgresClient.ErrSubscriptionNotFound},
+			wantStatus: http.StatusNotFound,
+			wantCode:   problem.CodeNotFound,
+		},
+		{
+			name:       "delete failing in storage",
+			method:     http.MethodDelete,
+			target:     "/v1/subscriptions/1",
+			storage:    &fakePostgresClient{err: fmt.Errorf("DeleteSubscription: %w", sqlErr)},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   problem.CodeInternal,
+		},
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions",
+			storage:    &fakePostgresClient{subscriptions: []*api.Subscription{stored}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "list failing in storage",
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions",
+			storage:    &fakePostgresClient{err: errors.New("connection reset by peer")},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   problem.CodeInternal,
+		},
+		{
+			name:       "total",
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/total?start_date=07-2025&end_date=09-2025",
+			storage:    &fakePostgresClient{subscriptions: []*api.Subscription{stored}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "total with invalid date",
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/total?start_date=2025-07&end_date=09-2025",
+			storage:    &fakePostgresClient{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   problem.CodeInvalidRequest,
+		},
+		{
+			name:       "total timed out",
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/total?start_date=07-2025&end_date=09-2025",
+			storage:    &fakePostgresClient{err: &pgconn.PgError{Code: "57014"}},
+			wantStatus: http.StatusGatewayTimeout,
+			wantCode:   problem.CodeTimeout,
+		},
+		{
+			name:       "v2 create with a v1 date",
+			method:     http.MethodPost,
+			target:     "/v2/subscriptions",
+			body:       body,
+			storage:    &fakePostgresClient{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   problem.CodeInvalidBody,
+		},
+		{
+			name:       "v2 total with a v1 date",
+			method:     http.MethodGet,
+			target:     "/v2/subscriptions/total?start_date=07-2025&end_date=09-2025",
+			storage:    &fakePostgresClient{},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   problem.CodeInvalidRequest,
+		},
+		{
+			name:       "v2 total",
+			method:     http.MethodGet,
+			target:     "/v2/subscriptions/total?start_date=2025-07&end_date=2025-09",
+			storage:    &fakePostgresClient{subscriptions: []*api.Subscription{stored}},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			newSubscriptionsRouter(tt.storage).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			if tt.wantCode == "" {
+				return
+			}
+
+			if contentType := rec.Header().Get("Content-Type"); contentType != problem.ContentType {
+				t.Errorf("content type = %q, want %q", contentType, problem.ContentType)
+			}
+
+			var p problem.Problem
+			if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+				t.Fatalf("cannot decode problem: %v", err)
+			}
+
+			if p.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", p.Code, tt.wantCode)
+			}
+
+			if p.Status != tt.wantStatus {
+				t.Errorf("problem status = %d, want %d", p.Status, tt.wantStatus)
+			}
+
+			if strings.Contains(p.Detail, "relation") || strings.Contains(p.Detail, "connection reset") {
+				t.Errorf("detail leaks the storage error: %q", p.Detail)
+			}
+		})
+	}
+}
+
+func TestTotalPriceResponse(t *testing.T) {
+	storage := &fakePostgresClient{subscriptions: []*api.Subscription{
+		{ServiceName: "Yandex Plus", Price: 400, UserID: "u1", StartDate: "07-2025"},
+		{ServiceName: "Netflix", Price: 1000, UserID: "u1", StartDate: "08-2025", EndDate: "08-2025"},
+	}}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/subscriptions/total?start_date=07-2025&end_date=09-2025", nil)
+	rec := httptest.NewRecorder()
+
+	newSubscriptionsRouter(storage).ServeHTTP(rec, req)
+
+	var resp struct {
+		Data int `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("cannot decode response: %v", err)
+	}
+
+	if want := 3*400 + 1000; resp.Data != want {
+		t.Errorf("total = %d, want %d", resp.Data, want)
+	}
+}
+
+func TestSubscriptionV2Mapping(t *testing.T) {
+	storage := &fakePostgresClient{subscriptions: []*api.Subscription{
+		{ServiceName: "Netflix", Price: 1000, UserID: "u1", StartDate: "07-2025", EndDate: "12-2025"},
+	}}
+	router := newSubscriptionsRouter(storage)
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/subscriptions/1", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("cannot decode response: %v", err)
+	}
+
+	if resp.Data["start_date"] != "2025-07" || resp.Data["end_date"] != "2025-12" {
+		t.Errorf("dates = %v, %v, want 2025-07, 2025-12", resp.Data["start_date"], resp.Data["end_date"])
+	}
+
+	body := `{"service_name":"Netflix","price":1000,"user_id":"u1","start_date":"2025-07"}`
+	req = httptest.NewRequest(http.MethodPost, "/v2/subscriptions", strings.NewReader(body))
+	rec = httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	if storage.saved == nil || storage.saved.StartDate != "07-2025" || storage.saved.EndDate != "" {
+		t.Errorf("saved = %+v, want start date 07-2025 and no end date", storage.saved)
+	}
+}
+
+func TestSubscriptionV2ListSkipsLegacyRows(t *testing.T) {
+	storage := &fakePostgresClient{subscriptions: []*api.Subscription{
+		{ServiceName: "Netflix", Price: 1000, UserID: "u1", StartDate: "2025-07"},
+		{ServiceName: "Spotify", Price: 300, UserID: "u1", StartDate: "07-2025", EndDate: "someday"},
+		{ServiceName: "Yandex Plus", Price: 400, UserID: "u1", StartDate: "07-2025"},
+	}}
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/subscriptions", nil)
+	rec := httptest.NewRecorder()
+
+	newSubscriptionsRouter(storage).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("cannot decode response: %v", err)
+	}
+
+	if len(resp.Data) != 1 || resp.Data[0]["service_name"] != "Yandex Plus" {
+		t.Errorf("data = %v, want only the subscription with valid dates", resp.Data)
+	}
+}
@@ -2,54 +2,91 @@ package handlers
 
 import (
 	"net/http"
-	"time"
 
 	"go.uber.org/zap"
 
-	"subscriptions/internal/api"
+	"subscriptions/internal/cache"
+	"subscriptions/internal/service"
 	"subscriptions/internal/storage/postgresClient"
 )
 
 // TotalPriceHandler godoc
 // @Summary Calculate total price of subscriptions
 // @Description Calculates the total price for subscriptions filtered by user_id and/or service_name during the specified date range.
+// @Description Callers authenticated as a regular user may only calculate it for their own subscriptions.
 // @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Produce json
 // @Param user_id query string false "User ID filter"
 // @Param service_name query string false "Service Name filter"
 // @Param start_date query string true "Start date in MM-YYYY format"
 // @Param end_date query string true "End date in MM-YYYY format"
-// @Success 200 {integer} int "Total price"
-// @Failure 400 {object} response
-// @Failure 500 {object} response
-// @Router /subscriptions/total-price [get]
-func TotalPriceHandler(logger *zap.Logger, pc postgresClient.PostgresClient) http.HandlerFunc {
+// @Success 200 {object} response{data=int} "Total price"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v1/subscriptions/total [get]
+func TotalPriceHandler(logger *zap.Logger, pc postgresClient.PostgresClient, aggregates *cache.Cache, rollups postgresClient.RollupClient) http.HandlerFunc {
+	return totalPriceHandler(logger, pc, aggregates, rollups, v1)
+}
+
+// TotalPriceV2Handler godoc
+// @Summary Calculate total price of subscriptions
+// @Description Calculates the total price for subscriptions filtered by user_id and/or service_name during the specified date range.
+// @Description Callers authenticated as a regular user may only calculate it for their own subscriptions.
+// @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query string false "User ID filter"
+// @Param service_name query string false "Service Name filter"
+// @Param start_date query string true "Start date in YYYY-MM format"
+// @Param end_date query string true "End date in YYYY-MM format"
+// @Success 200 {object} response{data=int} "Total price"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v2/subscriptions/total [get]
+func TotalPriceV2Handler(logger *zap.Logger, pc postgresClient.PostgresClient, aggregates *cache.Cache, rollups postgresClient.RollupClient) http.HandlerFunc {
+	return totalPriceHandler(logger, pc, aggregates, rollups, v2)
+}
+
+// totalPriceHandler calculates the total price for the period given in the month layout of the given API version,
+// caching it in aggregates and reading the periods covered by the monthly spend rollup from rollups.
+func totalPriceHandler(logger *zap.Logger, pc postgresClient.PostgresClient, aggregates *cache.Cache, rollups postgresClient.RollupClient, v *version) http.HandlerFunc {
+	subscriptions := service.NewSubscriptions(pc).WithCache(aggregates).WithRollups(rollups)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, serviceName, startDate, endDate := parseQueryParams(r)
 
-		startPeriod, err := time.Parse("01-2006", startDate)
+		startPeriod, err := v.parseMonth(startDate)
 		if err != nil {
-			writeResponseWithError(logger, w, http.StatusBadRequest, "invalid 'startDate' date format. Use MM-YYYY")
+			writeError(logger, w, r, invalid("invalid 'startDate' date format. Use "+v.monthFormat))
 			logger.Error("TotalPriceHandler: invalid startDate in query params", zap.String("startDate", startDate), zap.Error(err))
 			return
 		}
 
-		endPeriod, err := time.Parse("01-2006", endDate)
+		endPeriod, err := v.parseMonth(endDate)
 		if err != nil {
-			writeResponseWithError(logger, w, http.StatusBadRequest, "invalid 'endDate' date format. Use MM-YYYY")
+			writeError(logger, w, r, invalid("invalid 'endDate' date format. Use "+v.monthFormat))
 			logger.Error("TotalPriceHandler: invalid endDate in query params", zap.String("endDate", endDate), zap.Error(err))
 			return
 		}
 
-		subscriptions, err := pc.ListFilteredSubscriptions(userID, serviceName)
+		total, err := subscriptions.TotalPrice(r.Context(), userID, serviceName, startPeriod, endPeriod)
 		if err != nil {
-			logger.Error("TotalPriceHandler: failed to load subscriptions", zap.Error(err))
-			writeResponseWithError(logger, w, http.StatusInternalServerError, "cannot load subscriptions")
+			logger.Error("TotalPriceHandler: failed to calculate total price", zap.Error(err))
+			writeError(logger, w, r, err)
 			return
 		}
 
-		total := processSubscriptions(startPeriod, endPeriod, subscriptions)
-
 		writeJSONResponse(logger, w, http.StatusOK, total)
 	}
 }
@@ -63,47 +100,3 @@ func parseQueryParams(r *http.Request) (string, string, string, string) {
 
 	return userID, serviceName, startDate, endDate
 }
-
-// processSubscriptions returns a calculated total price for the specified time period.
-func processSubscriptions(startPeriod time.Time, endPeriod time.Time, subscriptions []*api.Subscription) int {
-	total := 0
-	for _, subscription := range subscriptions {
-		subscriptionStart, err := time.Parse("01-2006", subscription.StartDate)
-		if err != nil {
-			continue
-		}
-
-		subscriptionEnd := endPeriod
-		if subscription.EndDate != "" {
-			subscriptionEnd, err = time.Parse("01-2006", subscription.EndDate)
-			if err != nil {
-				continue
-			}
-		}
-
-		var intersectStart time.Time
-
-		if subscriptionStart.After(startPeriod) {
-			intersectStart = subscriptionStart
-		} else {
-			intersectStart = startPeriod
-		}
-
-		var intersectEnd time.Time
-
-		if subscriptionEnd.Before(endPeriod) {
-			intersectEnd = subscriptionEnd
-		} else {
-			intersectEnd = endPeriod
-		}
-
-		if !intersectStart.After(intersectEnd) {
-			years := intersectEnd.Year() - intersectStart.Year()
-			months := int(intersectEnd.Month()) - int(intersectStart.Month())
-			months = years*12 + months + 1
-			total += months * subscription.Price
-		}
-	}
-
-	return total
-}
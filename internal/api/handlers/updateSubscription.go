@@ -1,12 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"go.uber.org/zap"
 
-	"subscriptions/internal/api"
+	"subscriptions/internal/service"
 	"subscriptions/internal/storage/postgresClient"
 )
 
@@ -14,35 +13,76 @@ import (
 // @Summary Update a subscription by ID
 // @Description Updates subscription data for the given subscription ID.
 // @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Subscription ID"
 // @Param subscription body api.Subscription true "Subscription data to update"
 // @Success 200 {object} response
-// @Failure 400 {object} response
-// @Failure 500 {object} response
-// @Router /subscriptions/{id} [put]
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v1/subscriptions/{id} [put]
 func UpdateSubscriptionHandler(logger *zap.Logger, pc postgresClient.PostgresClient) func(http.ResponseWriter, *http.Request) {
+	return updateSubscriptionHandler(logger, pc, v1)
+}
+
+// UpdateSubscriptionV2Handler godoc
+// @Summary Update a subscription by ID
+// @Description Updates subscription data for the given subscription ID.
+// @Tags subscriptions
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param subscription body api.SubscriptionV2 true "Subscription data to update"
+// @Success 200 {object} response
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 504 {object} problem.Problem
+// @Router /v2/subscriptions/{id} [put]
+func UpdateSubscriptionV2Handler(logger *zap.Logger, pc postgresClient.PostgresClient) func(http.ResponseWriter, *http.Request) {
+	return updateSubscriptionHandler(logger, pc, v2)
+}
+
+// updateSubscriptionHandler updates the subscription with the one decoded by the given API version.
+func updateSubscriptionHandler(logger *zap.Logger, pc postgresClient.PostgresClient, v *version) func(http.ResponseWriter, *http.Request) {
+	subscriptions := service.NewSubscriptions(pc)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := parseIdParam(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(logger, w, r, err)
 			logger.Error("UpdateSubscriptionHandler: cannot get id from URL", zap.Error(err))
 			return
 		}
 
-		subscription := &api.Subscription{}
-
-		err = json.NewDecoder(r.Body).Decode(subscription)
+		subscription, err := v.decode(r.Body)
 		if err != nil {
-			writeResponseWithError(logger, w, http.StatusBadRequest, err.Error())
+			writeError(logger, w, r, bodyError(err))
 			logger.Error("UpdateSubscriptionHandler: cannot decode body", zap.Error(err))
 			return
 		}
 
-		err = pc.UpdateSubscription(id, subscription)
+		err = subscriptions.Update(r.Context(), id, subscription)
 		if err != nil {
-			writeResponseWithError(logger, w, http.StatusInternalServerError, err.Error())
+			writeError(logger, w, r, err)
 			logger.Error("UpdateSubscriptionHandler:", zap.Error(err))
 			return
 		}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// UpdateWebhookHandler godoc
+// @Summary Update a webhook by ID
+// @Description Replaces the URL, secret and event filter of the webhook with the given ID.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param webhook body api.Webhook true "Webhook data to update"
+// @Success 200 {object} response
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /v1/webhooks/{id} [put]
+// @Router /v2/webhooks/{id} [put]
+func UpdateWebhookHandler(logger *zap.Logger, wc postgresClient.WebhookClient) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIdParam(r)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("UpdateWebhookHandler: cannot get id from URL", zap.Error(err))
+			return
+		}
+
+		webhook := &api.Webhook{}
+
+		err = json.NewDecoder(r.Body).Decode(webhook)
+		if err != nil {
+			writeError(logger, w, r, bodyError(err))
+			logger.Error("UpdateWebhookHandler: cannot decode body", zap.Error(err))
+			return
+		}
+
+		err = validateWebhook(webhook)
+		if err != nil {
+			writeError(logger, w, r, validationFailed(err))
+			logger.Error("UpdateWebhookHandler: invalid webhook", zap.Error(err))
+			return
+		}
+
+		err = wc.UpdateWebhook(r.Context(), id, webhook)
+		if err != nil {
+			writeError(logger, w, r, err)
+			logger.Error("UpdateWebhookHandler:", zap.Error(err))
+			return
+		}
+
+		writeJSONResponse(logger, w, http.StatusOK, nil)
+	}
+}
@@ -2,18 +2,22 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
 )
 
-// response uses in writeJSONResponse and writeResponseWithError for structured response to HTTP client.
+// response uses in writeJSONResponse for structured response to HTTP client. Errors are written as problem details by writeError.
 type response struct {
-	Status  string      `json:"status"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
+	Status string      `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
 }
 
 // parseIdParam extracts and validates the id URL parameters from request path.
@@ -22,39 +26,43 @@ func parseIdParam(r *http.Request) (int, error) {
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return 0, err
+		return 0, invalid("invalid 'id', must be an integer")
 	}
 
 	return id, nil
 }
 
-// writeJSONResponse sets header as application/json and writes response to HTTP client with specified data and status code.
-func writeJSONResponse(logger *zap.Logger, w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+// validateWebhook checks that the webhook has an absolute http(s) URL, a secret and known event types.
+func validateWebhook(webhook *api.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid 'url', must be an absolute http(s) URL")
+	}
 
-	resp := response{
-		Status: http.StatusText(statusCode),
-		Data:   data,
+	if webhook.Secret == "" {
+		return fmt.Errorf("'secret' is required")
 	}
 
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		logger.Warn("writeJSONResponse: failed to encode response", zap.Error(err))
+	for _, event := range webhook.Events {
+		if !slices.Contains(api.EventTypes, event) {
+			return fmt.Errorf("unknown event type: %s", event)
+		}
 	}
+
+	return nil
 }
 
-// writeResponseWithError sets header as application/json and writes response about error.
-func writeResponseWithError(logger *zap.Logger, w http.ResponseWriter, statusCode int, message string) {
+// writeJSONResponse sets header as application/json and writes response to HTTP client with specified data and status code.
+func writeJSONResponse(logger *zap.Logger, w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
 	resp := response{
-		Message: message,
-		Status:  http.StatusText(statusCode),
+		Status: http.StatusText(statusCode),
+		Data:   data,
 	}
 
-	err := json.NewEncoder(w).Encode(resp)
-	if err != nil {
-		logger.Warn("writeResponseWithError: cannot send report to caller", zap.Error(err))
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Warn("writeJSONResponse: failed to encode response", zap.Error(err))
 	}
 }
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+)
+
+// version maps subscriptions between the model of an API version and the storage one,
+// so the handlers are shared by all versions.
+type version struct {
+	// monthLayout is the layout of the months in the query parameters,
+	// monthFormat its description for error messages.
+	monthLayout string
+	monthFormat string
+
+	decode func(io.Reader) (*api.Subscription, error)
+	encode func(*api.Subscription) (interface{}, error)
+}
+
+// v1 serves the subscriptions as stored, with MM-YYYY dates.
+var v1 = &version{
+	monthLayout: api.MonthLayout,
+	monthFormat: "MM-YYYY",
+	decode: func(r io.Reader) (*api.Subscription, error) {
+		subscription := &api.Subscription{}
+		if err := json.NewDecoder(r).Decode(subscription); err != nil {
+			return nil, err
+		}
+
+		return subscription, nil
+	},
+	encode: func(subscription *api.Subscription) (interface{}, error) {
+		return subscription, nil
+	},
+}
+
+// v2 serves the subscriptions as api.SubscriptionV2, with YYYY-MM months.
+var v2 = &version{
+	monthLayout: api.ISOMonthLayout,
+	monthFormat: "YYYY-MM",
+	decode: func(r io.Reader) (*api.Subscription, error) {
+		dto := &api.SubscriptionV2{}
+		if err := json.NewDecoder(r).Decode(dto); err != nil {
+			return nil, err
+		}
+
+		subscription := &api.Subscription{
+			ServiceName: dto.ServiceName,
+			Price:       dto.Price,
+			UserID:      dto.UserID,
+			StartDate:   dto.StartDate.Format(api.MonthLayout),
+		}
+		if dto.EndDate != nil {
+			subscription.EndDate = dto.EndDate.Format(api.MonthLayout)
+		}
+
+		return subscription, nil
+	},
+	encode: func(subscription *api.Subscription) (interface{}, error) {
+		startDate, err := api.ParseMonth(api.MonthLayout, subscription.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("stored start date: %w", err)
+		}
+
+		dto := &api.SubscriptionV2{
+			ServiceName: subscription.ServiceName,
+			Price:       subscription.Price,
+			UserID:      subscription.UserID,
+			StartDate:   startDate,
+		}
+
+		if subscription.EndDate != "" {
+			endDate, err := api.ParseMonth(api.MonthLayout, subscription.EndDate)
+			if err != nil {
+				return nil, fmt.Errorf("stored end date: %w", err)
+			}
+
+			dto.EndDate = &endDate
+		}
+
+		return dto, nil
+	},
+}
+
+// parseMonth parses a month given in a query parameter of the version.
+func (v *version) parseMonth(value string) (time.Time, error) {
+	return time.Parse(v.monthLayout, value)
+}
+
+// encodeAll maps all subscriptions to the model of the version. Stored subscriptions which cannot be mapped,
+// such as legacy rows with malformed dates saved before the subscriptions were validated, are logged and skipped,
+// so a single one does not fail the whole list.
+func (v *version) encodeAll(logger *zap.Logger, subscriptions []*api.Subscription) []interface{} {
+	res := make([]interface{}, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		dto, err := v.encode(subscription)
+		if err != nil {
+			logger.Warn("encodeAll: skipping subscription which cannot be encoded",
+				zap.String("user_id", subscription.UserID),
+				zap.String("service_name", subscription.ServiceName),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		res = append(res, dto)
+	}
+
+	return res
+}
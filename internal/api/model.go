@@ -1,9 +1,25 @@
 package api
 
+import "time"
+
 // HttpServer defines the configuration parameters for the HTTP server.
+// A zero timeout means no timeout. MaxBodyBytes limits the request bodies of the routes accepting one.
+// The server serves TLS when TLSCertFile and TLSKeyFile are set, reloading the certificate
+// every TLSReloadInterval if the files have changed.
 type HttpServer struct {
-	Host string `env:"HTTP_HOST"`
-	Port int    `env:"HTTP_PORT"`
+	Host string `env:"HTTP_HOST" env-default:"0.0.0.0"`
+	Port int    `env:"HTTP_PORT" env-default:"8081"`
+
+	ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" env-default:"5s"`
+	ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" env-default:"30s"`
+	WriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" env-default:"30s"`
+	IdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" env-default:"2m"`
+	MaxHeaderBytes    int           `env:"HTTP_MAX_HEADER_BYTES" env-default:"65536"`
+	MaxBodyBytes      int64         `env:"HTTP_MAX_BODY_BYTES" env-default:"1048576"`
+
+	TLSCertFile       string        `env:"HTTP_TLS_CERT_FILE"`
+	TLSKeyFile        string        `env:"HTTP_TLS_KEY_FILE"`
+	TLSReloadInterval time.Duration `env:"HTTP_TLS_RELOAD_INTERVAL" env-default:"1m"`
 }
 
 // Subscription represents a user's subscription to a service.
@@ -15,3 +31,105 @@ type Subscription struct {
 	StartDate   string `json:"start_date"`
 	EndDate     string `json:"end_date,omitempty"`
 }
+
+// MonthlyCost is the total price of subscriptions for a month.
+type MonthlyCost struct {
+	Month Month `json:"month" swaggertype:"string" example:"2025-07"`
+	Total int   `json:"total"`
+}
+
+// SubscriptionV2 represents a user's subscription in the v2 API, which types the dates as months.
+// EndDate is omitted for subscriptions without an end.
+type SubscriptionV2 struct {
+	ServiceName string `json:"service_name"`
+	Price       int    `json:"price"`
+	UserID      string `json:"user_id"`
+	StartDate   Month  `json:"start_date" swaggertype:"string" example:"2025-07"`
+	EndDate     *Month `json:"end_date,omitempty" swaggertype:"string" example:"2025-12"`
+}
+
+// Subscription lifecycle event types delivered to webhooks.
+const (
+	EventSubscriptionCreated = "subscription.created"
+	EventSubscriptionUpdated = "subscription.updated"
+	EventSubscriptionDeleted = "subscription.deleted"
+	EventSubscriptionExpired = "subscription.expired"
+)
+
+// EventTypes lists all subscription lifecycle event types.
+var EventTypes = []string{
+	EventSubscriptionCreated,
+	EventSubscriptionUpdated,
+	EventSubscriptionDeleted,
+	EventSubscriptionExpired,
+}
+
+// Event represents a subscription lifecycle event as delivered to webhooks and event stream clients.
+// Data holds the subscription state after the change (or before it, for deletions).
+type Event struct {
+	ID             int          `json:"id"`
+	TenantID       string       `json:"tenant_id"`
+	Type           string       `json:"type"`
+	SubscriptionID int          `json:"subscription_id"`
+	OccurredAt     time.Time    `json:"occurred_at"`
+	Data           Subscription `json:"data"`
+}
+
+// Webhook represents an endpoint registered to receive subscription lifecycle events.
+// An empty Events list subscribes the endpoint to all event types.
+// Secret is used to sign deliveries and is never returned to HTTP clients.
+type Webhook struct {
+	ID     int      `json:"id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+}
+
+// WebhookDelivery represents the state of delivering a single event to a webhook.
+type WebhookDelivery struct {
+	ID            int        `json:"id"`
+	WebhookID     int        `json:"webhook_id"`
+	EventID       int        `json:"event_id"`
+	EventType     string     `json:"event_type"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	StatusCode    *int       `json:"status_code,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// APIKey represents a key used to authenticate API clients.
+// Key holds the plaintext key and is only returned once, when the key is created;
+// only its hash is stored.
+type APIKey struct {
+	ID         int        `json:"id"`
+	TenantID   string     `json:"tenant_id,omitempty"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Permission is an action a role may perform, either on the data of all users or only on the caller's own data.
+type Permission struct {
+	Action   string `json:"action"`
+	AllUsers bool   `json:"all_users"`
+}
+
+// Role describes a role and the permissions it grants.
+type Role struct {
+	Name        string       `json:"name"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// RoleAssignment grants a role to a subject, which is the user id carried by the bearer token.
+type RoleAssignment struct {
+	Subject   string    `json:"subject"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
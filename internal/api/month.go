@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// MonthLayout is the MM-YYYY layout of the subscription dates of the v1 API and the storage.
+	MonthLayout = "01-2006"
+
+	// ISOMonthLayout is the ISO 8601 YYYY-MM layout of the Month values of the v2 API.
+	ISOMonthLayout = "2006-01"
+)
+
+// Month is a calendar month, encoded in JSON as an ISO 8601 year and month, e.g. "2025-07".
+type Month struct {
+	time.Time
+}
+
+// ParseMonth parses the month in the given layout.
+func ParseMonth(layout string, value string) (Month, error) {
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return Month{}, fmt.Errorf("invalid month %q", value)
+	}
+
+	return Month{Time: t}, nil
+}
+
+// String returns the month in the ISO 8601 layout.
+func (m Month) String() string {
+	return m.Format(ISOMonthLayout)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (m Month) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *Month) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("month must be a string in YYYY-MM format")
+	}
+
+	month, err := ParseMonth(ISOMonthLayout, value)
+	if err != nil {
+		return fmt.Errorf("%w, use YYYY-MM", err)
+	}
+
+	*m = month
+
+	return nil
+}
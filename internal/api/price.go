@@ -0,0 +1,61 @@
+package api
+
+import "time"
+
+// TotalPrice returns the total price of the subscriptions for the months from startPeriod through endPeriod.
+// Subscriptions without an end date are charged through endPeriod; those with invalid dates are skipped.
+func TotalPrice(startPeriod time.Time, endPeriod time.Time, subscriptions []*Subscription) int {
+	total := 0
+	for _, subscription := range subscriptions {
+		subscriptionStart, err := time.Parse(MonthLayout, subscription.StartDate)
+		if err != nil {
+			continue
+		}
+
+		subscriptionEnd := endPeriod
+		if subscription.EndDate != "" {
+			subscriptionEnd, err = time.Parse(MonthLayout, subscription.EndDate)
+			if err != nil {
+				continue
+			}
+		}
+
+		var intersectStart time.Time
+
+		if subscriptionStart.After(startPeriod) {
+			intersectStart = subscriptionStart
+		} else {
+			intersectStart = startPeriod
+		}
+
+		var intersectEnd time.Time
+
+		if subscriptionEnd.Before(endPeriod) {
+			intersectEnd = subscriptionEnd
+		} else {
+			intersectEnd = endPeriod
+		}
+
+		if !intersectStart.After(intersectEnd) {
+			years := intersectEnd.Year() - intersectStart.Year()
+			months := int(intersectEnd.Month()) - int(intersectStart.Month())
+			months = years*12 + months + 1
+			total += months * subscription.Price
+		}
+	}
+
+	return total
+}
+
+// MonthlyCosts returns the total price of the subscriptions for every month from startPeriod through endPeriod.
+func MonthlyCosts(startPeriod time.Time, endPeriod time.Time, subscriptions []*Subscription) []MonthlyCost {
+	var res []MonthlyCost
+	for month := startPeriod; !month.After(endPeriod); month = month.AddDate(0, 1, 0) {
+		res = append(res, MonthlyCost{
+			Month: Month{Time: month},
+			Total: TotalPrice(month, month, subscriptions),
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,55 @@
+package apidocs
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	httpSwagger "github.com/swaggo/http-swagger/v2"
+	"go.uber.org/zap"
+
+	"subscriptions/docs"
+)
+
+const (
+	// SpecPath is the path the OpenAPI specification is served at.
+	SpecPath = "/openapi.json"
+
+	// UIPath is the path the Swagger UI is served under.
+	UIPath = "/docs"
+)
+
+// SpecHandler serves the OpenAPI specification generated from the annotations of the handlers.
+// The router strips the extension of the path with middleware.URLFormat, so the handler is routed
+// at the path without it and serves the JSON format only.
+func SpecHandler(logger *zap.Logger) http.HandlerFunc {
+	spec := []byte(docs.SwaggerInfo.ReadDoc())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string); format != "json" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if _, err := w.Write(spec); err != nil {
+			logger.Warn("OpenAPI: cannot send specification to caller", zap.Error(err))
+		}
+	}
+}
+
+// UIHandler serves the Swagger UI embedded in the binary, rendering the specification served at SpecPath.
+// It must be routed at UIPath and under it.
+func UIHandler() http.HandlerFunc {
+	ui := httpSwagger.Handler(httpSwagger.URL(SpecPath))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == UIPath || r.URL.Path == UIPath+"/" {
+			http.Redirect(w, r, UIPath+"/index.html", http.StatusMovedPermanently)
+			return
+		}
+
+		ui(w, r)
+	}
+}
@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/problem"
+	"subscriptions/internal/storage/postgresClient"
+	"subscriptions/internal/tenant"
+)
+
+// principalKey is the context key under which the authenticated Principal is stored.
+type principalKey struct{}
+
+// userScopeKey is the context key under which the user the request is restricted to is stored.
+type userScopeKey struct{}
+
+// Authenticator validates API keys and bearer tokens.
+type Authenticator struct {
+	storage     Storage
+	logger      *zap.Logger
+	enabled     bool
+	adminKey    string
+	adminTenant string
+	jwks        *JWKS
+	parser      *jwt.Parser
+	rolesClaim  string
+	adminRole   string
+	tenantClaim string
+}
+
+// New creates and returns a new Authenticator instance, applying defaults to the unset config fields
+// and loading the JWKS if it is configured.
+func New(config *Config, storage Storage, logger *zap.Logger) (*Authenticator, error) {
+	if config.JWKSRefresh == 0 {
+		config.JWKSRefresh = DefaultJWKSRefresh
+	}
+
+	if config.RolesClaim == "" {
+		config.RolesClaim = DefaultRolesClaim
+	}
+
+	if config.AdminRole == "" {
+		config.AdminRole = DefaultAdminRole
+	}
+
+	if config.TenantClaim == "" {
+		config.TenantClaim = DefaultTenantClaim
+	}
+
+	authenticator := &Authenticator{
+		storage:     storage,
+		logger:      logger,
+		enabled:     config.Enabled,
+		adminKey:    config.AdminKey,
+		adminTenant: config.AdminTenant,
+		rolesClaim:  config.RolesClaim,
+		adminRole:   config.AdminRole,
+		tenantClaim: config.TenantClaim,
+	}
+
+	if config.JWKS != "" {
+		jwks, err := NewJWKS(config.JWKS, config.JWKSRefresh)
+		if err != nil {
+			return nil, fmt.Errorf("New: %w", err)
+		}
+
+		options := []jwt.ParserOption{
+			jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+			jwt.WithExpirationRequired(),
+		}
+
+		if config.Issuer != "" {
+			options = append(options, jwt.WithIssuer(config.Issuer))
+		}
+
+		if config.Audience != "" {
+			options = append(options, jwt.WithAudience(config.Audience))
+		}
+
+		authenticator.jwks = jwks
+		authenticator.parser = jwt.NewParser(options...)
+	}
+
+	return authenticator, nil
+}
+
+// Middleware authenticates the request by the bearer token in the Authorization header or the key
+// in the X-API-Key header and stores the Principal in its context, binding the request to the tenant of the credentials.
+// Requests without valid credentials are rejected with 401. If authentication is disabled, every request passes through.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		ctx, err := a.Authenticate(r.Context(), token, r.Header.Get(APIKeyHeader))
+		if err != nil {
+			problem.Write(a.logger, w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// Authenticate resolves the Principal of the bearer token or, if there is none, of the API key and returns
+// a copy of ctx carrying it, bound to the tenant of the credentials. Credentials which are not bound to a valid tenant
+// are rejected, so callers can never select the tenant themselves. The errors are *problem.Error values.
+// If authentication is disabled, ctx is returned as is.
+func (a *Authenticator) Authenticate(ctx context.Context, token string, key string) (context.Context, error) {
+	if !a.enabled {
+		return ctx, nil
+	}
+
+	if token != "" {
+		principal, err := a.authenticateToken(token)
+		if err != nil {
+			a.logger.Warn("Authenticator: invalid bearer token", zap.Error(err))
+			return nil, problem.New(problem.CodeUnauthenticated, "invalid bearer token")
+		}
+
+		return withPrincipal(ctx, principal)
+	}
+
+	if key == "" {
+		return nil, problem.New(problem.CodeUnauthenticated, "missing credentials")
+	}
+
+	principal, err := a.authenticate(ctx, key)
+	if err != nil {
+		if errors.Is(err, postgresClient.ErrAPIKeyNotFound) {
+			return nil, problem.New(problem.CodeUnauthenticated, "invalid api key")
+		}
+
+		a.logger.Error("Authenticator: cannot authenticate api key", zap.Error(err))
+		return nil, problem.Wrap(problem.CodeInternal, "cannot authenticate api key", err)
+	}
+
+	return withPrincipal(ctx, principal)
+}
+
+// authenticate resolves the Principal of the given key, checking the static admin key first.
+func (a *Authenticator) authenticate(ctx context.Context, key string) (*Principal, error) {
+	if a.adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(a.adminKey)) == 1 {
+		return &Principal{Name: "admin", TenantID: a.adminTenant, Scopes: Scopes}, nil
+	}
+
+	apiKey, err := a.storage.AuthenticateAPIKey(ctx, HashKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		Name:     apiKey.Name,
+		KeyID:    apiKey.ID,
+		TenantID: apiKey.TenantID,
+		Scopes:   apiKey.Scopes,
+	}, nil
+}
+
+// authenticateToken verifies the bearer token against the JWKS and resolves its Principal.
+// The "sub" claim becomes the user id, the tenant claim the tenant and the roles claim the roles,
+// with the admin role mapped to RoleAdmin.
+func (a *Authenticator) authenticateToken(token string) (*Principal, error) {
+	if a.jwks == nil {
+		return nil, fmt.Errorf("bearer tokens are not accepted: jwks is not configured")
+	}
+
+	claims := jwt.MapClaims{}
+
+	_, err := a.parser.ParseWithClaims(token, claims, a.jwks.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("missing sub claim")
+	}
+
+	roles := claimStrings(claims[a.rolesClaim])
+	for i, role := range roles {
+		if role == a.adminRole {
+			roles[i] = RoleAdmin
+		}
+	}
+
+	tenantID, _ := claims[a.tenantClaim].(string)
+
+	return &Principal{
+		Name:     subject,
+		TenantID: tenantID,
+		UserID:   subject,
+		Roles:    roles,
+	}, nil
+}
+
+// WithUserScope returns a copy of ctx restricting the request to the data of userID,
+// or allowing access to the data of all users if userID is empty.
+func WithUserScope(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userScopeKey{}, userID)
+}
+
+// ScopedUserID returns the user whose data the caller of the request is restricted to, see UserScope.
+func ScopedUserID(r *http.Request) string {
+	return UserScope(r.Context())
+}
+
+// UserScope returns the user whose data the caller is restricted to, or an empty string if it may access
+// the data of all users (including when authentication is disabled).
+// Unless authorization decided otherwise with WithUserScope, users are restricted to their own data.
+func UserScope(ctx context.Context) string {
+	if userID, ok := ctx.Value(userScopeKey{}).(string); ok {
+		return userID
+	}
+
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return principal.UserID
+}
+
+// HasScope reports whether the principal was granted the given scope.
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// WithPrincipal returns a copy of ctx carrying the given Principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// withPrincipal returns a copy of ctx carrying the given Principal and bound to its tenant.
+// Principals which are not bound to a valid tenant are rejected with 403; in particular, the pseudo tenant
+// of the background jobs is never accepted from credentials.
+func withPrincipal(ctx context.Context, principal *Principal) (context.Context, error) {
+	if !tenant.Valid(principal.TenantID) {
+		return nil, problem.New(problem.CodeForbidden, "credentials are not bound to a valid tenant")
+	}
+
+	return WithPrincipal(tenant.WithTenant(ctx, principal.TenantID), principal), nil
+}
+
+// PrincipalFromContext returns the Principal stored in ctx by the Middleware.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// GenerateKey returns a new random API key together with its display prefix and the hash to store.
+func GenerateKey() (string, string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("GenerateKey: %w", err)
+	}
+
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	return key, key[:len(keyPrefix)+8], HashKey(key), nil
+}
+
+// HashKey returns the hex-encoded SHA-256 hash of the key, under which the key is stored.
+// Keys are long random strings, so a fast unsalted hash is sufficient.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// claimStrings converts a claim holding a string or a list of strings into a slice.
+func claimStrings(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		res := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	default:
+		return nil
+	}
+}
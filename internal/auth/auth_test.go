@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/problem"
+	"subscriptions/internal/storage/postgresClient"
+	"subscriptions/internal/tenant"
+)
+
+// fakeStorage keeps the active API keys by their hashes, like the storage filters out the revoked ones.
+type fakeStorage struct {
+	keys map[string]*api.APIKey
+	err  error
+}
+
+func (f *fakeStorage) AuthenticateAPIKey(_ context.Context, hash string) (*api.APIKey, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	key, ok := f.keys[hash]
+	if !ok || key.RevokedAt != nil {
+		return nil, postgresClient.ErrAPIKeyNotFound
+	}
+
+	return key, nil
+}
+
+func TestHashKey(t *testing.T) {
+	if got, want := HashKey("sk_test"), "12b2820cf1639904311da5771de1e5bb65c77073fdc7c555df395942df42896b"; got != want {
+		t.Errorf("HashKey() = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	seen := make(map[string]bool)
+
+	for range 3 {
+		key, prefix, hash, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(key, keyPrefix) || len(key) != len(keyPrefix)+43 {
+			t.Errorf("GenerateKey() key = %q, want %q and 32 random bytes", key, keyPrefix)
+		}
+
+		if prefix != key[:len(keyPrefix)+8] {
+			t.Errorf("GenerateKey() prefix = %q, want the start of %q", prefix, key)
+		}
+
+		if hash != HashKey(key) {
+			t.Errorf("GenerateKey() hash = %q, want %q", hash, HashKey(key))
+		}
+
+		if seen[key] {
+			t.Errorf("GenerateKey() = %q twice", key)
+		}
+		seen[key] = true
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	key, _, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revokedKey, _, revokedHash, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revokedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	keys := map[string]*api.APIKey{
+		hash:                 {ID: 7, Name: "ci", TenantID: "acme", Scopes: []string{ScopeSubscriptionsRead}},
+		revokedHash:          {ID: 8, Name: "old", TenantID: "acme", Scopes: []string{ScopeSubscriptionsRead}, RevokedAt: &revokedAt},
+		HashKey("sk_orphan"): {ID: 9, Name: "orphan", Scopes: []string{ScopeSubscriptionsRead}},
+	}
+
+	tests := []struct {
+		name      string
+		config    Config
+		storage   *fakeStorage
+		key       string
+		want      *Principal
+		wantCode  problem.Code
+		unchanged bool
+	}{
+		{
+			name:    "valid key",
+			config:  Config{Enabled: true},
+			storage: &fakeStorage{keys: keys},
+			key:     key,
+			want:    &Principal{Name: "ci", KeyID: 7, TenantID: "acme", Scopes: []string{ScopeSubscriptionsRead}},
+		},
+		{
+			name:     "unknown key",
+			config:   Config{Enabled: true},
+			storage:  &fakeStorage{keys: keys},
+			key:      "sk_unknown",
+			wantCode: problem.CodeUnauthenticated,
+		},
+		{
+			name:     "revoked key",
+			config:   Config{Enabled: true},
+			storage:  &fakeStorage{keys: keys},
+			key:      revokedKey,
+			wantCode: problem.CodeUnauthenticated,
+		},
+		{
+			name:     "missing credentials",
+			config:   Config{Enabled: true},
+			storage:  &fakeStorage{keys: keys},
+			wantCode: problem.CodeUnauthenticated,
+		},
+		{
+			name:     "key without a tenant",
+			config:   Config{Enabled: true},
+			storage:  &fakeStorage{keys: keys},
+			key:      "sk_orphan",
+			wantCode: problem.CodeForbidden,
+		},
+		{
+			name:     "storage failure",
+			config:   Config{Enabled: true},
+			storage:  &fakeStorage{err: errors.New("connection refused")},
+			key:      key,
+			wantCode: problem.CodeInternal,
+		},
+		{
+			name:    "admin key",
+			config:  Config{Enabled: true, AdminKey: "sk_admin", AdminTenant: "acme"},
+			storage: &fakeStorage{err: errors.New("not called")},
+			key:     "sk_admin",
+			want:    &Principal{Name: "admin", TenantID: "acme", Scopes: Scopes},
+		},
+		{
+			name:      "disabled",
+			storage:   &fakeStorage{err: errors.New("not called")},
+			key:       "sk_unknown",
+			unchanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New(&tt.config, tt.storage, zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, err := a.Authenticate(context.Background(), "", tt.key)
+			if tt.wantCode != "" {
+				var e *problem.Error
+				if !errors.As(err, &e) || e.Code != tt.wantCode {
+					t.Fatalf("Authenticate() error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+
+			principal, ok := PrincipalFromContext(ctx)
+			if tt.unchanged {
+				if ok {
+					t.Errorf("Authenticate() principal = %+v, want none while disabled", principal)
+				}
+				return
+			}
+
+			if !ok || principal.Name != tt.want.Name || principal.KeyID != tt.want.KeyID ||
+				principal.TenantID != tt.want.TenantID || !slices.Equal(principal.Scopes, tt.want.Scopes) {
+				t.Fatalf("Authenticate() principal = %+v, want %+v", principal, tt.want)
+			}
+
+			if tenantID, _ := tenant.FromContext(ctx); tenantID != tt.want.TenantID {
+				t.Errorf("Authenticate() tenant = %q, want %q", tenantID, tt.want.TenantID)
+			}
+		})
+	}
+}
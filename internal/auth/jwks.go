@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// jwksTimeout defines the maximum duration of fetching a remote JWKS.
+	jwksTimeout = 10 * time.Second
+
+	// jwksRetry defines the minimum interval between two loads of a remote JWKS, successful or not.
+	jwksRetry = time.Minute
+)
+
+// JWKS holds the public keys used to verify bearer tokens, loaded from a local file or an URL.
+// Keys fetched from an URL are refreshed periodically and whenever a token refers to an unknown key id,
+// but at most once per jwksRetry, by the request which finds them due; the other requests use the current keys.
+type JWKS struct {
+	source  string
+	refresh time.Duration
+	client  *http.Client
+	now     func() time.Time
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	loadedAt    time.Time
+	attemptedAt time.Time
+}
+
+// jwk is a single JSON Web Key as defined by RFC 7517.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewJWKS loads the key set from source, which is either a file path or an http(s) URL.
+func NewJWKS(source string, refresh time.Duration) (*JWKS, error) {
+	jwks := &JWKS{
+		source:  source,
+		refresh: refresh,
+		client:  &http.Client{Timeout: jwksTimeout},
+		now:     time.Now,
+	}
+
+	jwks.attemptedAt = jwks.now()
+	if err := jwks.load(); err != nil {
+		return nil, err
+	}
+
+	return jwks, nil
+}
+
+// Keyfunc returns the key referred to by the token's "kid" header, for use with jwt.Parse.
+func (j *JWKS) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if j.refresh > 0 && j.claim(j.refresh) {
+		// The current keys stay in use if the refresh fails.
+		_ = j.load()
+	}
+
+	if key, ok := j.lookup(kid); ok {
+		return key, nil
+	}
+
+	if j.claim(0) {
+		if err := j.load(); err != nil {
+			return nil, err
+		}
+
+		if key, ok := j.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key id: %q", kid)
+}
+
+// lookup returns the key with the given id. A token without id matches the only key of a single-key set.
+func (j *JWKS) lookup(kid string) (crypto.PublicKey, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+
+	key, ok := j.keys[kid]
+
+	return key, ok
+}
+
+// claim reports whether the caller should reload the remote keys loaded longer than age ago, recording the attempt
+// so that the keys are not reloaded again for jwksRetry, whether the load succeeds or fails.
+func (j *JWKS) claim(age time.Duration) bool {
+	if !j.isRemote() {
+		return false
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+	if now.Sub(j.loadedAt) <= age || now.Sub(j.attemptedAt) < jwksRetry {
+		return false
+	}
+
+	j.attemptedAt = now
+
+	return true
+}
+
+// isRemote reports whether the key set is fetched over http(s).
+func (j *JWKS) isRemote() bool {
+	return strings.HasPrefix(j.source, "http://") || strings.HasPrefix(j.source, "https://")
+}
+
+// load reads and parses the key set, replacing the current keys.
+func (j *JWKS) load() error {
+	var data []byte
+	var err error
+
+	if j.isRemote() {
+		data, err = j.fetch()
+	} else {
+		data, err = os.ReadFile(j.source)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load jwks: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err = json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return fmt.Errorf("failed to parse jwk %q: %w", k.Kid, err)
+		}
+
+		keys[k.Kid] = key
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.loadedAt = j.now()
+	j.mu.Unlock()
+
+	return nil
+}
+
+// fetch downloads the key set from the remote source.
+func (j *JWKS) fetch() ([]byte, error) {
+	resp, err := j.client.Get(j.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// publicKey converts the JWK into an RSA, ECDSA or Ed25519 public key.
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 key size: %d", len(x))
+		}
+
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+// decodeBigInt decodes a base64url-encoded big-endian integer.
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,289 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/problem"
+)
+
+// jwksServer serves the key set of its keys, failing while status is set, and counts the fetches.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    []jwk
+	status  int
+	fetches int
+}
+
+func newJWKSServer(t *testing.T, keys ...jwk) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.fetches++
+		if s.status != 0 {
+			w.WriteHeader(s.status)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string][]jwk{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// set replaces the served keys and the failure status.
+func (s *jwksServer) set(status int, keys ...jwk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status, s.keys = status, keys
+}
+
+func (s *jwksServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fetches
+}
+
+// newSigningKey returns a new Ed25519 key together with its JWK.
+func newSigningKey(t *testing.T, kid string) (ed25519.PrivateKey, jwk) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return private, jwk{Kty: "OKP", Crv: "Ed25519", Kid: kid, Use: "sig", X: base64.RawURLEncoding.EncodeToString(public)}
+}
+
+// sign returns a token with the given claims signed by the key with the given id.
+func sign(t *testing.T, key ed25519.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+
+	res, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	key, public := newSigningKey(t, "k1")
+	other, _ := newSigningKey(t, "k2")
+	server := newJWKSServer(t, public)
+
+	a, err := New(&Config{Enabled: true, JWKS: server.URL, Issuer: "https://id.example.com", Audience: "subscriptions"}, &fakeStorage{}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		res := jwt.MapClaims{
+			"sub":       "alice",
+			"iss":       "https://id.example.com",
+			"aud":       "subscriptions",
+			"exp":       time.Now().Add(time.Hour).Unix(),
+			"tenant_id": "acme",
+			"roles":     []string{"admin", "viewer"},
+		}
+		change(res)
+		return res
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		want     *Principal
+		wantCode problem.Code
+	}{
+		{
+			name:  "valid",
+			token: sign(t, key, "k1", claims(func(jwt.MapClaims) {})),
+			want:  &Principal{Name: "alice", TenantID: "acme", UserID: "alice", Roles: []string{RoleAdmin, "viewer"}},
+		},
+		{
+			name:     "expired",
+			token:    sign(t, key, "k1", claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })),
+			wantCode: problem.CodeUnauthenticated,
+		},
+		{
+			name:     "without expiry",
+			token:    sign(t, key, "k1", claims(func(c jwt.MapClaims) { delete(c, "exp") })),
+			wantCode: problem.CodeUnauthenticated,
+		},
+		{
+			name:     "other audience",
+			token:    sign(t, key, "k1", claims(func(c jwt.MapClaims) { c["aud"] = "billing" })),
+			wantCode: problem.CodeUnauthenticated,
+		},
+		{
+			name:     "other issuer",
+			token:    sign(t, key, "k1", claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })),
+			wantCode: problem.CodeUnauthenticated,
+		},
+		{
+			name:     "unknown key id",
+			token:    sign(t, other, "k2", claims(func(jwt.MapClaims) {})),
+			wantCode: problem.CodeUnauthenticated,
+		},
+		{
+			name:     "signed by another key",
+			token:    sign(t, other, "k1", claims(func(jwt.MapClaims) {})),
+			wantCode: problem.CodeUnauthenticated,
+		},
+		{
+			name:     "without tenant",
+			token:    sign(t, key, "k1", claims(func(c jwt.MapClaims) { delete(c, "tenant_id") })),
+			wantCode: problem.CodeForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := a.Authenticate(context.Background(), tt.token, "")
+			if tt.wantCode != "" {
+				var e *problem.Error
+				if !errors.As(err, &e) || e.Code != tt.wantCode {
+					t.Fatalf("Authenticate() error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+
+			principal, ok := PrincipalFromContext(ctx)
+			if !ok || principal.Name != tt.want.Name || principal.TenantID != tt.want.TenantID ||
+				principal.UserID != tt.want.UserID || !slices.Equal(principal.Roles, tt.want.Roles) {
+				t.Errorf("Authenticate() principal = %+v, want %+v", principal, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	oldKey, oldPublic := newSigningKey(t, "old")
+	newKey, newPublic := newSigningKey(t, "new")
+	server := newJWKSServer(t, oldPublic)
+
+	a, err := New(&Config{Enabled: true, JWKS: server.URL, JWKSRefresh: time.Hour}, &fakeStorage{}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	a.jwks.now = func() time.Time { return now }
+
+	claims := jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(24 * time.Hour).Unix(), "tenant_id": "acme"}
+	oldToken := sign(t, oldKey, "old", claims)
+	newToken := sign(t, newKey, "new", claims)
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		status  int
+		keys    []jwk
+		token   string
+		allowed bool
+		fetches int
+	}{
+		{
+			name:    "current key",
+			keys:    []jwk{oldPublic},
+			token:   oldToken,
+			allowed: true,
+			fetches: 1,
+		},
+		{
+			name:    "rotated key right after the load",
+			keys:    []jwk{oldPublic, newPublic},
+			token:   newToken,
+			fetches: 1,
+		},
+		{
+			name:    "rotated key is loaded for its unknown id",
+			advance: jwksRetry,
+			keys:    []jwk{oldPublic, newPublic},
+			token:   newToken,
+			allowed: true,
+			fetches: 2,
+		},
+		{
+			name:    "failed refresh keeps the keys",
+			advance: time.Hour + time.Second,
+			status:  http.StatusBadGateway,
+			token:   oldToken,
+			allowed: true,
+			fetches: 3,
+		},
+		{
+			name:    "refresh is not retried right after the failure",
+			advance: jwksRetry - time.Second,
+			status:  http.StatusBadGateway,
+			token:   newToken,
+			allowed: true,
+			fetches: 3,
+		},
+		{
+			name:    "nor for an unknown id",
+			status:  http.StatusBadGateway,
+			token:   sign(t, newKey, "unknown", claims),
+			fetches: 3,
+		},
+		{
+			name:    "retired key is dropped by the next refresh",
+			advance: time.Second,
+			keys:    []jwk{newPublic},
+			token:   oldToken,
+			fetches: 4,
+		},
+		{
+			name:    "new key after the refresh",
+			keys:    []jwk{newPublic},
+			token:   newToken,
+			allowed: true,
+			fetches: 4,
+		},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		server.set(step.status, step.keys...)
+
+		_, err := a.Authenticate(context.Background(), step.token, "")
+		if allowed := err == nil; allowed != step.allowed {
+			t.Fatalf("%s: Authenticate() error = %v, want allowed = %v", step.name, err, step.allowed)
+		}
+
+		if got := server.count(); got != step.fetches {
+			t.Errorf("%s: fetches = %d, want %d", step.name, got, step.fetches)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"subscriptions/internal/api"
+)
+
+const (
+	// ScopeSubscriptionsRead allows reading subscriptions and streaming their changes.
+	ScopeSubscriptionsRead = "subscriptions:read"
+
+	// ScopeSubscriptionsWrite allows creating, updating and deleting subscriptions.
+	ScopeSubscriptionsWrite = "subscriptions:write"
+
+	// ScopeReportsRead allows running aggregate reports such as the total price.
+	ScopeReportsRead = "reports:read"
+
+	// ScopeWebhooksManage allows managing webhooks and reading their delivery log.
+	ScopeWebhooksManage = "webhooks:manage"
+
+	// ScopeKeysManage allows managing API keys.
+	ScopeKeysManage = "keys:manage"
+
+	// ScopeRolesManage allows managing role assignments.
+	ScopeRolesManage = "roles:manage"
+
+	// RoleAdmin is the role granting every permission on the data of all users.
+	RoleAdmin = "admin"
+
+	// APIKeyHeader is the request header carrying the API key.
+	APIKeyHeader = "X-API-Key"
+
+	// DefaultRolesClaim defines the token claim listing the roles of the user.
+	DefaultRolesClaim = "roles"
+
+	// DefaultAdminRole defines the token role which is mapped to RoleAdmin.
+	DefaultAdminRole = "admin"
+
+	// DefaultTenantClaim defines the token claim binding the user to a tenant.
+	DefaultTenantClaim = "tenant_id"
+
+	// DefaultJWKSRefresh defines how often a JWKS fetched from an URL is reloaded.
+	DefaultJWKSRefresh = time.Hour
+
+	// keyPrefix marks the API keys issued by the service.
+	keyPrefix = "sk_"
+)
+
+// Scopes lists all scopes that may be granted to an API key.
+var Scopes = []string{
+	ScopeSubscriptionsRead,
+	ScopeSubscriptionsWrite,
+	ScopeReportsRead,
+	ScopeWebhooksManage,
+	ScopeKeysManage,
+	ScopeRolesManage,
+}
+
+// Config defines the authentication settings.
+// AdminKey is an optional static key granted every scope in AdminTenant. It is meant only to bootstrap the first
+// API keys of a deployment and should be unset again once they are issued.
+// JWKS is a file path or an URL of the keys verifying bearer tokens; bearer tokens are rejected if it is not set.
+// Every credential must be bound to a tenant: tokens without a valid tenant claim are rejected.
+type Config struct {
+	Enabled     bool          `env:"AUTH_ENABLED"`
+	AdminKey    string        `env:"AUTH_ADMIN_KEY" secret:"true"`
+	AdminTenant string        `env:"AUTH_ADMIN_TENANT"`
+	JWKS        string        `env:"AUTH_JWKS"`
+	JWKSRefresh time.Duration `env:"AUTH_JWKS_REFRESH" env-default:"1h"`
+	Issuer      string        `env:"AUTH_JWT_ISSUER"`
+	Audience    string        `env:"AUTH_JWT_AUDIENCE"`
+	RolesClaim  string        `env:"AUTH_JWT_ROLES_CLAIM" env-default:"roles"`
+	AdminRole   string        `env:"AUTH_JWT_ADMIN_ROLE" env-default:"admin"`
+	TenantClaim string        `env:"AUTH_JWT_TENANT_CLAIM" env-default:"tenant_id"`
+}
+
+// Principal describes the authenticated caller of a request.
+// API keys act on behalf of a service, are not bound to a user and are authorized by their Scopes;
+// bearer tokens carry the user in UserID and the roles listed in the token in Roles.
+// TenantID is the tenant the credentials are bound to.
+type Principal struct {
+	Name     string
+	KeyID    int
+	TenantID string
+	UserID   string
+	Roles    []string
+	Scopes   []string
+}
+
+// Storage defines the storage operations required by the Authenticator.
+type Storage interface {
+	AuthenticateAPIKey(context.Context, string) (*api.APIKey, error)
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]BackendFactory{BackendMemory: newLRU}
+)
+
+// RegisterBackend makes a backend available under the given name, so it can be selected with Config.Backend.
+// It panics if a backend with the name is already registered.
+func RegisterBackend(name string, factory BackendFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if _, ok := backends[name]; ok {
+		panic(fmt.Sprintf("cache: backend already registered: %s", name))
+	}
+
+	backends[name] = factory
+}
+
+// Backends returns the sorted names of the registered backends.
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// New creates and returns a new Cache with the backend named in the config, applying defaults to the unset config fields.
+func New(config *Config) (*Cache, error) {
+	if config.Backend == "" {
+		config.Backend = BackendMemory
+	}
+
+	if config.Size == 0 {
+		config.Size = DefaultSize
+	}
+
+	if config.TTL == 0 {
+		config.TTL = DefaultTTL
+	}
+
+	if !config.Enabled {
+		return &Cache{}, nil
+	}
+
+	backendsMu.RLock()
+	factory, ok := backends[config.Backend]
+	backendsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("New: unknown cache backend: %s", config.Backend)
+	}
+
+	backend, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("New: %w", err)
+	}
+
+	return &Cache{backend: backend, ttl: config.TTL, generations: make(map[string]uint64)}, nil
+}
+
+// Enabled reports whether the cache caches anything.
+func (c *Cache) Enabled() bool {
+	return c != nil && c.backend != nil
+}
+
+// SetObserver sets the observer receiving the outcome of all subsequent lookups and invalidations.
+func (c *Cache) SetObserver(observer Observer) {
+	c.observer = observer
+}
+
+// Get returns the cached result of the query in the tenant of ctx.
+func (c *Cache) Get(ctx context.Context, query Query) (interface{}, bool) {
+	key, ok := c.key(ctx, query)
+	if !ok {
+		return nil, false
+	}
+
+	value, hit := c.backend.Get(key)
+	if c.observer != nil {
+		c.observer.ObserveCacheLookup(query.Aggregate, hit)
+	}
+
+	return value, hit
+}
+
+// Generation returns the generation of the aggregates of the tenant of ctx, which changes with every invalidation
+// of them. It must be read before the result of a query is computed and passed to Set.
+func (c *Cache) Generation(ctx context.Context) uint64 {
+	if !c.Enabled() {
+		return 0
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation(tenantID)
+}
+
+// Set caches the result of the query in the tenant of ctx, computed as of the given generation, see Generation.
+// The result is dropped if the aggregates of the tenant were invalidated since, as a change committed
+// while it was computed may be missing from it. The value is shared by the callers getting it,
+// so it must not be modified afterwards.
+func (c *Cache) Set(ctx context.Context, query Query, generation uint64, value interface{}) {
+	key, ok := c.key(ctx, query)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation(key.TenantID) != generation {
+		return
+	}
+
+	c.backend.Set(key, value, c.ttl)
+}
+
+// SubscriptionsChanged invalidates the aggregates of the tenant depending on any of the given subscriptions:
+// those whose filters match a subscription and whose period overlaps its months.
+func (c *Cache) SubscriptionsChanged(tenantID string, subscriptions ...*api.Subscription) {
+	if !c.Enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generations[tenantID]++
+
+	removed := c.backend.DeleteFunc(func(key Key) bool {
+		if tenantID != tenant.All && key.TenantID != tenantID {
+			return false
+		}
+
+		return slices.ContainsFunc(subscriptions, key.dependsOn)
+	})
+
+	if c.observer != nil && removed > 0 {
+		c.observer.ObserveCacheInvalidation(removed)
+	}
+}
+
+// generation returns the generation of the aggregates of the tenant, which moves with the invalidations
+// of the tenant and of all tenants. c.mu must be held.
+func (c *Cache) generation(tenantID string) uint64 {
+	return c.generations[tenantID] + c.generations[tenant.All]
+}
+
+// key returns the key of the query in the tenant of ctx, or false if nothing is cached for ctx.
+// Queries across all tenants are not cached.
+func (c *Cache) key(ctx context.Context, query Query) (Key, bool) {
+	if !c.Enabled() {
+		return Key{}, false
+	}
+
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok || tenantID == tenant.All {
+		return Key{}, false
+	}
+
+	query.Start = month(query.Start)
+	query.End = month(query.End)
+
+	return Key{TenantID: tenantID, Query: query}, true
+}
+
+// dependsOn reports whether the aggregate of the key may change with the subscription. Subscriptions with invalid
+// dates are not charged, but are assumed to overlap every period.
+func (k Key) dependsOn(subscription *api.Subscription) bool {
+	if k.UserID != "" && k.UserID != subscription.UserID {
+		return false
+	}
+
+	if k.ServiceName != "" && k.ServiceName != subscription.ServiceName {
+		return false
+	}
+
+	if start, err := time.Parse(api.MonthLayout, subscription.StartDate); err == nil && start.After(k.End) {
+		return false
+	}
+
+	if subscription.EndDate != "" {
+		if end, err := time.Parse(api.MonthLayout, subscription.EndDate); err == nil && end.Before(k.Start) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// month returns the first moment of the month of t in UTC.
+func month(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+func date(year int, month time.Month) time.Time {
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func newTestCache(t *testing.T, size int) *Cache {
+	t.Helper()
+
+	c, err := New(&Config{Enabled: true, Size: size})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestSubscriptionsChanged(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), "acme")
+
+	// 2025 of the user u1 for Netflix.
+	query := Query{Aggregate: AggregateTotal, UserID: "u1", ServiceName: "Netflix", Start: date(2025, 1), End: date(2025, 12)}
+
+	tests := []struct {
+		name         string
+		tenantID     string
+		subscription *api.Subscription
+		invalidated  bool
+	}{
+		{
+			name:         "matching subscription",
+			tenantID:     "acme",
+			subscription: &api.Subscription{UserID: "u1", ServiceName: "Netflix", StartDate: "03-2025"},
+			invalidated:  true,
+		},
+		{
+			name:         "all tenants",
+			tenantID:     tenant.All,
+			subscription: &api.Subscription{UserID: "u1", ServiceName: "Netflix", StartDate: "03-2025"},
+			invalidated:  true,
+		},
+		{
+			name:         "other tenant",
+			tenantID:     "globex",
+			subscription: &api.Subscription{UserID: "u1", ServiceName: "Netflix", StartDate: "03-2025"},
+		},
+		{
+			name:         "other user",
+			tenantID:     "acme",
+			subscription: &api.Subscription{UserID: "u2", ServiceName: "Netflix", StartDate: "03-2025"},
+		},
+		{
+			name:         "other service",
+			tenantID:     "acme",
+			subscription: &api.Subscription{UserID: "u1", ServiceName: "Spotify", StartDate: "03-2025"},
+		},
+		{
+			name:         "starts after the period",
+			tenantID:     "acme",
+			subscription: &api.Subscription{UserID: "u1", ServiceName: "Netflix", StartDate: "01-2026"},
+		},
+		{
+			name:         "ends before the period",
+			tenantID:     "acme",
+			subscription: &api.Subscription{UserID: "u1", ServiceName: "Netflix", StartDate: "01-2024", EndDate: "12-2024"},
+		},
+		{
+			name:         "ends within the period",
+			tenantID:     "acme",
+			subscription: &api.Subscription{UserID: "u1", ServiceName: "Netflix", StartDate: "01-2024", EndDate: "01-2025"},
+			invalidated:  true,
+		},
+		{
+			name:         "invalid dates",
+			tenantID:     "acme",
+			subscription: &api.Subscription{UserID: "u1", ServiceName: "Netflix", StartDate: "2025-03"},
+			invalidated:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t, DefaultSize)
+			c.Set(ctx, query, c.Generation(ctx), 100)
+
+			c.SubscriptionsChanged(tt.tenantID, tt.subscription)
+
+			_, hit := c.Get(ctx, query)
+			if hit == tt.invalidated {
+				t.Errorf("cached = %v, want %v", hit, !tt.invalidated)
+			}
+		})
+	}
+}
+
+func TestSetAfterInvalidation(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), "acme")
+	query := Query{Aggregate: AggregateTotal, UserID: "u1", Start: date(2025, 1), End: date(2025, 12)}
+
+	tests := []struct {
+		name     string
+		tenantID string
+		cached   bool
+	}{
+		{
+			name:     "same tenant",
+			tenantID: "acme",
+		},
+		{
+			name:     "all tenants",
+			tenantID: tenant.All,
+		},
+		{
+			name:     "other tenant",
+			tenantID: "globex",
+			cached:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t, DefaultSize)
+
+			// The total is computed after a miss, while a change is committed and invalidates the aggregates,
+			// which are not cached yet, so nothing is removed.
+			if _, hit := c.Get(ctx, query); hit {
+				t.Fatal("empty cache hit")
+			}
+
+			generation := c.Generation(ctx)
+			c.SubscriptionsChanged(tt.tenantID, &api.Subscription{UserID: "u1", ServiceName: "Netflix", StartDate: "03-2025"})
+			c.Set(ctx, query, generation, 100)
+
+			if _, hit := c.Get(ctx, query); hit != tt.cached {
+				t.Errorf("cached = %v, want %v", hit, tt.cached)
+			}
+
+			// The next computation starts after the invalidation, so its result is cached.
+			c.Set(ctx, query, c.Generation(ctx), 200)
+			if value, hit := c.Get(ctx, query); !hit || value != 200 {
+				t.Errorf("Get() = %v, %v, want 200, true", value, hit)
+			}
+		})
+	}
+}
+
+func TestCacheKeys(t *testing.T) {
+	c := newTestCache(t, DefaultSize)
+
+	acme := tenant.WithTenant(context.Background(), "acme")
+	query := Query{Aggregate: AggregateTotal, Start: date(2025, 1), End: date(2025, 12)}
+	c.Set(acme, query, c.Generation(acme), 100)
+
+	// Periods are normalized to their months.
+	normalized := query
+	normalized.Start = time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	if value, hit := c.Get(acme, normalized); !hit || value != 100 {
+		t.Errorf("Get() = %v, %v, want 100, true", value, hit)
+	}
+
+	if _, hit := c.Get(tenant.WithTenant(context.Background(), "globex"), query); hit {
+		t.Error("aggregate of another tenant is cached")
+	}
+
+	monthly := query
+	monthly.Aggregate = AggregateMonthly
+	if _, hit := c.Get(acme, monthly); hit {
+		t.Error("another aggregate is cached")
+	}
+
+	all := tenant.WithTenant(context.Background(), tenant.All)
+	c.Set(all, query, c.Generation(all), 200)
+	if _, hit := c.Get(all, query); hit {
+		t.Error("aggregate across all tenants is cached")
+	}
+}
+
+func TestLRU(t *testing.T) {
+	c := newTestCache(t, 2)
+	ctx := tenant.WithTenant(context.Background(), "acme")
+
+	queries := make([]Query, 3)
+	for i := range queries {
+		queries[i] = Query{Aggregate: AggregateTotal, Start: date(2025, time.Month(i+1)), End: date(2025, 12)}
+	}
+
+	c.Set(ctx, queries[0], c.Generation(ctx), 0)
+	c.Set(ctx, queries[1], c.Generation(ctx), 1)
+	c.Get(ctx, queries[0])
+	c.Set(ctx, queries[2], c.Generation(ctx), 2)
+
+	for i, want := range []bool{true, false, true} {
+		if _, hit := c.Get(ctx, queries[i]); hit != want {
+			t.Errorf("query %d cached = %v, want %v", i, hit, want)
+		}
+	}
+
+	c.ttl = -time.Second
+	c.Set(ctx, queries[0], c.Generation(ctx), 0)
+	if _, hit := c.Get(ctx, queries[0]); hit {
+		t.Error("expired aggregate is cached")
+	}
+}
+
+func TestDisabledCache(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), "acme")
+	query := Query{Aggregate: AggregateTotal}
+
+	for _, c := range []*Cache{nil, {}} {
+		c.Set(ctx, query, c.Generation(ctx), 100)
+		c.SubscriptionsChanged("acme", &api.Subscription{})
+
+		if _, hit := c.Get(ctx, query); hit {
+			t.Error("disabled cache caches")
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+// newLRU creates the memory backend holding up to config.Size aggregates.
+func newLRU(config *Config) (Backend, error) {
+	return &lru{
+		size:  config.Size,
+		order: list.New(),
+		items: make(map[Key]*list.Element),
+	}, nil
+}
+
+// Get returns the value of key and marks it as the most recently used, removing it if it has expired.
+func (l *lru) Get(key Key) (interface{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := element.Value.(*entry)
+	if time.Now().After(e.expires) {
+		l.remove(element)
+		return nil, false
+	}
+
+	l.order.MoveToFront(element)
+
+	return e.value, true
+}
+
+// Set caches the value of key, evicting the least recently used value if the cache is full.
+func (l *lru) Set(key Key, value interface{}, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expires := time.Now().Add(ttl)
+
+	if element, ok := l.items[key]; ok {
+		e := element.Value.(*entry)
+		e.value = value
+		e.expires = expires
+		l.order.MoveToFront(element)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&entry{key: key, value: value, expires: expires})
+
+	if l.order.Len() > l.size {
+		l.remove(l.order.Back())
+	}
+}
+
+// DeleteFunc removes the values of the keys matching match and returns their number.
+func (l *lru) DeleteFunc(match func(Key) bool) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	removed := 0
+	for element := l.order.Front(); element != nil; {
+		next := element.Next()
+
+		if match(element.Value.(*entry).key) {
+			l.remove(element)
+			removed++
+		}
+
+		element = next
+	}
+
+	return removed
+}
+
+// remove removes the element from the cache; the lock must be held.
+func (l *lru) remove(element *list.Element) {
+	l.order.Remove(element)
+	delete(l.items, element.Value.(*entry).key)
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	// BackendMemory keeps the aggregates in an LRU in the memory of every replica.
+	BackendMemory = "memory"
+
+	// AggregateTotal is the total price of the subscriptions of a query for its period.
+	AggregateTotal = "total"
+
+	// AggregateMonthly is the series of the monthly total prices of the subscriptions of a query for its period.
+	AggregateMonthly = "monthly"
+
+	// DefaultSize defines the default maximum number of cached aggregates.
+	DefaultSize = 10000
+
+	// DefaultTTL defines how long an aggregate is cached by default.
+	DefaultTTL = 5 * time.Minute
+)
+
+// Config defines the cache of the aggregate queries.
+// Aggregates are invalidated as soon as a subscription they depend on is saved, updated or deleted through
+// the storage of this replica; TTL bounds how long the changes made elsewhere may go unnoticed.
+type Config struct {
+	Enabled bool          `env:"CACHE_ENABLED"`
+	Backend string        `env:"CACHE_BACKEND" env-default:"memory"`
+	Size    int           `env:"CACHE_SIZE" env-default:"10000"`
+	TTL     time.Duration `env:"CACHE_TTL" env-default:"5m"`
+}
+
+// Query is an aggregate query over the subscriptions of the tenant of a request, optionally filtered by user
+// and service, for the months from Start through End.
+type Query struct {
+	Aggregate   string
+	UserID      string
+	ServiceName string
+	Start       time.Time
+	End         time.Time
+}
+
+// Key identifies a cached aggregate: the normalized query and the tenant it was run in.
+type Key struct {
+	TenantID string
+	Query
+}
+
+// Backend stores the cached aggregates. Implementations must be safe for concurrent use.
+type Backend interface {
+	// Get returns the value of key, if it is cached and has not expired.
+	Get(key Key) (interface{}, bool)
+
+	// Set caches the value of key for ttl.
+	Set(key Key, value interface{}, ttl time.Duration)
+
+	// DeleteFunc removes the values of the keys for which match returns true and returns their number.
+	DeleteFunc(match func(Key) bool) int
+}
+
+// BackendFactory creates the backend named in the config.
+type BackendFactory func(config *Config) (Backend, error)
+
+// Observer receives the outcome of the cache lookups and the number of aggregates removed by invalidations.
+type Observer interface {
+	ObserveCacheLookup(aggregate string, hit bool)
+	ObserveCacheInvalidation(removed int)
+}
+
+// Cache caches the results of the aggregate queries. A nil or disabled Cache caches nothing.
+type Cache struct {
+	backend  Backend
+	ttl      time.Duration
+	observer Observer
+
+	// mu orders the invalidations with the values set, so a value computed before an invalidation
+	// is never cached after it. generations counts the invalidations per tenant.
+	mu          sync.Mutex
+	generations map[string]uint64
+}
+
+// lru is the memory backend, evicting the least recently used aggregate once it holds size of them.
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[Key]*list.Element
+}
+
+// entry is a cached value in the lru.
+type entry struct {
+	key     Key
+	value   interface{}
+	expires time.Time
+}
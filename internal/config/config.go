@@ -1,31 +1,190 @@
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
+	"os"
+	"strings"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/joho/godotenv"
 
 	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+	"subscriptions/internal/cache"
+	"subscriptions/internal/contract"
+	"subscriptions/internal/deprecation"
+	"subscriptions/internal/events"
+	"subscriptions/internal/graph"
+	"subscriptions/internal/grpcserver"
+	"subscriptions/internal/health"
 	"subscriptions/internal/logger"
+	"subscriptions/internal/metrics"
+	"subscriptions/internal/ratelimit"
+	"subscriptions/internal/rbac"
+	"subscriptions/internal/rollup"
+	"subscriptions/internal/scheduler"
 	"subscriptions/internal/storage/postgresClient"
+	"subscriptions/internal/tenant"
+	"subscriptions/internal/tracing"
+	"subscriptions/internal/webhooks"
 )
 
+// fileSuffix marks the variables naming a file the value of a setting is read from, e.g. POSTGRES_PASSWORD_FILE.
+const fileSuffix = "_FILE"
+
 // Config defines configuration parameters for the notification-service application,
 // including HTTP server setting, SMTP/PostreSQL/Redis credentials, logger optional and calculate timeouts.
 type Config struct {
-	HttpServer api.HttpServer
-	Postgres   postgresClient.Config
-	Logger     logger.Config
+	HttpServer  api.HttpServer
+	Deprecation deprecation.Config
+	OpenAPI     contract.Config
+	GRPC        grpcserver.Config
+	GraphQL     graph.Config
+	Postgres    postgresClient.Config
+	Cache       cache.Config
+	Rollup      rollup.Config
+	Logger      logger.Config
+	Scheduler   scheduler.Config
+	Webhooks    webhooks.Config
+	Events      events.Config
+	Auth        auth.Config
+	RBAC        rbac.Config
+	Tenant      tenant.Config
+	RateLimit   ratelimit.Config
+	Metrics     metrics.Config
+	Tracing     tracing.Config
+	Health      health.Config
+}
+
+// Source describes where the configuration is loaded from. The layers take precedence in this order:
+// command line flags, environment variables, the configuration file and the defaults of the settings.
+// Every setting can also be read from the file named by the variable with the _FILE suffix
+// (e.g. POSTGRES_PASSWORD_FILE), which takes precedence over the variable itself and is meant for secrets.
+type Source struct {
+	// Path is the configuration file, in the .env format.
+	Path string
+
+	// Required makes a missing configuration file an error; otherwise the file is skipped.
+	Required bool
+
+	overrides map[string]string
 }
 
-// New loads the configuration from the specified file path and initializes computed timeout values.
+// NewSource creates and returns a new Source reading the optional configuration file at path.
+func NewSource(path string) *Source {
+	return &Source{
+		Path:      path,
+		overrides: make(map[string]string),
+	}
+}
+
+// New loads the configuration from the specified file path and the environment and validates it.
 // Returns a fully filled Config instance or an error if loading fails.
 func New(path string) (*Config, error) {
+	source := NewSource(path)
+	source.Required = true
+
+	return source.Load()
+}
+
+// RegisterFlags registers the --config flag and a flag for every setting on fs, named after its variable:
+// HTTP_PORT is set with --http-port. Secrets also get a flag for their file, e.g. --postgres-password-file.
+func (s *Source) RegisterFlags(fs *flag.FlagSet) {
+	fs.Func("config", fmt.Sprintf("configuration `file` (default %q)", s.Path), func(value string) error {
+		s.Path = value
+		s.Required = true
+		return nil
+	})
+
+	for _, setting := range settings(&Config{}) {
+		s.registerFlag(fs, setting.Key)
+
+		if setting.Secret {
+			s.registerFlag(fs, setting.Key+fileSuffix)
+		}
+	}
+}
+
+func (s *Source) registerFlag(fs *flag.FlagSet, key string) {
+	name := strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+
+	fs.Func(name, "overrides "+key, func(value string) error {
+		s.overrides[key] = value
+		return nil
+	})
+}
+
+// Load reads the configuration from all layers of the source and validates it.
+// Every validation problem is reported in the returned error.
+func (s *Source) Load() (*Config, error) {
+	// The file only fills in the variables which are not set in the environment.
+	if err := godotenv.Load(s.Path); err != nil {
+		if s.Required || !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+	}
+
+	if err := s.setOverrides(true); err != nil {
+		return nil, err
+	}
+
+	if err := readFiles(); err != nil {
+		return nil, err
+	}
+
+	if err := s.setOverrides(false); err != nil {
+		return nil, err
+	}
+
 	var cfg Config
 
-	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
+
+// setOverrides exports the values given by the flags to the environment, either those naming
+// files or the rest, so the file flags are resolved before the plain ones take precedence over them.
+func (s *Source) setOverrides(files bool) error {
+	for key, value := range s.overrides {
+		if strings.HasSuffix(key, fileSuffix) != files {
+			continue
+		}
+
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// readFiles replaces the value of every setting which has its _FILE variable set with the content of that file.
+func readFiles() error {
+	for _, setting := range settings(&Config{}) {
+		path := os.Getenv(setting.Key + fileSuffix)
+		if path == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s%s: %w", setting.Key, fileSuffix, err)
+		}
+
+		if err = os.Setenv(setting.Key, strings.TrimRight(string(content), "\r\n")); err != nil {
+			return fmt.Errorf("failed to set %s: %w", setting.Key, err)
+		}
+	}
+
+	return nil
+}
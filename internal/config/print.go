@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// redacted replaces the values of secrets in the printed configuration.
+const redacted = "********"
+
+// setting describes a single configuration value and the variable it is read from.
+type setting struct {
+	Key    string
+	Secret bool
+	Value  reflect.Value
+}
+
+// settings lists the settings of cfg, a pointer to a struct, in the order of their declaration.
+// Secrets are the fields tagged with secret:"true".
+func settings(cfg interface{}) []setting {
+	var res []setting
+
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		t := v.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			if key, ok := field.Tag.Lookup("env"); ok {
+				res = append(res, setting{
+					Key:    key,
+					Secret: field.Tag.Get("secret") == "true",
+					Value:  v.Field(i),
+				})
+				continue
+			}
+
+			if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(i))
+			}
+		}
+	}
+
+	walk(reflect.ValueOf(cfg).Elem())
+
+	return res
+}
+
+// Print writes the effective configuration to w in the .env format, with the values of secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	for _, setting := range settings(c) {
+		value := format(setting.Value)
+		if setting.Secret && value != "" {
+			value = redacted
+		}
+
+		if _, err := fmt.Fprintf(w, "%s=%s\n", setting.Key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// format formats the value of a setting the way it is read: lists are separated by commas.
+func format(v reflect.Value) string {
+	if v.Kind() != reflect.Slice {
+		return fmt.Sprint(v.Interface())
+	}
+
+	items := make([]string, v.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+
+	return strings.Join(items, ",")
+}
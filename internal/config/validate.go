@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"subscriptions/internal/cache"
+	"subscriptions/internal/contract"
+	"subscriptions/internal/deprecation"
+	"subscriptions/internal/ratelimit"
+	"subscriptions/internal/rbac"
+	"subscriptions/internal/tenant"
+	"subscriptions/internal/tracing"
+)
+
+// maxPort is the highest valid TCP port.
+const maxPort = 65535
+
+// validator collects the problems found in the configuration.
+type validator struct {
+	errs []error
+}
+
+// check records the problem with the given setting unless ok holds.
+func (v *validator) check(ok bool, key string, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s %s", key, fmt.Sprintf(format, args...)))
+	}
+}
+
+func (v *validator) required(key string, value string) {
+	v.check(value != "", key, "is required")
+}
+
+func (v *validator) port(key string, port int) {
+	v.check(port >= 1 && port <= maxPort, key, "must be between 1 and %d, got %d", maxPort, port)
+}
+
+func (v *validator) positive(key string, d time.Duration) {
+	v.check(d > 0, key, "must be positive, got %s", d)
+}
+
+func (v *validator) oneOf(key string, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+
+	v.check(false, key, "must be one of %q, got %q", allowed, value)
+}
+
+// Validate checks that the required settings are set and the others are within their ranges.
+// All problems are reported at once, joined in the returned error.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.port("HTTP_PORT", c.HttpServer.Port)
+	v.check(c.HttpServer.ReadHeaderTimeout >= 0, "HTTP_READ_HEADER_TIMEOUT", "must not be negative, got %s", c.HttpServer.ReadHeaderTimeout)
+	v.check(c.HttpServer.ReadTimeout >= 0, "HTTP_READ_TIMEOUT", "must not be negative, got %s", c.HttpServer.ReadTimeout)
+	v.check(c.HttpServer.WriteTimeout >= 0, "HTTP_WRITE_TIMEOUT", "must not be negative, got %s", c.HttpServer.WriteTimeout)
+	v.check(c.HttpServer.IdleTimeout >= 0, "HTTP_IDLE_TIMEOUT", "must not be negative, got %s", c.HttpServer.IdleTimeout)
+	v.check(c.HttpServer.MaxHeaderBytes >= 0, "HTTP_MAX_HEADER_BYTES", "must not be negative, got %d", c.HttpServer.MaxHeaderBytes)
+	v.check(c.HttpServer.MaxBodyBytes >= 0, "HTTP_MAX_BODY_BYTES", "must not be negative, got %d", c.HttpServer.MaxBodyBytes)
+	if c.HttpServer.TLSCertFile != "" || c.HttpServer.TLSKeyFile != "" {
+		v.required("HTTP_TLS_CERT_FILE", c.HttpServer.TLSCertFile)
+		v.required("HTTP_TLS_KEY_FILE", c.HttpServer.TLSKeyFile)
+		v.check(c.HttpServer.TLSReloadInterval >= 0, "HTTP_TLS_RELOAD_INTERVAL", "must not be negative, got %s", c.HttpServer.TLSReloadInterval)
+	}
+
+	if c.GRPC.Enabled {
+		v.port("GRPC_PORT", c.GRPC.Port)
+		v.check(c.GRPC.Port != c.HttpServer.Port, "GRPC_PORT", "must differ from HTTP_PORT (%d)", c.HttpServer.Port)
+	}
+
+	v.check(c.GraphQL.MaxComplexity >= 1, "GRAPHQL_MAX_COMPLEXITY", "must be at least 1, got %d", c.GraphQL.MaxComplexity)
+	v.check(c.GraphQL.MaxDepth >= 1, "GRAPHQL_MAX_DEPTH", "must be at least 1, got %d", c.GraphQL.MaxDepth)
+
+	if _, err := deprecation.Parse(c.Deprecation.Routes); err != nil {
+		v.check(false, "API_DEPRECATIONS", "%v", err)
+	}
+
+	v.required("POSTGRES_HOST", c.Postgres.Host)
+	v.required("POSTGRES_USER", c.Postgres.User)
+	v.required("POSTGRES_DATABASE", c.Postgres.Database)
+	if port, err := strconv.Atoi(c.Postgres.Port); err != nil {
+		v.check(false, "POSTGRES_PORT", "must be a number, got %q", c.Postgres.Port)
+	} else {
+		v.port("POSTGRES_PORT", port)
+	}
+	v.positive("POSTGRES_TIMEOUT", c.Postgres.Timeout)
+	v.check(c.Postgres.MaxConns >= 1, "POSTGRES_MAX_CONNECTIONS", "must be at least 1, got %d", c.Postgres.MaxConns)
+	v.check(c.Postgres.MinConns >= 0 && c.Postgres.MinConns <= c.Postgres.MaxConns, "POSTGRES_MIN_CONNECTIONS",
+		"must be between 0 and POSTGRES_MAX_CONNECTIONS (%d), got %d", c.Postgres.MaxConns, c.Postgres.MinConns)
+
+	if len(c.Postgres.Replicas) > 0 {
+		v.check(!slices.Contains(c.Postgres.Replicas, ""), "POSTGRES_REPLICAS", "must not contain empty DSNs")
+		v.positive("POSTGRES_REPLICA_CHECK_INTERVAL", c.Postgres.ReplicaCheckInterval)
+		v.check(c.Postgres.ReplicaMaxLag >= 0, "POSTGRES_REPLICA_MAX_LAG", "must not be negative, got %s", c.Postgres.ReplicaMaxLag)
+
+		if c.Postgres.ReadYourWrites {
+			v.positive("POSTGRES_READ_YOUR_WRITES_WINDOW", c.Postgres.ReadYourWritesWindow)
+		}
+	}
+
+	if c.Cache.Enabled {
+		v.oneOf("CACHE_BACKEND", c.Cache.Backend, cache.Backends()...)
+		v.check(c.Cache.Size >= 1, "CACHE_SIZE", "must be at least 1, got %d", c.Cache.Size)
+		v.positive("CACHE_TTL", c.Cache.TTL)
+	}
+
+	if c.Rollup.Enabled {
+		v.check(c.Rollup.Horizon >= 1, "ROLLUP_HORIZON", "must be at least 1, got %d", c.Rollup.Horizon)
+		v.positive("ROLLUP_REFRESH_INTERVAL", c.Rollup.Interval)
+	}
+
+	v.oneOf("LOGGER", c.Logger.Env, "dev", "prod")
+
+	v.oneOf("OPENAPI_VALIDATE_REQUESTS", c.OpenAPI.Requests, contract.ModeOff, contract.ModeLog, contract.ModeReject)
+	v.oneOf("OPENAPI_VALIDATE_RESPONSES", c.OpenAPI.Responses, contract.ModeOff, contract.ModeLog, contract.ModeReject)
+	v.check(c.OpenAPI.Responses == contract.ModeOff || c.Logger.Env != "prod", "OPENAPI_VALIDATE_RESPONSES",
+		"is meant for development and tests, must be %s with LOGGER=prod", contract.ModeOff)
+
+	if c.Scheduler.Enabled {
+		v.positive("SCHEDULER_INTERVAL", c.Scheduler.Interval)
+		v.positive("SCHEDULER_WINDOW", c.Scheduler.Window)
+		v.oneOf("SCHEDULER_NOTIFIER", c.Scheduler.Notifier, "log", "webhook", "smtp")
+
+		switch c.Scheduler.Notifier {
+		case "webhook":
+			v.required("SCHEDULER_WEBHOOK_URL", c.Scheduler.WebhookURL)
+		case "smtp":
+			v.required("SCHEDULER_SMTP_HOST", c.Scheduler.SMTPHost)
+			v.port("SCHEDULER_SMTP_PORT", c.Scheduler.SMTPPort)
+			v.required("SCHEDULER_SMTP_FROM", c.Scheduler.SMTPFrom)
+			v.required("SCHEDULER_SMTP_TO", c.Scheduler.SMTPTo)
+		}
+	}
+
+	if c.Webhooks.Enabled {
+		v.positive("WEBHOOKS_INTERVAL", c.Webhooks.Interval)
+		v.positive("WEBHOOKS_EXPIRY_INTERVAL", c.Webhooks.ExpiryInterval)
+		v.check(c.Webhooks.BatchSize >= 1, "WEBHOOKS_BATCH_SIZE", "must be at least 1, got %d", c.Webhooks.BatchSize)
+		v.check(c.Webhooks.MaxAttempts >= 1, "WEBHOOKS_MAX_ATTEMPTS", "must be at least 1, got %d", c.Webhooks.MaxAttempts)
+		v.positive("WEBHOOKS_BACKOFF", c.Webhooks.Backoff)
+		v.check(c.Webhooks.MaxBackoff >= c.Webhooks.Backoff, "WEBHOOKS_MAX_BACKOFF",
+			"must not be less than WEBHOOKS_BACKOFF (%s), got %s", c.Webhooks.Backoff, c.Webhooks.MaxBackoff)
+		v.positive("WEBHOOKS_TIMEOUT", c.Webhooks.Timeout)
+	}
+
+	v.check(c.Events.BufferSize >= 1, "EVENTS_BUFFER_SIZE", "must be at least 1, got %d", c.Events.BufferSize)
+	v.positive("EVENTS_HEARTBEAT", c.Events.Heartbeat)
+	v.positive("EVENTS_RETENTION", c.Events.Retention)
+	v.positive("EVENTS_PRUNE_INTERVAL", c.Events.PruneInterval)
+
+	if c.Auth.Enabled {
+		v.positive("AUTH_JWKS_REFRESH", c.Auth.JWKSRefresh)
+		if c.Auth.AdminKey != "" {
+			v.check(tenant.Valid(c.Auth.AdminTenant), "AUTH_ADMIN_TENANT", "must be a valid tenant id when AUTH_ADMIN_KEY is set, got %q", c.Auth.AdminTenant)
+		}
+	}
+
+	_, ok := rbac.FindRole(c.RBAC.DefaultRole)
+	v.check(ok, "RBAC_DEFAULT_ROLE", "must be a known role, got %q", c.RBAC.DefaultRole)
+
+	v.check(c.Tenant.Default == "" || tenant.Valid(c.Tenant.Default), "TENANT_DEFAULT", "is not a valid tenant id: %q", c.Tenant.Default)
+
+	if c.RateLimit.Enabled {
+		v.oneOf("RATELIMIT_BACKEND", c.RateLimit.Backend, ratelimit.BackendMemory, ratelimit.BackendPostgres)
+		v.check(c.RateLimit.DefaultRequests >= 1, "RATELIMIT_DEFAULT_REQUESTS", "must be at least 1, got %d", c.RateLimit.DefaultRequests)
+		v.positive("RATELIMIT_DEFAULT_PERIOD", c.RateLimit.DefaultPeriod)
+		v.check(c.RateLimit.ReportsRequests >= 1, "RATELIMIT_REPORTS_REQUESTS", "must be at least 1, got %d", c.RateLimit.ReportsRequests)
+		v.positive("RATELIMIT_REPORTS_PERIOD", c.RateLimit.ReportsPeriod)
+		v.check(c.RateLimit.AuthRequests >= 1, "RATELIMIT_AUTH_REQUESTS", "must be at least 1, got %d", c.RateLimit.AuthRequests)
+		v.positive("RATELIMIT_AUTH_PERIOD", c.RateLimit.AuthPeriod)
+	}
+
+	if c.Tracing.Enabled {
+		v.oneOf("TRACING_EXPORTER", c.Tracing.Exporter, tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterFile)
+		if c.Tracing.Exporter == tracing.ExporterFile {
+			v.required("TRACING_FILE", c.Tracing.File)
+		}
+		v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	v.positive("HEALTH_CHECK_TIMEOUT", c.Health.CheckTimeout)
+	v.check(c.Health.ShutdownDelay >= 0, "HEALTH_SHUTDOWN_DELAY", "must not be negative, got %s", c.Health.ShutdownDelay)
+
+	if len(v.errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(v.errs...))
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"go.uber.org/zap"
+
+	"subscriptions/docs"
+	"subscriptions/internal/problem"
+)
+
+// New creates and returns a new Validator of the specification in docs, applying the default modes if not set.
+// Request bodies larger than maxBodyBytes are left to the body limit of their route and are not validated.
+func New(config *Config, maxBodyBytes int64, logger *zap.Logger) (*Validator, error) {
+	if config.Requests == "" {
+		config.Requests = ModeOff
+	}
+
+	if config.Responses == "" {
+		config.Responses = ModeOff
+	}
+
+	for _, mode := range []string{config.Requests, config.Responses} {
+		if mode != ModeOff && mode != ModeLog && mode != ModeReject {
+			return nil, fmt.Errorf("New: unknown validation mode: %s", mode)
+		}
+	}
+
+	// swag generates a Swagger 2.0 document, which is converted to OpenAPI 3 to be validated against.
+	var spec openapi2.T
+	if err := json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &spec); err != nil {
+		return nil, fmt.Errorf("New: cannot parse specification: %w", err)
+	}
+
+	doc, err := openapi2conv.ToV3(&spec)
+	if err != nil {
+		return nil, fmt.Errorf("New: cannot convert specification: %w", err)
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("New: invalid specification: %w", err)
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("New: %w", err)
+	}
+
+	return &Validator{
+		router:       router,
+		logger:       logger,
+		requests:     config.Requests,
+		responses:    config.Responses,
+		maxBodyBytes: maxBodyBytes,
+		options: &openapi3filter.Options{
+			// The credentials are checked by the authentication middleware.
+			AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+			IncludeResponseStatus: true,
+		},
+	}, nil
+}
+
+// Middleware validates the requests and the responses of the documented routes according to the modes of the config.
+// Requests not matching the specification are rejected with 400 in ModeReject, and responses not matching it
+// are replaced with 500.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	if v.requests == ModeOff && v.responses == ModeOff {
+		return next
+	}
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		route, params, err := v.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: params,
+			Route:      route,
+			Options:    v.options,
+		}
+
+		if v.requests != ModeOff && v.bodyFits(r) {
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				v.logger.Warn("request does not match the OpenAPI specification",
+					zap.String("method", r.Method),
+					zap.String("route", route.Path),
+					zap.String("mismatch", summary(err)),
+				)
+
+				if v.requests == ModeReject {
+					problem.Write(v.logger, w, r, problem.Wrap(problem.CodeInvalidRequest, summary(err), err))
+					return
+				}
+			}
+		}
+
+		if v.responses == ModeOff {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw := &responseWriter{ResponseWriter: w, header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		if rw.streaming {
+			return
+		}
+
+		v.validateResponse(w, input, rw)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// validateResponse validates the buffered response of the request and writes it, or the problem replacing it.
+func (v *Validator) validateResponse(w http.ResponseWriter, input *openapi3filter.RequestValidationInput, rw *responseWriter) {
+	// Problem details are JSON, documented as the JSON the routes produce.
+	header := rw.header.Clone()
+	if mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil && mediaType == problem.ContentType {
+		header.Set("Content-Type", "application/json")
+	}
+
+	err := openapi3filter.ValidateResponse(input.Request.Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 rw.status,
+		Header:                 header,
+		Body:                   io.NopCloser(bytes.NewReader(rw.body.Bytes())),
+		Options:                v.options,
+	})
+	if err != nil {
+		v.logger.Error("response does not match the OpenAPI specification",
+			zap.String("method", input.Request.Method),
+			zap.String("route", input.Route.Path),
+			zap.Int("status", rw.status),
+			zap.String("mismatch", summary(err)),
+		)
+
+		if v.responses == ModeReject {
+			problem.Write(v.logger, w, input.Request, err)
+			return
+		}
+	}
+
+	rw.send()
+}
+
+// bodyFits reports whether the body of r does not exceed maxBodyBytes, reading at most maxBodyBytes+1 bytes of it.
+// The read part is put back, so the handler reads the whole body.
+func (v *Validator) bodyFits(r *http.Request) bool {
+	if v.maxBodyBytes <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return true
+	}
+
+	if r.ContentLength > v.maxBodyBytes {
+		return false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, v.maxBodyBytes+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+
+	return err == nil && int64(len(data)) <= v.maxBodyBytes
+}
+
+// summary returns the first line of the validation error, without the dump of the schema and the value.
+func summary(err error) string {
+	line, _, _ := strings.Cut(err.Error(), "\n")
+	return line
+}
+
+// Header returns the header of the buffered response, or of the stream once the response turned out to be one.
+func (rw *responseWriter) Header() http.Header {
+	if rw.streaming {
+		return rw.ResponseWriter.Header()
+	}
+
+	return rw.header
+}
+
+// WriteHeader buffers the status code, and starts passing the response through if it is an event stream.
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.wroteHeader {
+		return
+	}
+
+	rw.wroteHeader = true
+	rw.status = status
+
+	if mediaType, _, err := mime.ParseMediaType(rw.header.Get("Content-Type")); err == nil && mediaType == "text/event-stream" {
+		rw.send()
+		rw.streaming = true
+	}
+}
+
+// Write buffers b, or writes it to the stream.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	if rw.streaming {
+		return rw.ResponseWriter.Write(b)
+	}
+
+	return rw.body.Write(b)
+}
+
+// Flush flushes the stream; buffered responses are written once they are validated.
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	if !rw.streaming {
+		return
+	}
+
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// send writes the buffered response.
+func (rw *responseWriter) send() {
+	header := rw.ResponseWriter.Header()
+	for key, values := range rw.header {
+		header[key] = values
+	}
+
+	rw.ResponseWriter.WriteHeader(rw.status)
+
+	if rw.body.Len() > 0 {
+		_, _ = rw.ResponseWriter.Write(rw.body.Bytes())
+	}
+}
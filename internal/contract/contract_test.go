@@ -0,0 +1,187 @@
+package contract
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// subscription is a request body matching the api.Subscription schema.
+const subscription = `{"service_name":"Yandex Plus","price":400,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"07-2025"}`
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     Config
+		method     string
+		target     string
+		body       string
+		response   string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "valid request and response",
+			config:     Config{Requests: ModeReject, Responses: ModeReject},
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/total?start_date=01-2025&end_date=12-2025",
+			response:   `{"status":"OK","data":4800}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"OK","data":4800}`,
+		},
+		{
+			name:       "missing query parameter rejected",
+			config:     Config{Requests: ModeReject},
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/total?start_date=01-2025",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `"code":"invalid_request"`,
+		},
+		{
+			name:       "invalid path parameter rejected",
+			config:     Config{Requests: ModeReject},
+			method:     http.MethodDelete,
+			target:     "/v1/subscriptions/abc",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `parameter \"id\" in path`,
+		},
+		{
+			name:       "invalid body rejected",
+			config:     Config{Requests: ModeReject},
+			method:     http.MethodPost,
+			target:     "/v1/subscriptions",
+			body:       `{"service_name":1}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `/service_name`,
+		},
+		{
+			name:       "invalid body logged",
+			config:     Config{Requests: ModeLog},
+			method:     http.MethodPost,
+			target:     "/v1/subscriptions",
+			body:       `{"service_name":1}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"service_name":1}`,
+		},
+		{
+			name:       "body over the limit left to the route",
+			config:     Config{Requests: ModeReject},
+			method:     http.MethodPost,
+			target:     "/v1/subscriptions",
+			body:       `{"service_name":1,"padding":"` + strings.Repeat("x", 1024) + `"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "valid body passed to the handler",
+			config:     Config{Requests: ModeReject},
+			method:     http.MethodPost,
+			target:     "/v1/subscriptions",
+			body:       subscription,
+			wantStatus: http.StatusOK,
+			wantBody:   subscription,
+		},
+		{
+			name:       "undocumented route not validated",
+			config:     Config{Requests: ModeReject, Responses: ModeReject},
+			method:     http.MethodGet,
+			target:     "/metrics",
+			response:   "metrics",
+			wantStatus: http.StatusOK,
+			wantBody:   "metrics",
+		},
+		{
+			name:       "invalid response replaced",
+			config:     Config{Responses: ModeReject},
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/total?start_date=01-2025&end_date=12-2025",
+			response:   `{"status":"OK","data":"4800"}`,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `"code":"internal"`,
+		},
+		{
+			name:       "invalid response logged",
+			config:     Config{Responses: ModeLog},
+			method:     http.MethodGet,
+			target:     "/v1/subscriptions/total?start_date=01-2025&end_date=12-2025",
+			response:   `{"status":"OK","data":"4800"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"OK","data":"4800"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := New(&tt.config, 1024, zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The handler responds with the configured response, or echoes the request body.
+			handler := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if tt.response != "" {
+					_, _ = io.WriteString(w, tt.response)
+					return
+				}
+
+				_, _ = io.Copy(w, r.Body)
+			}))
+
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePassesStreamsThrough(t *testing.T) {
+	v, err := New(&Config{Responses: ModeReject}, 0, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flushed := make(chan string, 1)
+	handler := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {}\n\n")
+
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush() error = %v", err)
+		}
+
+		flushed <- w.(*responseWriter).ResponseWriter.(*httptest.ResponseRecorder).Body.String()
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/subscriptions/events", nil))
+
+	if got := <-flushed; got != "data: {}\n\n" {
+		t.Errorf("flushed body = %q, want the event written before the handler returned", got)
+	}
+
+	if !w.Flushed {
+		t.Error("stream was not flushed")
+	}
+}
+
+func TestNewRejectsUnknownMode(t *testing.T) {
+	if _, err := New(&Config{Requests: "strict"}, 0, zap.NewNop()); err == nil {
+		t.Error("New() error = nil, want an error for an unknown mode")
+	}
+}
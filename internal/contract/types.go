@@ -0,0 +1,52 @@
+package contract
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"go.uber.org/zap"
+)
+
+const (
+	// ModeOff disables the validation.
+	ModeOff = "off"
+
+	// ModeLog logs the mismatches and serves the requests as usual.
+	ModeLog = "log"
+
+	// ModeReject logs the mismatches and rejects the requests, or replaces the responses, not matching the specification.
+	ModeReject = "reject"
+)
+
+// Config defines the validation of the requests and the responses against the OpenAPI specification in docs,
+// generated from the annotations of the handlers. Each of them is validated in one of the modes ModeOff, ModeLog
+// or ModeReject. Responses are buffered to be validated, so validating them is meant for development and tests.
+// Routes missing from the specification are not validated.
+type Config struct {
+	Requests  string `env:"OPENAPI_VALIDATE_REQUESTS" env-default:"off"`
+	Responses string `env:"OPENAPI_VALIDATE_RESPONSES" env-default:"off"`
+}
+
+// Validator validates the requests and the responses of the routes documented in the OpenAPI specification.
+type Validator struct {
+	router       routers.Router
+	logger       *zap.Logger
+	requests     string
+	responses    string
+	maxBodyBytes int64
+	options      *openapi3filter.Options
+}
+
+// responseWriter buffers a response until it is validated. Streams are not validated: the buffered response
+// is written as soon as it turns out to be a stream, and the rest of it is passed through.
+type responseWriter struct {
+	http.ResponseWriter
+
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+	streaming   bool
+}
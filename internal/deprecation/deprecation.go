@@ -0,0 +1,177 @@
+package deprecation
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// HeaderDeprecation announces when a route is deprecated (RFC 9745).
+	HeaderDeprecation = "Deprecation"
+
+	// HeaderSunset announces when a route stops being served (RFC 8594).
+	HeaderSunset = "Sunset"
+)
+
+// New creates Deprecations from the rules of the configuration.
+func New(config *Config) (*Deprecations, error) {
+	rules, err := Parse(config.Routes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Deprecations{rules: rules}, nil
+}
+
+// Parse parses the rules in the format of Config.Routes.
+func Parse(spec string) ([]Rule, error) {
+	var rules []Rule
+
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, dates, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("deprecation %q: missing '=' before the dates", entry)
+		}
+
+		rule := Rule{}
+
+		route = strings.TrimSpace(route)
+		if method, pattern, ok := strings.Cut(route, " "); ok {
+			rule.Method = strings.ToUpper(method)
+			route = strings.TrimSpace(pattern)
+		}
+
+		if !strings.HasPrefix(route, "/") {
+			return nil, fmt.Errorf("deprecation %q: route pattern must start with '/'", entry)
+		}
+
+		rule.Pattern, rule.Prefix = strings.CutSuffix(route, "*")
+
+		deprecated, sunset, hasSunset := strings.Cut(dates, ",")
+
+		var err error
+		rule.Deprecated, err = parseDate(deprecated)
+		if err != nil {
+			return nil, fmt.Errorf("deprecation %q: invalid deprecation date: %w", entry, err)
+		}
+
+		if hasSunset {
+			rule.Sunset, err = parseDate(sunset)
+			if err != nil {
+				return nil, fmt.Errorf("deprecation %q: invalid sunset date: %w", entry, err)
+			}
+
+			if rule.Sunset.Before(rule.Deprecated) {
+				return nil, fmt.Errorf("deprecation %q: sunset date is before the deprecation date", entry)
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// parseDate parses a date in the YYYY-MM-DD or RFC 3339 format.
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// Middleware sets the deprecation headers of the responses of the routes matching a rule.
+// The first matching rule wins.
+func (d *Deprecations) Middleware(next http.Handler) http.Handler {
+	if len(d.rules) == 0 {
+		return next
+	}
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&responseWriter{ResponseWriter: w, deprecations: d, request: r}, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// match returns the rule of the given method and chi route pattern.
+func (d *Deprecations) match(method, pattern string) (Rule, bool) {
+	for _, rule := range d.rules {
+		if rule.Method != "" && rule.Method != method {
+			continue
+		}
+
+		if pattern == rule.Pattern || (rule.Prefix && strings.HasPrefix(pattern, rule.Pattern)) {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// setHeaders sets the deprecation headers if the route of the request is deprecated.
+func (rw *responseWriter) setHeaders() {
+	rctx := chi.RouteContext(rw.request.Context())
+	if rctx == nil {
+		return
+	}
+
+	rule, ok := rw.deprecations.match(rw.request.Method, rctx.RoutePattern())
+	if !ok {
+		return
+	}
+
+	header := rw.Header()
+	header.Set(HeaderDeprecation, "@"+strconv.FormatInt(rule.Deprecated.Unix(), 10))
+	if !rule.Sunset.IsZero() {
+		header.Set(HeaderSunset, rule.Sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+// WriteHeader sets the deprecation headers before writing the status code.
+func (rw *responseWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.setHeaders()
+	}
+
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write sets the deprecation headers before writing the body, if the status code was not written.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush sets the deprecation headers before flushing the response, so streams get them too.
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
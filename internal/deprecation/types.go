@@ -0,0 +1,44 @@
+package deprecation
+
+import (
+	"net/http"
+	"time"
+)
+
+// Config defines the deprecated routes of the API.
+// Routes is a list of rules separated by ';', each in the form [METHOD ]PATTERN=DEPRECATED[,SUNSET],
+// e.g. "GET /v1/subscriptions/total=2025-09-01,2026-03-01;/v1/*=2025-10-01".
+// PATTERN is a chi route pattern, or a prefix of one when it ends with '*'; the dates are in the
+// YYYY-MM-DD or RFC 3339 format.
+type Config struct {
+	Routes string `env:"API_DEPRECATIONS"`
+}
+
+// Rule marks the routes matching a pattern as deprecated.
+type Rule struct {
+	// Method restricts the rule to a request method; an empty one matches every method.
+	Method string
+
+	// Pattern is the chi route pattern matched; when Prefix is set it matches every pattern starting with it.
+	Pattern string
+	Prefix  bool
+
+	// Deprecated is the date the routes are deprecated at, and Sunset the optional date they are removed at.
+	Deprecated time.Time
+	Sunset     time.Time
+}
+
+// Deprecations sets the Deprecation and Sunset headers of the responses of deprecated routes.
+type Deprecations struct {
+	rules []Rule
+}
+
+// responseWriter sets the deprecation headers of a request right before its response is written,
+// when the route pattern of the request is fully resolved.
+type responseWriter struct {
+	http.ResponseWriter
+
+	deprecations *Deprecations
+	request      *http.Request
+	wroteHeader  bool
+}
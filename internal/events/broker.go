@@ -0,0 +1,155 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+// Broker listens for subscription lifecycle events published by Postgres NOTIFY
+// and fans them out to the connected stream clients. Every replica runs its own Broker,
+// so clients receive the changes made through any replica.
+type Broker struct {
+	storage    Storage
+	logger     *zap.Logger
+	bufferSize int
+	heartbeat  time.Duration
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// subscriber is a single stream client interested in the events of userID (or of everyone, if empty)
+// within tenantID.
+type subscriber struct {
+	tenantID string
+	userID   string
+	ch       chan *api.Event
+}
+
+// New creates and returns a new Broker instance, applying defaults to the unset config fields.
+func New(config *Config, storage Storage, logger *zap.Logger) *Broker {
+	if config.BufferSize == 0 {
+		config.BufferSize = DefaultBufferSize
+	}
+
+	if config.Heartbeat == 0 {
+		config.Heartbeat = DefaultHeartbeat
+	}
+
+	return &Broker{
+		storage:     storage,
+		logger:      logger,
+		bufferSize:  config.BufferSize,
+		heartbeat:   config.Heartbeat,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Heartbeat returns the interval at which idle streams should send a keep-alive comment.
+func (b *Broker) Heartbeat() time.Duration {
+	return b.heartbeat
+}
+
+// Start listens for notifications in background until Stop is called or ctx is done,
+// reconnecting if the notification connection fails.
+func (b *Broker) Start(ctx context.Context) {
+	ctx, b.cancel = context.WithCancel(ctx)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		for {
+			err := b.storage.ListenEvents(ctx, func(id int) { b.publish(ctx, id) })
+			if err != nil {
+				b.logger.Error("Broker: listening failed", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}()
+}
+
+// Stop stops listening and closes all subscriber channels, which ends the open streams.
+func (b *Broker) Stop() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+
+	b.wg.Wait()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		close(sub.ch)
+		delete(b.subscribers, sub)
+	}
+}
+
+// Subscribe registers a stream client for the events of userID (or of all users, if empty) within tenantID.
+// The returned channel is closed when the client falls too far behind or the broker stops;
+// the returned function must be called once the client goes away.
+func (b *Broker) Subscribe(tenantID string, userID string) (<-chan *api.Event, func()) {
+	sub := &subscriber{
+		tenantID: tenantID,
+		userID:   userID,
+		ch:       make(chan *api.Event, b.bufferSize),
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[sub]; ok {
+			close(sub.ch)
+			delete(b.subscribers, sub)
+		}
+	}
+
+	return sub.ch, unsubscribe
+}
+
+// publish loads the notified event of any tenant and sends it to the interested subscribers.
+// Subscribers whose buffer is full are disconnected, they are expected to resume with Last-Event-ID.
+func (b *Broker) publish(ctx context.Context, id int) {
+	event, err := b.storage.GetEvent(tenant.WithTenant(ctx, tenant.All), id)
+	if err != nil {
+		b.logger.Error("Broker: cannot load event", zap.Int("event_id", id), zap.Error(err))
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		if sub.tenantID != event.TenantID || (sub.userID != "" && sub.userID != event.Data.UserID) {
+			continue
+		}
+
+		select {
+		case sub.ch <- event:
+		default:
+			b.logger.Warn("Broker: disconnecting slow subscriber", zap.Int("event_id", id))
+			close(sub.ch)
+			delete(b.subscribers, sub)
+		}
+	}
+}
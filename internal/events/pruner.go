@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/tenant"
+)
+
+// Pruner deletes the events older than the retention from the outbox, together with their webhook deliveries.
+// The events still being delivered to webhooks are kept until their deliveries finish.
+type Pruner struct {
+	storage      PruneStorage
+	logger       *zap.Logger
+	retention    time.Duration
+	interval     time.Duration
+	undispatched bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPruner creates and returns a new Pruner instance, applying default retention and interval if not set.
+// If undispatched is set, which it should be when no webhook dispatcher runs, the events not fanned out
+// to the webhooks are deleted as well.
+func NewPruner(config *Config, storage PruneStorage, undispatched bool, logger *zap.Logger) *Pruner {
+	if config.Retention == 0 {
+		config.Retention = DefaultRetention
+	}
+
+	if config.PruneInterval == 0 {
+		config.PruneInterval = DefaultPruneInterval
+	}
+
+	return &Pruner{
+		storage:      storage,
+		logger:       logger,
+		retention:    config.Retention,
+		interval:     config.PruneInterval,
+		undispatched: undispatched,
+	}
+}
+
+// Start runs the pruner loop in background until Stop is called or ctx is done.
+func (p *Pruner) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := p.RunOnce(ctx); err != nil {
+				p.logger.Error("Pruner: run failed", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the pruner loop and waits for the pruning in flight to finish.
+func (p *Pruner) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+
+	p.wg.Wait()
+}
+
+// RunOnce deletes the expired events of all tenants in batches.
+func (p *Pruner) RunOnce(ctx context.Context) error {
+	ctx = tenant.WithTenant(ctx, tenant.All)
+
+	var total int64
+	for ctx.Err() == nil {
+		rows, err := p.storage.PruneEvents(ctx, p.retention, p.undispatched, pruneBatchSize)
+		if err != nil {
+			return fmt.Errorf("RunOnce: %w", err)
+		}
+
+		total += rows
+		if rows < pruneBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		p.logger.Info("Pruner: events pruned", zap.Int64("events", total))
+	}
+
+	return nil
+}
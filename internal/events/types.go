@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"subscriptions/internal/api"
+)
+
+const (
+	// DefaultBufferSize defines how many events may wait for a slow stream client before it is disconnected.
+	DefaultBufferSize = 64
+
+	// DefaultHeartbeat defines how often a comment is sent to keep idle streams open.
+	DefaultHeartbeat = 15 * time.Second
+
+	// DefaultRetention defines how long the events are kept in the outbox by default.
+	DefaultRetention = 7 * 24 * time.Hour
+
+	// DefaultPruneInterval defines how often the outbox is pruned by default.
+	DefaultPruneInterval = time.Hour
+
+	// pruneBatchSize defines how many events are deleted per query when the outbox is pruned.
+	pruneBatchSize = 1000
+
+	// reconnectDelay defines the pause before listening again after the notification connection fails.
+	reconnectDelay = time.Second
+)
+
+// Config defines the event stream settings. The events are kept in the outbox for Retention, so streams
+// can be resumed and webhooks retried within it; the Pruner deletes the older ones every PruneInterval.
+type Config struct {
+	BufferSize    int           `env:"EVENTS_BUFFER_SIZE" env-default:"64"`
+	Heartbeat     time.Duration `env:"EVENTS_HEARTBEAT" env-default:"15s"`
+	Retention     time.Duration `env:"EVENTS_RETENTION" env-default:"168h"`
+	PruneInterval time.Duration `env:"EVENTS_PRUNE_INTERVAL" env-default:"1h"`
+}
+
+// Storage defines the storage operations required by the Broker.
+type Storage interface {
+	ListenEvents(context.Context, func(int)) error
+	GetEvent(context.Context, int) (*api.Event, error)
+}
+
+// PruneStorage defines the storage operations required by the Pruner.
+type PruneStorage interface {
+	PruneEvents(context.Context, time.Duration, bool, int) (int64, error)
+}
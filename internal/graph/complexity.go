@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// analysis estimates the cost of a validated query document before it is executed.
+type analysis struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+}
+
+// analyze returns the complexity and the depth of the operation of the document with the given name,
+// or of its only operation if the name is empty. See Config for how the complexity is counted.
+func analyze(schema *graphql.Schema, document *ast.Document, operationName string) (int, int) {
+	a := &analysis{
+		schema:    schema,
+		fragments: make(map[string]*ast.FragmentDefinition),
+	}
+
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch d := definition.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				operation = d
+			}
+		}
+	}
+
+	if operation == nil {
+		return 0, 0
+	}
+
+	return a.selectionSet(operation.SelectionSet, schema.QueryType(), 1)
+}
+
+// selectionSet returns the complexity and the depth of the selections of an object of the given type
+// nested at the given depth.
+func (a *analysis) selectionSet(set *ast.SelectionSet, parent *graphql.Object, depth int) (int, int) {
+	if set == nil || parent == nil {
+		return 0, 0
+	}
+
+	complexity, maxDepth := 0, depth
+
+	for _, selection := range set.Selections {
+		var c, d int
+
+		switch s := selection.(type) {
+		case *ast.Field:
+			c, d = a.field(s, parent, depth)
+		case *ast.InlineFragment:
+			c, d = a.selectionSet(s.SelectionSet, a.condition(s.TypeCondition, parent), depth)
+		case *ast.FragmentSpread:
+			if fragment, ok := a.fragments[s.Name.Value]; ok {
+				c, d = a.selectionSet(fragment.SelectionSet, a.condition(fragment.TypeCondition, parent), depth)
+			}
+		}
+
+		complexity += c
+		maxDepth = max(maxDepth, d)
+	}
+
+	return complexity, maxDepth
+}
+
+// field returns the complexity and the depth of a field of an object of the given type.
+// Introspection fields, which are not defined by the object, count as a single field.
+func (a *analysis) field(field *ast.Field, parent *graphql.Object, depth int) (int, int) {
+	definition, ok := parent.Fields()[field.Name.Value]
+	if !ok {
+		return 1, depth
+	}
+
+	multiplier := 1
+	t := definition.Type
+	for {
+		if nonNull, ok := t.(*graphql.NonNull); ok {
+			t = nonNull.OfType
+			continue
+		}
+
+		if list, ok := t.(*graphql.List); ok {
+			multiplier *= listMultiplier
+			t = list.OfType
+			continue
+		}
+
+		break
+	}
+
+	object, _ := t.(*graphql.Object)
+	complexity, maxDepth := a.selectionSet(field.SelectionSet, object, depth+1)
+
+	return 1 + multiplier*complexity, max(depth, maxDepth)
+}
+
+// condition returns the type a fragment applies to, or parent if it has no type condition.
+func (a *analysis) condition(named *ast.Named, parent *graphql.Object) *graphql.Object {
+	if named == nil {
+		return parent
+	}
+
+	object, _ := a.schema.Type(named.Name.Value).(*graphql.Object)
+	return object
+}
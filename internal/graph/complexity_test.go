@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"go.uber.org/zap"
+)
+
+func TestAnalyze(t *testing.T) {
+	h, err := New(&Config{}, &fakeStorage{}, nil, nil, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		complexity    int
+		depth         int
+	}{
+		{
+			name:       "scalar of a single object",
+			query:      `{ user(id: "alice") { id } }`,
+			complexity: 2,
+			depth:      2,
+		},
+		{
+			name:       "list",
+			query:      `{ users { id } }`,
+			complexity: 1 + 10*1,
+			depth:      2,
+		},
+		{
+			name:       "nested lists",
+			query:      `{ users { id subscriptions { price service { name } } } }`,
+			complexity: 1 + 10*(1+(1+10*(1+(1+1)))),
+			depth:      4,
+		},
+		{
+			name: "fragment",
+			query: `
+				{ services { ...costs } }
+				fragment costs on Service { totalCost(from: "2025-01", to: "2025-12") monthlyCosts(from: "2025-01", to: "2025-12") { total } }`,
+			complexity: 1 + 10*(1+(1+10*1)),
+			depth:      3,
+		},
+		{
+			name:       "inline fragment",
+			query:      `{ service(name: "Netflix") { ... on Service { name users { id } } } }`,
+			complexity: 1 + (1 + (1 + 10*1)),
+			depth:      3,
+		},
+		{
+			name:       "introspection",
+			query:      `{ __typename users { __typename } }`,
+			complexity: 1 + (1 + 10*1),
+			depth:      2,
+		},
+		{
+			name: "named operation",
+			query: `
+				query Small { user(id: "alice") { id } }
+				query Large { users { subscriptions { price } } }`,
+			operationName: "Large",
+			complexity:    1 + 10*(1+10*1),
+			depth:         3,
+		},
+		{
+			name:          "unknown operation",
+			query:         `query Small { user(id: "alice") { id } }`,
+			operationName: "Large",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			complexity, depth := analyze(&h.schema, document, tt.operationName)
+			if complexity != tt.complexity || depth != tt.depth {
+				t.Errorf("analyze() = %d, %d, want %d, %d", complexity, depth, tt.complexity, tt.depth)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		query  string
+		err    string
+	}{
+		{
+			name:   "within the limits",
+			config: Config{MaxComplexity: 111, MaxDepth: 3},
+			query:  `{ users { subscriptions { price } } }`,
+		},
+		{
+			name:   "too complex",
+			config: Config{MaxComplexity: 110, MaxDepth: 3},
+			query:  `{ users { subscriptions { price } } }`,
+			err:    "query complexity 111 exceeds the limit of 110",
+		},
+		{
+			name:   "too deep",
+			config: Config{MaxComplexity: 1000, MaxDepth: 2},
+			query:  `{ users { subscriptions { price } } }`,
+			err:    "query depth 3 exceeds the limit of 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &fakeStorage{}
+
+			h, err := New(&tt.config, storage, nil, nil, zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := serve(t, h, newContext(""), tt.query)
+			if tt.err == "" {
+				if len(res.Errors) > 0 {
+					t.Fatalf("errors = %v", res.Errors)
+				}
+				return
+			}
+
+			if len(res.Errors) != 1 || res.Errors[0].Message != tt.err {
+				t.Fatalf("errors = %v, want %q", res.Errors, tt.err)
+			}
+
+			if storage.calls() != 0 {
+				t.Errorf("storage was called %d times, want the query rejected before it is executed", storage.calls())
+			}
+		})
+	}
+}
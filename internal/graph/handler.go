@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/cache"
+	"subscriptions/internal/problem"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// New creates and returns a new Handler instance backed by storage and caching the cost aggregates in aggregates,
+// applying the default limits if not set. The costs of the periods covered by the monthly spend rollup are read
+// from rollups, unless it is nil.
+func New(config *Config, storage Storage, aggregates *cache.Cache, rollups Rollups, logger *zap.Logger) (*Handler, error) {
+	if config.MaxComplexity == 0 {
+		config.MaxComplexity = DefaultMaxComplexity
+	}
+
+	if config.MaxDepth == 0 {
+		config.MaxDepth = DefaultMaxDepth
+	}
+
+	h := &Handler{
+		storage:       storage,
+		aggregates:    aggregates,
+		rollups:       rollups,
+		logger:        logger,
+		maxComplexity: config.MaxComplexity,
+		maxDepth:      config.MaxDepth,
+	}
+
+	schema, err := newSchema(h)
+	if err != nil {
+		return nil, fmt.Errorf("New: %w", err)
+	}
+	h.schema = schema
+
+	return h, nil
+}
+
+// ServeHTTP executes the GraphQL query in the JSON body of a POST request. Requests which are not GraphQL
+// requests are rejected with problem details; errors of the query itself are reported in the errors
+// of the GraphQL response, like GraphQL clients expect.
+//
+// @Summary Run a GraphQL reporting query
+// @Description Executes a GraphQL query over subscriptions, users, services and their costs.
+// @Description Errors of the query are reported in the errors of the GraphQL response with status 200.
+// @Tags reports
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body request true "GraphQL request"
+// @Success 200 {object} object "GraphQL response"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Router /graphql [post]
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.Write(h.logger, w, r, problem.Wrap(problem.CodeBodyTooLarge, "request body is too large", err))
+			return
+		}
+
+		problem.Write(h.logger, w, r, problem.Wrap(problem.CodeInvalidBody, "cannot decode request body: "+err.Error(), err))
+		return
+	}
+
+	if req.Query == "" {
+		problem.Write(h.logger, w, r, problem.New(problem.CodeInvalidRequest, "'query' is required"))
+		return
+	}
+
+	ctx := r.Context()
+
+	// The loaders fetch the data of the cached aggregates too, so with the cache enabled they read from the primary:
+	// a value read from a replica which has not seen a change yet would stay cached after the change invalidated it.
+	if h.aggregates.Enabled() {
+		ctx = postgresClient.WithPrimary(ctx)
+	}
+
+	ctx = context.WithValue(ctx, loadersKey{}, h.newLoaders(ctx))
+
+	h.writeResult(w, h.execute(ctx, &req))
+}
+
+// execute parses, validates and executes the query, rejecting it if it exceeds the complexity or depth limits.
+func (h *Handler) execute(ctx context.Context, req *request) *graphql.Result {
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	validation := graphql.ValidateDocument(&h.schema, document, nil)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	complexity, depth := analyze(&h.schema, document, req.OperationName)
+	if complexity > h.maxComplexity {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(
+			fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, h.maxComplexity))}
+	}
+
+	if depth > h.maxDepth {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(
+			fmt.Errorf("query depth %d exceeds the limit of %d", depth, h.maxDepth))}
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           document,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+}
+
+// writeResult writes the GraphQL response.
+func (h *Handler) writeResult(w http.ResponseWriter, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.logger.Warn("GraphQL: cannot send result to caller", zap.Error(err))
+	}
+}
@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"context"
+	"slices"
+)
+
+// newLoader creates and returns a new loader fetching the batches of keys with fetch.
+func newLoader[K comparable, V any](fetch func(context.Context, []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch:   fetch,
+		results: make(map[K]V),
+		errs:    make(map[K]error),
+	}
+}
+
+// load requests the value of key and returns a thunk resolving it. The thunks of all keys requested before
+// the first of them is called are fetched together, which the executor does level by level of the query.
+func (l *loader[K, V]) load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	_, done := l.results[key]
+	_, failed := l.errs[key]
+	if !done && !failed && !slices.Contains(l.pending, key) {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			batch := l.pending
+			l.pending = nil
+
+			res, err := l.fetch(ctx, batch)
+			for _, k := range batch {
+				if err != nil {
+					l.errs[k] = err
+					continue
+				}
+
+				l.results[k] = res[k]
+			}
+		}
+
+		return l.results[key], l.errs[key]
+	}
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestLoader(t *testing.T) {
+	var batches [][]string
+
+	l := newLoader(func(_ context.Context, keys []string) (map[string]int, error) {
+		batches = append(batches, slices.Clone(keys))
+
+		res := make(map[string]int)
+		for _, key := range keys {
+			res[key] = len(key)
+		}
+
+		return res, nil
+	})
+
+	ctx := context.Background()
+
+	// The keys of a level are requested before any of them is needed.
+	thunks := []func() (int, error){l.load(ctx, "a"), l.load(ctx, "bb"), l.load(ctx, "a"), l.load(ctx, "ccc")}
+	for i, want := range []int{1, 2, 1, 3} {
+		if got, err := thunks[i](); err != nil || got != want {
+			t.Errorf("thunk %d = %d, %v, want %d", i, got, err, want)
+		}
+	}
+
+	// The next level fetches only the keys which were not loaded yet.
+	next := []func() (int, error){l.load(ctx, "bb"), l.load(ctx, "dddd")}
+	for i, want := range []int{2, 4} {
+		if got, err := next[i](); err != nil || got != want {
+			t.Errorf("next thunk %d = %d, %v, want %d", i, got, err, want)
+		}
+	}
+
+	want := [][]string{{"a", "bb", "ccc"}, {"dddd"}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Errorf("batches = %v, want %v", batches, want)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	fetchErr := errors.New("connection refused")
+	calls := 0
+
+	l := newLoader(func(context.Context, []string) (map[string]int, error) {
+		calls++
+		return nil, fetchErr
+	})
+
+	ctx := context.Background()
+	first, second := l.load(ctx, "a"), l.load(ctx, "b")
+
+	for _, thunk := range []func() (int, error){first, second, l.load(ctx, "a")} {
+		if _, err := thunk(); !errors.Is(err, fetchErr) {
+			t.Errorf("thunk error = %v, want %v", err, fetchErr)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("fetch was called %d times, want the failed keys not to be fetched again", calls)
+	}
+}
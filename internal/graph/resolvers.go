@@ -0,0 +1,528 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+	"subscriptions/internal/cache"
+	"subscriptions/internal/problem"
+	"subscriptions/internal/service"
+)
+
+// newLoaders creates the loaders of a request. Callers restricted to their own data only get their own subscriptions
+// and their own spend.
+func (h *Handler) newLoaders(ctx context.Context) *loaders {
+	owner := auth.UserScope(ctx)
+
+	l := &loaders{
+		generation: h.aggregates.Generation(ctx),
+		userSubscriptions: newLoader(func(ctx context.Context, userIDs []string) (map[string][]*api.Subscription, error) {
+			if owner != "" {
+				userIDs = slices.DeleteFunc(userIDs, func(userID string) bool { return userID != owner })
+				if len(userIDs) == 0 {
+					return nil, nil
+				}
+			}
+
+			subscriptions, err := h.storage.ListSubscriptionsOfUsers(ctx, userIDs)
+			if err != nil {
+				return nil, err
+			}
+
+			return groupBy(subscriptions, func(s *api.Subscription) string { return s.UserID }), nil
+		}),
+		serviceSubscriptions: newLoader(func(ctx context.Context, names []string) (map[string][]*api.Subscription, error) {
+			subscriptions, err := h.storage.ListSubscriptionsOfServices(ctx, names)
+			if err != nil {
+				return nil, err
+			}
+
+			if owner != "" {
+				subscriptions = slices.DeleteFunc(subscriptions, func(s *api.Subscription) bool { return s.UserID != owner })
+			}
+
+			return groupBy(subscriptions, func(s *api.Subscription) string { return s.ServiceName }), nil
+		}),
+	}
+
+	if h.rollups == nil {
+		return l
+	}
+
+	l.coverage = sync.OnceValues(func() (time.Time, error) {
+		through, ok, err := h.rollups.GetMonthlySpendCoverage(ctx)
+		if !ok {
+			return time.Time{}, err
+		}
+
+		return through, err
+	})
+
+	l.userSpend = newLoader(func(ctx context.Context, keys []spendKey) (map[spendKey][]api.MonthlyCost, error) {
+		return fetchSpend(keys, func(userIDs []string, p period) (map[string][]api.MonthlyCost, error) {
+			if owner != "" {
+				userIDs = slices.DeleteFunc(userIDs, func(userID string) bool { return userID != owner })
+				if len(userIDs) == 0 {
+					return nil, nil
+				}
+			}
+
+			return h.rollups.ListMonthlySpendOfUsers(ctx, userIDs, p.start, p.end)
+		})
+	})
+
+	l.serviceSpend = newLoader(func(ctx context.Context, keys []spendKey) (map[spendKey][]api.MonthlyCost, error) {
+		return fetchSpend(keys, func(names []string, p period) (map[string][]api.MonthlyCost, error) {
+			return h.rollups.ListMonthlySpendOfServices(ctx, names, owner, p.start, p.end)
+		})
+	})
+
+	return l
+}
+
+func (h *Handler) resolveSubscriptions(p graphql.ResolveParams) (interface{}, error) {
+	userID, _ := p.Args["userId"].(string)
+	serviceName, _ := p.Args["serviceName"].(string)
+
+	if owner := auth.UserScope(p.Context); owner != "" {
+		if userID != "" && userID != owner {
+			return nil, h.fail(problem.New(problem.CodeForbidden, "cannot list subscriptions of another user"))
+		}
+
+		userID = owner
+	}
+
+	subscriptions, err := h.storage.ListFilteredSubscriptions(p.Context, userID, serviceName)
+	if err != nil {
+		return nil, h.fail(err)
+	}
+
+	return subscriptions, nil
+}
+
+func (h *Handler) resolveUsers(p graphql.ResolveParams) (interface{}, error) {
+	if owner := auth.UserScope(p.Context); owner != "" {
+		return []userNode{{ID: owner}}, nil
+	}
+
+	userIDs, err := h.storage.ListUserIDs(p.Context)
+	if err != nil {
+		return nil, h.fail(err)
+	}
+
+	res := make([]userNode, 0, len(userIDs))
+	for _, userID := range userIDs {
+		res = append(res, userNode{ID: userID})
+	}
+
+	return res, nil
+}
+
+// resolveUser returns the user with the given id; restricted callers see no other user than themselves.
+func (h *Handler) resolveUser(p graphql.ResolveParams) (interface{}, error) {
+	userID, _ := p.Args["id"].(string)
+
+	if owner := auth.UserScope(p.Context); owner != "" && userID != owner {
+		return nil, nil
+	}
+
+	return userNode{ID: userID}, nil
+}
+
+func (h *Handler) resolveServices(p graphql.ResolveParams) (interface{}, error) {
+	var names []string
+
+	if owner := auth.UserScope(p.Context); owner != "" {
+		subscriptions, err := h.storage.ListFilteredSubscriptions(p.Context, owner, "")
+		if err != nil {
+			return nil, h.fail(err)
+		}
+
+		names = distinct(subscriptions, func(s *api.Subscription) string { return s.ServiceName })
+	} else {
+		var err error
+		names, err = h.storage.ListServiceNames(p.Context)
+		if err != nil {
+			return nil, h.fail(err)
+		}
+	}
+
+	res := make([]serviceNode, 0, len(names))
+	for _, name := range names {
+		res = append(res, serviceNode{Name: name})
+	}
+
+	return res, nil
+}
+
+func (h *Handler) resolveService(p graphql.ResolveParams) (interface{}, error) {
+	name, _ := p.Args["name"].(string)
+	return serviceNode{Name: name}, nil
+}
+
+func (h *Handler) resolveUserSubscriptions(p graphql.ResolveParams) (interface{}, error) {
+	serviceName, _ := p.Args["serviceName"].(string)
+
+	return h.userSubscriptions(p, func(subscriptions []*api.Subscription) (interface{}, error) {
+		if serviceName == "" {
+			return subscriptions, nil
+		}
+
+		return slices.DeleteFunc(slices.Clone(subscriptions), func(s *api.Subscription) bool { return s.ServiceName != serviceName }), nil
+	})
+}
+
+func (h *Handler) resolveUserServices(p graphql.ResolveParams) (interface{}, error) {
+	return h.userSubscriptions(p, func(subscriptions []*api.Subscription) (interface{}, error) {
+		res := []serviceNode{}
+		for _, name := range distinct(subscriptions, func(s *api.Subscription) string { return s.ServiceName }) {
+			res = append(res, serviceNode{Name: name})
+		}
+
+		return res, nil
+	})
+}
+
+func (h *Handler) resolveUserTotalCost(p graphql.ResolveParams) (interface{}, error) {
+	return h.userAggregate(p, cache.AggregateTotal)
+}
+
+func (h *Handler) resolveUserMonthlyCosts(p graphql.ResolveParams) (interface{}, error) {
+	return h.userAggregate(p, cache.AggregateMonthly)
+}
+
+func (h *Handler) resolveServiceSubscriptions(p graphql.ResolveParams) (interface{}, error) {
+	return h.serviceSubscriptions(p, func(subscriptions []*api.Subscription) (interface{}, error) {
+		return subscriptions, nil
+	})
+}
+
+func (h *Handler) resolveServiceUsers(p graphql.ResolveParams) (interface{}, error) {
+	return h.serviceSubscriptions(p, func(subscriptions []*api.Subscription) (interface{}, error) {
+		res := []userNode{}
+		for _, userID := range distinct(subscriptions, func(s *api.Subscription) string { return s.UserID }) {
+			res = append(res, userNode{ID: userID})
+		}
+
+		return res, nil
+	})
+}
+
+func (h *Handler) resolveServiceSubscriberCount(p graphql.ResolveParams) (interface{}, error) {
+	return h.serviceSubscriptions(p, func(subscriptions []*api.Subscription) (interface{}, error) {
+		return len(distinct(subscriptions, func(s *api.Subscription) string { return s.UserID })), nil
+	})
+}
+
+func (h *Handler) resolveServiceTotalCost(p graphql.ResolveParams) (interface{}, error) {
+	return h.serviceAggregate(p, cache.AggregateTotal)
+}
+
+func (h *Handler) resolveServiceMonthlyCosts(p graphql.ResolveParams) (interface{}, error) {
+	return h.serviceAggregate(p, cache.AggregateMonthly)
+}
+
+// userAggregate resolves the aggregate of the subscriptions of the user being resolved for the period of the field.
+func (h *Handler) userAggregate(p graphql.ResolveParams, aggregate string) (interface{}, error) {
+	start, end, err := parsePeriod(p)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := p.Source.(userNode).ID
+	query := cache.Query{Aggregate: aggregate, UserID: userID, Start: start, End: end}
+
+	// Restricted callers get no subscriptions of other users, see newLoaders, so their aggregates are not cached.
+	owner := auth.UserScope(p.Context)
+
+	l := loadersFrom(p.Context)
+	source := aggregateSource{name: userID, subscriptions: l.userSubscriptions, spend: l.userSpend}
+
+	return h.aggregate(p, query, owner == "" || owner == userID, source)
+}
+
+// serviceAggregate resolves the aggregate of the subscriptions of the service being resolved for the period of the field.
+// The aggregates of restricted callers only cover their own subscriptions, see newLoaders.
+func (h *Handler) serviceAggregate(p graphql.ResolveParams, aggregate string) (interface{}, error) {
+	start, end, err := parsePeriod(p)
+	if err != nil {
+		return nil, err
+	}
+
+	name := p.Source.(serviceNode).Name
+	query := cache.Query{
+		Aggregate:   aggregate,
+		UserID:      auth.UserScope(p.Context),
+		ServiceName: name,
+		Start:       start,
+		End:         end,
+	}
+
+	l := loadersFrom(p.Context)
+	source := aggregateSource{name: name, subscriptions: l.serviceSubscriptions, spend: l.serviceSpend}
+
+	return h.aggregate(p, query, true, source)
+}
+
+// aggregate returns the cached result of the query, or a thunk computing it from the monthly spend of the source
+// if the rollup covers the period, or from its subscriptions otherwise, and caching it if cacheable.
+func (h *Handler) aggregate(p graphql.ResolveParams, query cache.Query, cacheable bool, source aggregateSource) (interface{}, error) {
+	if cacheable {
+		if value, ok := h.aggregates.Get(p.Context, query); ok {
+			return value, nil
+		}
+	}
+
+	done := func(value interface{}) (interface{}, error) {
+		if cacheable {
+			h.aggregates.Set(p.Context, query, loadersFrom(p.Context).generation, value)
+		}
+
+		return value, nil
+	}
+
+	covered, err := h.covered(p.Context, query.End)
+	if err != nil {
+		return nil, h.fail(err)
+	}
+
+	if !covered {
+		thunk := source.subscriptions.load(p.Context, source.name)
+
+		return h.then(thunk, func(subscriptions []*api.Subscription) (interface{}, error) {
+			if query.Aggregate == cache.AggregateMonthly {
+				return done(api.MonthlyCosts(query.Start, query.End, subscriptions))
+			}
+
+			return done(api.TotalPrice(query.Start, query.End, subscriptions))
+		}), nil
+	}
+
+	thunk := source.spend.load(p.Context, spendKey{name: source.name, period: period{start: query.Start, end: query.End}})
+
+	return func() (interface{}, error) {
+		costs, err := thunk()
+		if err != nil {
+			return nil, h.fail(err)
+		}
+
+		if costs == nil {
+			costs = api.MonthlyCosts(query.Start, query.End, nil)
+		}
+
+		if query.Aggregate == cache.AggregateMonthly {
+			return done(costs)
+		}
+
+		total := 0
+		for _, cost := range costs {
+			total += cost.Total
+		}
+
+		return done(total)
+	}, nil
+}
+
+// covered reports whether the monthly spend rollup covers the months through end.
+func (h *Handler) covered(ctx context.Context, end time.Time) (bool, error) {
+	coverage := loadersFrom(ctx).coverage
+	if coverage == nil {
+		return false, nil
+	}
+
+	through, err := coverage()
+	if err != nil {
+		return false, err
+	}
+
+	return !through.IsZero() && !end.After(through), nil
+}
+
+// userSubscriptions returns a thunk passing the subscriptions of the user being resolved to fn,
+// so the subscriptions of all users of a level of the query are loaded together.
+func (h *Handler) userSubscriptions(p graphql.ResolveParams, fn func([]*api.Subscription) (interface{}, error)) (interface{}, error) {
+	source := p.Source.(userNode)
+	thunk := loadersFrom(p.Context).userSubscriptions.load(p.Context, source.ID)
+
+	return h.then(thunk, fn), nil
+}
+
+// serviceSubscriptions returns a thunk passing the subscriptions of the service being resolved to fn,
+// so the subscriptions of all services of a level of the query are loaded together.
+func (h *Handler) serviceSubscriptions(p graphql.ResolveParams, fn func([]*api.Subscription) (interface{}, error)) (interface{}, error) {
+	source := p.Source.(serviceNode)
+	thunk := loadersFrom(p.Context).serviceSubscriptions.load(p.Context, source.Name)
+
+	return h.then(thunk, fn), nil
+}
+
+// then returns the thunk the executor resolves once the loaded subscriptions are needed.
+func (h *Handler) then(thunk func() ([]*api.Subscription, error), fn func([]*api.Subscription) (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		subscriptions, err := thunk()
+		if err != nil {
+			return nil, h.fail(err)
+		}
+
+		if subscriptions == nil {
+			subscriptions = []*api.Subscription{}
+		}
+
+		return fn(subscriptions)
+	}
+}
+
+// fail maps err to the error reported to the client, classifying it like the REST API does,
+// so the clients never see the storage errors themselves.
+func (h *Handler) fail(err error) error {
+	e := service.Classify(err)
+	if e.Code == problem.CodeInternal {
+		h.logger.Error("GraphQL: cannot resolve field", zap.Error(err))
+		return errors.New("internal server error")
+	}
+
+	return errors.New(e.Detail)
+}
+
+func resolveServiceNameOf(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*api.Subscription).ServiceName, nil
+}
+
+func resolvePrice(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*api.Subscription).Price, nil
+}
+
+func resolveUserID(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*api.Subscription).UserID, nil
+}
+
+func resolveStartDate(p graphql.ResolveParams) (interface{}, error) {
+	return isoMonth(p.Source.(*api.Subscription).StartDate)
+}
+
+func resolveEndDate(p graphql.ResolveParams) (interface{}, error) {
+	endDate := p.Source.(*api.Subscription).EndDate
+	if endDate == "" {
+		return nil, nil
+	}
+
+	return isoMonth(endDate)
+}
+
+func resolveSubscriptionUser(p graphql.ResolveParams) (interface{}, error) {
+	return userNode{ID: p.Source.(*api.Subscription).UserID}, nil
+}
+
+func resolveSubscriptionService(p graphql.ResolveParams) (interface{}, error) {
+	return serviceNode{Name: p.Source.(*api.Subscription).ServiceName}, nil
+}
+
+func resolveSubscriptionCost(p graphql.ResolveParams) (interface{}, error) {
+	start, end, err := parsePeriod(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.TotalPrice(start, end, []*api.Subscription{p.Source.(*api.Subscription)}), nil
+}
+
+func resolveUserIdentity(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(userNode).ID, nil
+}
+
+func resolveServiceName(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(serviceNode).Name, nil
+}
+
+func resolveMonth(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(api.MonthlyCost).Month.Format(api.ISOMonthLayout), nil
+}
+
+func resolveTotal(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(api.MonthlyCost).Total, nil
+}
+
+// parsePeriod parses the from and to arguments of a field.
+func parsePeriod(p graphql.ResolveParams) (time.Time, time.Time, error) {
+	from, _ := p.Args["from"].(string)
+	to, _ := p.Args["to"].(string)
+
+	start, err := api.ParseMonth(api.ISOMonthLayout, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid 'from' date format. Use YYYY-MM")
+	}
+
+	end, err := api.ParseMonth(api.ISOMonthLayout, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid 'to' date format. Use YYYY-MM")
+	}
+
+	return start.Time, end.Time, nil
+}
+
+// isoMonth converts a stored MM-YYYY month to the YYYY-MM format.
+func isoMonth(value string) (string, error) {
+	month, err := api.ParseMonth(api.MonthLayout, value)
+	if err != nil {
+		return "", err
+	}
+
+	return month.Format(api.ISOMonthLayout), nil
+}
+
+// groupBy groups the subscriptions by the given key.
+func groupBy(subscriptions []*api.Subscription, key func(*api.Subscription) string) map[string][]*api.Subscription {
+	res := make(map[string][]*api.Subscription)
+	for _, subscription := range subscriptions {
+		res[key(subscription)] = append(res[key(subscription)], subscription)
+	}
+
+	return res
+}
+
+// distinct returns the distinct keys of the subscriptions, in alphabetical order.
+func distinct(subscriptions []*api.Subscription, key func(*api.Subscription) string) []string {
+	res := []string{}
+	for _, subscription := range subscriptions {
+		res = append(res, key(subscription))
+	}
+
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+// fetchSpend fetches the monthly spend of the keys with a call of fetch per period, so the spend of all users
+// or services of a level of the query is read together.
+func fetchSpend(keys []spendKey, fetch func([]string, period) (map[string][]api.MonthlyCost, error)) (map[spendKey][]api.MonthlyCost, error) {
+	names := make(map[period][]string)
+	for _, key := range keys {
+		names[key.period] = append(names[key.period], key.name)
+	}
+
+	res := make(map[spendKey][]api.MonthlyCost, len(keys))
+	for p, names := range names {
+		spend, err := fetch(names, p)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, costs := range spend {
+			res[spendKey{name: name, period: p}] = costs
+		}
+	}
+
+	return res, nil
+}
+
+// loadersFrom returns the loaders of the request stored in ctx by the Handler.
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
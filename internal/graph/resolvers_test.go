@@ -0,0 +1,282 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+)
+
+// fakeStorage serves the subscriptions and counts the calls of every method.
+type fakeStorage struct {
+	subscriptions []*api.Subscription
+
+	mu      sync.Mutex
+	counts  map[string]int
+	batches map[string][][]string
+}
+
+func (f *fakeStorage) record(method string, keys []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.counts == nil {
+		f.counts = make(map[string]int)
+		f.batches = make(map[string][][]string)
+	}
+
+	f.counts[method]++
+	f.batches[method] = append(f.batches[method], slices.Clone(keys))
+}
+
+func (f *fakeStorage) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	total := 0
+	for _, count := range f.counts {
+		total += count
+	}
+
+	return total
+}
+
+func (f *fakeStorage) filter(keep func(*api.Subscription) bool) []*api.Subscription {
+	var res []*api.Subscription
+	for _, subscription := range f.subscriptions {
+		if keep(subscription) {
+			res = append(res, subscription)
+		}
+	}
+
+	return res
+}
+
+func (f *fakeStorage) ListFilteredSubscriptions(_ context.Context, userID string, serviceName string) ([]*api.Subscription, error) {
+	f.record("ListFilteredSubscriptions", []string{userID, serviceName})
+
+	return f.filter(func(s *api.Subscription) bool {
+		return (userID == "" || s.UserID == userID) && (serviceName == "" || s.ServiceName == serviceName)
+	}), nil
+}
+
+func (f *fakeStorage) ListSubscriptionsOfUsers(_ context.Context, userIDs []string) ([]*api.Subscription, error) {
+	f.record("ListSubscriptionsOfUsers", userIDs)
+
+	return f.filter(func(s *api.Subscription) bool { return slices.Contains(userIDs, s.UserID) }), nil
+}
+
+func (f *fakeStorage) ListSubscriptionsOfServices(_ context.Context, names []string) ([]*api.Subscription, error) {
+	f.record("ListSubscriptionsOfServices", names)
+
+	return f.filter(func(s *api.Subscription) bool { return slices.Contains(names, s.ServiceName) }), nil
+}
+
+func (f *fakeStorage) ListUserIDs(context.Context) ([]string, error) {
+	f.record("ListUserIDs", nil)
+
+	return distinct(f.subscriptions, func(s *api.Subscription) string { return s.UserID }), nil
+}
+
+func (f *fakeStorage) ListServiceNames(context.Context) ([]string, error) {
+	f.record("ListServiceNames", nil)
+
+	return distinct(f.subscriptions, func(s *api.Subscription) string { return s.ServiceName }), nil
+}
+
+// result is a decoded GraphQL response.
+type result struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// newContext returns the context of a request restricted to the data of owner, or unrestricted if owner is empty.
+func newContext(owner string) context.Context {
+	ctx := context.Background()
+	if owner == "" {
+		return ctx
+	}
+
+	return auth.WithUserScope(ctx, owner)
+}
+
+// serve runs the query through h with ctx and returns the decoded response.
+func serve(t *testing.T, h *Handler, ctx context.Context, query string) *result {
+	t.Helper()
+
+	body, err := json.Marshal(request{Query: query})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var res result
+	if err = json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	return &res
+}
+
+func newTestStorage() *fakeStorage {
+	return &fakeStorage{subscriptions: []*api.Subscription{
+		{ServiceName: "Netflix", Price: 400, UserID: "alice", StartDate: "01-2025"},
+		{ServiceName: "Spotify", Price: 200, UserID: "alice", StartDate: "03-2025"},
+		{ServiceName: "Netflix", Price: 400, UserID: "bob", StartDate: "02-2025"},
+		{ServiceName: "Yandex Plus", Price: 300, UserID: "carol", StartDate: "01-2025"},
+	}}
+}
+
+func TestBatching(t *testing.T) {
+	storage := newTestStorage()
+
+	h, err := New(&Config{MaxComplexity: 10000}, storage, nil, nil, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := serve(t, h, newContext(""), `{
+		users {
+			id
+			subscriptions { serviceName }
+			totalCost(from: "2025-01", to: "2025-12")
+			services { name subscriberCount users { id } }
+		}
+	}`)
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors = %v", res.Errors)
+	}
+
+	want := map[string]int{"ListUserIDs": 1, "ListSubscriptionsOfUsers": 1, "ListSubscriptionsOfServices": 1}
+	for method, count := range want {
+		if storage.counts[method] != count {
+			t.Errorf("%s was called %d times, want %d", method, storage.counts[method], count)
+		}
+	}
+
+	if storage.calls() != 3 {
+		t.Errorf("storage was called %v, want one call per level", storage.counts)
+	}
+
+	batches := storage.batches["ListSubscriptionsOfUsers"]
+	if len(batches) != 1 || !slices.Equal(batches[0], []string{"alice", "bob", "carol"}) {
+		t.Errorf("ListSubscriptionsOfUsers batches = %v, want all users in one", batches)
+	}
+}
+
+func TestOwnerFiltering(t *testing.T) {
+	tests := []struct {
+		name  string
+		owner string
+		query string
+		data  string
+		err   string
+	}{
+		{
+			name:  "all users",
+			query: `{ users { id } }`,
+			data:  `{"users":[{"id":"alice"},{"id":"bob"},{"id":"carol"}]}`,
+		},
+		{
+			name:  "restricted users",
+			owner: "alice",
+			query: `{ users { id } }`,
+			data:  `{"users":[{"id":"alice"}]}`,
+		},
+		{
+			name:  "other user",
+			owner: "alice",
+			query: `{ user(id: "bob") { id subscriptions { price } } }`,
+			data:  `{"user":null}`,
+		},
+		{
+			name:  "own user",
+			owner: "alice",
+			query: `{ user(id: "alice") { subscriptions { serviceName } } }`,
+			data:  `{"user":{"subscriptions":[{"serviceName":"Netflix"},{"serviceName":"Spotify"}]}}`,
+		},
+		{
+			name:  "other users through a service",
+			owner: "alice",
+			query: `{ service(name: "Netflix") { subscriberCount users { id } subscriptions { userId } } }`,
+			data:  `{"service":{"subscriberCount":1,"subscriptions":[{"userId":"alice"}],"users":[{"id":"alice"}]}}`,
+		},
+		{
+			name:  "all users through a service",
+			query: `{ service(name: "Netflix") { subscriberCount users { id } } }`,
+			data:  `{"service":{"subscriberCount":2,"users":[{"id":"alice"},{"id":"bob"}]}}`,
+		},
+		{
+			name:  "service of other users only",
+			owner: "alice",
+			query: `{ service(name: "Yandex Plus") { subscriberCount totalCost(from: "2025-01", to: "2025-12") } }`,
+			data:  `{"service":{"subscriberCount":0,"totalCost":0}}`,
+		},
+		{
+			name:  "restricted service cost",
+			owner: "alice",
+			query: `{ service(name: "Netflix") { totalCost(from: "2025-01", to: "2025-02") } }`,
+			data:  `{"service":{"totalCost":800}}`,
+		},
+		{
+			name:  "service cost",
+			query: `{ service(name: "Netflix") { totalCost(from: "2025-01", to: "2025-02") } }`,
+			data:  `{"service":{"totalCost":1200}}`,
+		},
+		{
+			name:  "restricted services",
+			owner: "alice",
+			query: `{ services { name } }`,
+			data:  `{"services":[{"name":"Netflix"},{"name":"Spotify"}]}`,
+		},
+		{
+			name:  "subscriptions of another user",
+			owner: "alice",
+			query: `{ subscriptions(userId: "bob") { price } }`,
+			err:   "cannot list subscriptions of another user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := New(&Config{}, newTestStorage(), nil, nil, zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := serve(t, h, newContext(tt.owner), tt.query)
+			if tt.err != "" {
+				if len(res.Errors) != 1 || res.Errors[0].Message != tt.err {
+					t.Fatalf("errors = %v, want %q", res.Errors, tt.err)
+				}
+				return
+			}
+
+			if len(res.Errors) > 0 {
+				t.Fatalf("errors = %v", res.Errors)
+			}
+
+			if string(res.Data) != tt.data {
+				t.Errorf("data = %s, want %s", res.Data, tt.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+// newSchema builds the schema of the reporting queries resolved by h.
+// Months are in the YYYY-MM format, like in the v2 REST API.
+func newSchema(h *Handler) (graphql.Schema, error) {
+	period := graphql.FieldConfigArgument{
+		"from": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String), Description: "First month, YYYY-MM"},
+		"to":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String), Description: "Last month, YYYY-MM"},
+	}
+
+	monthlyCostType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "MonthlyCost",
+		Description: "The total price of subscriptions for a month.",
+		Fields: graphql.Fields{
+			"month": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveMonth},
+			"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: resolveTotal},
+		},
+	})
+
+	var userType, serviceType *graphql.Object
+
+	subscriptionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Subscription",
+		Description: "A subscription of a user to a service.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"serviceName": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveServiceNameOf},
+				"price":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Monthly price", Resolve: resolvePrice},
+				"userId":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveUserID},
+				"startDate":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveStartDate},
+				"endDate":     &graphql.Field{Type: graphql.String, Resolve: resolveEndDate},
+				"user":        &graphql.Field{Type: graphql.NewNonNull(userType), Resolve: resolveSubscriptionUser},
+				"service":     &graphql.Field{Type: graphql.NewNonNull(serviceType), Resolve: resolveSubscriptionService},
+				"cost": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.Int),
+					Description: "Total price for the months of the period",
+					Args:        period,
+					Resolve:     resolveSubscriptionCost,
+				},
+			}
+		}),
+	})
+
+	userType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "User",
+		Description: "A user having subscriptions.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveUserIdentity},
+				"subscriptions": &graphql.Field{
+					Type: listOf(subscriptionType),
+					Args: graphql.FieldConfigArgument{
+						"serviceName": &graphql.ArgumentConfig{Type: graphql.String},
+					},
+					Resolve: h.resolveUserSubscriptions,
+				},
+				"services":     &graphql.Field{Type: listOf(serviceType), Resolve: h.resolveUserServices},
+				"totalCost":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Args: period, Resolve: h.resolveUserTotalCost},
+				"monthlyCosts": &graphql.Field{Type: listOf(monthlyCostType), Args: period, Resolve: h.resolveUserMonthlyCosts},
+			}
+		}),
+	})
+
+	serviceType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Service",
+		Description: "A service users are subscribed to.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"name":            &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveServiceName},
+				"subscriptions":   &graphql.Field{Type: listOf(subscriptionType), Resolve: h.resolveServiceSubscriptions},
+				"users":           &graphql.Field{Type: listOf(userType), Resolve: h.resolveServiceUsers},
+				"subscriberCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: h.resolveServiceSubscriberCount},
+				"totalCost":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Args: period, Resolve: h.resolveServiceTotalCost},
+				"monthlyCosts":    &graphql.Field{Type: listOf(monthlyCostType), Args: period, Resolve: h.resolveServiceMonthlyCosts},
+			}
+		}),
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"subscriptions": &graphql.Field{
+				Type: listOf(subscriptionType),
+				Args: graphql.FieldConfigArgument{
+					"userId":      &graphql.ArgumentConfig{Type: graphql.String},
+					"serviceName": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: h.resolveSubscriptions,
+			},
+			"users": &graphql.Field{Type: listOf(userType), Resolve: h.resolveUsers},
+			"user": &graphql.Field{
+				Type:    userType,
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: h.resolveUser,
+			},
+			"services": &graphql.Field{Type: listOf(serviceType), Resolve: h.resolveServices},
+			"service": &graphql.Field{
+				Type:    serviceType,
+				Args:    graphql.FieldConfigArgument{"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: h.resolveService,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// listOf returns the type of a non-null list of non-null items of the given type.
+func listOf(t graphql.Type) graphql.Type {
+	return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(t)))
+}
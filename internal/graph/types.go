@@ -0,0 +1,124 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/cache"
+)
+
+const (
+	// DefaultMaxComplexity defines the default maximum complexity of a query, see Config.
+	DefaultMaxComplexity = 1000
+
+	// DefaultMaxDepth defines the default maximum nesting of the fields of a query.
+	DefaultMaxDepth = 10
+
+	// listMultiplier is the number of items a list field is assumed to return when estimating the complexity of a query.
+	listMultiplier = 10
+)
+
+// Config defines the GraphQL endpoint settings.
+// The complexity of a query is the number of its fields, with the fields nested in a list counted listMultiplier times;
+// queries exceeding MaxComplexity or nesting fields deeper than MaxDepth are rejected before they are executed.
+type Config struct {
+	MaxComplexity int `env:"GRAPHQL_MAX_COMPLEXITY" env-default:"1000"`
+	MaxDepth      int `env:"GRAPHQL_MAX_DEPTH" env-default:"10"`
+}
+
+// Storage defines the storage operations the resolvers are backed by.
+type Storage interface {
+	ListFilteredSubscriptions(context.Context, string, string) ([]*api.Subscription, error)
+	ListSubscriptionsOfUsers(context.Context, []string) ([]*api.Subscription, error)
+	ListSubscriptionsOfServices(context.Context, []string) ([]*api.Subscription, error)
+	ListUserIDs(context.Context) ([]string, error)
+	ListServiceNames(context.Context) ([]string, error)
+}
+
+// Rollups defines the monthly spend rollup operations the cost resolvers read the periods covered by the rollup from.
+type Rollups interface {
+	GetMonthlySpendCoverage(context.Context) (time.Time, bool, error)
+	ListMonthlySpendOfUsers(context.Context, []string, time.Time, time.Time) (map[string][]api.MonthlyCost, error)
+	ListMonthlySpendOfServices(context.Context, []string, string, time.Time, time.Time) (map[string][]api.MonthlyCost, error)
+}
+
+// Handler serves GraphQL queries over HTTP.
+type Handler struct {
+	schema        graphql.Schema
+	storage       Storage
+	aggregates    *cache.Cache
+	rollups       Rollups
+	logger        *zap.Logger
+	maxComplexity int
+	maxDepth      int
+}
+
+// request is the body of a GraphQL request.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// userNode is the source of the User type; users exist only through their subscriptions.
+type userNode struct {
+	ID string
+}
+
+// serviceNode is the source of the Service type; services exist only through their subscriptions.
+type serviceNode struct {
+	Name string
+}
+
+// loaders batches the loading of the subscriptions and the monthly spend of the users and services of a request.
+// The coverage of the rollup is read once per request, if there is a rollup.
+type loaders struct {
+	userSubscriptions    *loader[string, []*api.Subscription]
+	serviceSubscriptions *loader[string, []*api.Subscription]
+	userSpend            *loader[spendKey, []api.MonthlyCost]
+	serviceSpend         *loader[spendKey, []api.MonthlyCost]
+	coverage             func() (time.Time, error)
+
+	// generation is the generation of the cached aggregates as of the start of the request, before anything
+	// was loaded, so the aggregates computed from the loaded data are not cached after an invalidation.
+	generation uint64
+}
+
+// period is the months from start through end.
+type period struct {
+	start time.Time
+	end   time.Time
+}
+
+// spendKey identifies the monthly spend of a user or a service for a period.
+type spendKey struct {
+	name string
+	period
+}
+
+// aggregateSource is what the aggregates of a user or a service are computed from: its subscriptions,
+// or its monthly spend for the periods covered by the rollup.
+type aggregateSource struct {
+	name          string
+	subscriptions *loader[string, []*api.Subscription]
+	spend         *loader[spendKey, []api.MonthlyCost]
+}
+
+// loadersKey is the context key under which the loaders of a request are stored.
+type loadersKey struct{}
+
+// loader collects the keys requested by the resolvers of the same level of a query and fetches them in a single batch
+// when the first of them is needed. The results are cached for the rest of the request.
+type loader[K comparable, V any] struct {
+	fetch func(context.Context, []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	results map[K]V
+	errs    map[K]error
+}
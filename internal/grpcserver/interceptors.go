@@ -0,0 +1,207 @@
+package grpcserver
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"subscriptions/internal/problem"
+	"subscriptions/internal/ratelimit"
+	"subscriptions/internal/service"
+	"subscriptions/internal/storage/postgresClient"
+	"subscriptions/internal/tenant"
+)
+
+// interceptor rate limits the call by address, authenticates it by the authorization or x-api-key metadata,
+// binds it to the tenant, rate limits it in the group of the method and authorizes it for the action of the method,
+// like the middlewares of the REST API. Methods without an action, such as the health and reflection services, are not guarded.
+func (g *Guard) interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	action, ok := methodActions[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	group, limited := methodGroups[info.FullMethod]
+
+	// Addresses are limited before authentication, so failed authentication attempts are limited too.
+	if limited {
+		if err := g.limit(ctx, ratelimit.GroupAuth, false); err != nil {
+			return nil, err
+		}
+	}
+
+	token, _ := strings.CutPrefix(firstMetadata(ctx, metadataAuthorization), "Bearer ")
+
+	ctx, err := g.Authenticator.Authenticate(ctx, token, firstMetadata(ctx, metadataAPIKey))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	ctx, err = tenant.Resolve(g.Tenant, ctx, firstMetadata(ctx, metadataTenant))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if limited {
+		if err = g.limit(ctx, group, true); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, err = g.Authorizer.Authorize(ctx, action)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return handler(ctx, req)
+}
+
+// limit takes a token of the rate limit group for the caller, sending the ratelimit-* headers like the REST API
+// does if always is set, or else only when the call is rejected. Calls over the limit are rejected with
+// ResourceExhausted and a retry-after header.
+func (g *Guard) limit(ctx context.Context, group string, always bool) error {
+	if g.Limiter == nil {
+		return nil
+	}
+
+	var address string
+	if p, ok := peer.FromContext(ctx); ok {
+		address = p.Addr.String()
+	}
+
+	decision := g.Limiter.Allow(ctx, group, address)
+	if decision == nil || (decision.Allowed && !always) {
+		return nil
+	}
+
+	header := metadata.Pairs(
+		"ratelimit-policy", decision.Policy,
+		"ratelimit-limit", strconv.Itoa(decision.Limit),
+		"ratelimit-remaining", strconv.Itoa(decision.Remaining),
+		"ratelimit-reset", strconv.Itoa(decision.Reset),
+	)
+
+	if !decision.Allowed {
+		header.Set("retry-after", strconv.Itoa(decision.RetryAfter))
+	}
+
+	if err := grpc.SetHeader(ctx, header); err != nil {
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	if !decision.Allowed {
+		return toStatus(problem.New(problem.CodeRateLimited, "rate limit exceeded"))
+	}
+
+	return nil
+}
+
+// observing reports the status code and the duration of every call to the observers.
+func observing(observers []CallObserver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		for _, observer := range observers {
+			observer.ObserveCall(info.FullMethod, status.Code(err).String(), time.Since(start))
+		}
+
+		return resp, err
+	}
+}
+
+// logging logs every call with its duration and status code.
+func logging(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		logger.Info("grpc call",
+			zap.String("method", info.FullMethod),
+			zap.String("code", status.Code(err).String()),
+			zap.Duration("duration", time.Since(start)),
+		)
+
+		return resp, err
+	}
+}
+
+// recoverer turns the panics of the calls into internal errors, so a single call cannot take the server down.
+func recoverer(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				logger.Error("grpc call panicked", zap.String("method", info.FullMethod), zap.Any("panic", p), zap.Stack("stack"))
+				err = status.Error(codes.Internal, "internal server error")
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// readYourWrites lets the storage route the reads of a call to the primary once it has written, and those of the next
+// calls of the client while they carry the x-read-your-writes metadata sent back in the header, if read-your-writes
+// is enabled.
+func readYourWrites(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx = postgresClient.WithReadYourWrites(ctx, firstMetadata(ctx, metadataReadYourWrites), func(token string, _ time.Time) {
+		// The header can only fail to be set once the response is sent, when the pin does not matter anymore.
+		_ = grpc.SetHeader(ctx, metadata.Pairs(metadataReadYourWrites, token))
+	})
+
+	return handler(ctx, req)
+}
+
+// toStatus maps err to the status reported to the client, classifying it like the REST API does.
+// Internal errors are reported without details.
+func toStatus(err error) error {
+	e := service.Classify(err)
+
+	code, ok := statusCodes[e.Code]
+	if !ok || code == codes.Internal {
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	st := status.New(code, e.Detail)
+	if len(e.Fields) == 0 {
+		return st.Err()
+	}
+
+	// The rejected fields are sent like the errors of the problem details of the REST API.
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Detail})
+	}
+
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// firstMetadata returns the first value of the incoming metadata key, or an empty string.
+func firstMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// invalid reports a call rejected by the validation of its request.
+func invalid(detail string) *problem.Error {
+	return problem.New(problem.CodeInvalidRequest, detail)
+}
@@ -0,0 +1,86 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	pb "subscriptions/internal/grpcserver/subscriptionsv1"
+	"subscriptions/internal/service"
+)
+
+// New creates and returns a new Server instance serving the subscriptions to the callers admitted by guard.
+// Every call is traced and reported to the observers.
+func New(config *Config, subscriptions *service.Subscriptions, guard *Guard, logger *zap.Logger, observers ...CallObserver) *Server {
+	logger = logger.Named("grpc")
+
+	s := &Server{
+		server: grpc.NewServer(
+			grpc.StatsHandler(otelgrpc.NewServerHandler()),
+			grpc.ChainUnaryInterceptor(
+				observing(observers),
+				recoverer(logger),
+				logging(logger),
+				guard.interceptor,
+				readYourWrites,
+			),
+		),
+		health: health.NewServer(),
+		addr:   fmt.Sprintf("%s:%d", config.Host, config.Port),
+		logger: logger,
+	}
+
+	pb.RegisterSubscriptionServiceServer(s.server, &subscriptionService{subscriptions: subscriptions, logger: logger})
+	healthpb.RegisterHealthServer(s.server, s.health)
+	reflection.Register(s.server)
+
+	return s
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// Start starts serving in the background. The process exits if the server cannot be started.
+func (s *Server) Start() {
+	go func() {
+		s.logger.Info("starting grpc server", zap.String("addr", s.addr))
+
+		listener, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			s.logger.Fatal("cannot start grpc server", zap.Error(err))
+		}
+
+		if err := s.server.Serve(listener); err != nil {
+			s.logger.Fatal("cannot start grpc server", zap.Error(err))
+		}
+	}()
+}
+
+// Shutdown gracefully stops the server, waiting for the active calls until ctx is done and cancelling them afterwards.
+// The health service reports NOT_SERVING from the start of the shutdown.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
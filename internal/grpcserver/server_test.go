@@ -0,0 +1,461 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+	pb "subscriptions/internal/grpcserver/subscriptionsv1"
+	"subscriptions/internal/ratelimit"
+	"subscriptions/internal/rbac"
+	"subscriptions/internal/service"
+	"subscriptions/internal/storage/postgresClient"
+	"subscriptions/internal/tenant"
+)
+
+// API keys known to fakeKeyStorage, bound to the tenant acme.
+const (
+	readKey  = "sk_read"
+	writeKey = "sk_write"
+)
+
+// fakeKeyStorage authenticates the API keys readKey and writeKey.
+type fakeKeyStorage struct{}
+
+func (fakeKeyStorage) AuthenticateAPIKey(_ context.Context, hash string) (*api.APIKey, error) {
+	switch hash {
+	case auth.HashKey(readKey):
+		return &api.APIKey{ID: 1, TenantID: "acme", Name: "reader", Scopes: []string{auth.ScopeSubscriptionsRead}}, nil
+	case auth.HashKey(writeKey):
+		return &api.APIKey{ID: 2, TenantID: "acme", Name: "writer", Scopes: []string{auth.ScopeSubscriptionsRead, auth.ScopeSubscriptionsWrite}}, nil
+	}
+
+	return nil, postgresClient.ErrAPIKeyNotFound
+}
+
+// fakePostgresClient keeps the subscriptions in the order of their ids.
+type fakePostgresClient struct {
+	records []*postgresClient.SubscriptionRecord
+}
+
+func (f *fakePostgresClient) SaveSubscription(_ context.Context, subscription *api.Subscription) (int, error) {
+	id := len(f.records) + 1
+	f.records = append(f.records, &postgresClient.SubscriptionRecord{ID: id, Subscription: *subscription})
+	return id, nil
+}
+
+func (f *fakePostgresClient) DeleteSubscription(context.Context, int) error {
+	return nil
+}
+
+func (f *fakePostgresClient) GetSubscription(_ context.Context, id int) (*api.Subscription, error) {
+	for _, record := range f.records {
+		if record.ID == id {
+			return &record.Subscription, nil
+		}
+	}
+
+	return nil, postgresClient.ErrSubscriptionNotFound
+}
+
+func (f *fakePostgresClient) ListSubscriptions(context.Context) ([]*api.Subscription, error) {
+	return nil, nil
+}
+
+func (f *fakePostgresClient) UpdateSubscription(context.Context, int, *api.Subscription) error {
+	return nil
+}
+
+func (f *fakePostgresClient) ListFilteredSubscriptions(context.Context, string, string) ([]*api.Subscription, error) {
+	return nil, nil
+}
+
+func (f *fakePostgresClient) ListSubscriptionsAfter(_ context.Context, afterID int, _ string, limit int) ([]*postgresClient.SubscriptionRecord, error) {
+	var res []*postgresClient.SubscriptionRecord
+	for _, record := range f.records {
+		if record.ID > afterID && len(res) < limit {
+			res = append(res, record)
+		}
+	}
+
+	return res, nil
+}
+
+func (f *fakePostgresClient) Close() {}
+
+// newGuard returns a Guard authenticating the keys of fakeKeyStorage and limiting the calls with limits.
+func newGuard(t *testing.T, limits *ratelimit.Config) *Guard {
+	t.Helper()
+
+	logger := zap.NewNop()
+
+	authenticator, err := auth.New(&auth.Config{Enabled: true}, fakeKeyStorage{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorizer, err := rbac.New(&rbac.Config{}, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limiter, err := ratelimit.New(limits, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Guard{Authenticator: authenticator, Authorizer: authorizer, Limiter: limiter, Tenant: &tenant.Config{}}
+}
+
+// newTestClient serves the subscriptions of storage guarded by guard over an in-memory connection.
+func newTestClient(t *testing.T, storage *fakePostgresClient, guard *Guard) *grpc.ClientConn {
+	t.Helper()
+
+	s := New(&Config{}, service.NewSubscriptions(storage), guard, zap.NewNop())
+
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+	t.Cleanup(s.server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+// withMetadata returns a context sending the given metadata key/value pairs.
+func withMetadata(kv ...string) context.Context {
+	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(kv...))
+}
+
+func TestGuard(t *testing.T) {
+	storage := &fakePostgresClient{records: []*postgresClient.SubscriptionRecord{
+		{ID: 1, Subscription: api.Subscription{ServiceName: "Netflix", Price: 400, UserID: "alice", StartDate: "07-2025"}},
+	}}
+	client := pb.NewSubscriptionServiceClient(newTestClient(t, storage, newGuard(t, &ratelimit.Config{Enabled: true})))
+
+	get := func(ctx context.Context) error {
+		_, err := client.GetSubscription(ctx, &pb.GetSubscriptionRequest{Id: 1})
+		return err
+	}
+
+	create := func(ctx context.Context) error {
+		_, err := client.CreateSubscription(ctx, &pb.CreateSubscriptionRequest{Subscription: &pb.Subscription{
+			ServiceName: "Spotify", Price: 200, UserId: "alice", StartDate: "2025-07",
+		}})
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		call     func(context.Context) error
+		wantCode codes.Code
+	}{
+		{
+			name:     "missing credentials",
+			ctx:      context.Background(),
+			call:     get,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "unknown api key",
+			ctx:      withMetadata(metadataAPIKey, "sk_unknown"),
+			call:     get,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "invalid bearer token",
+			ctx:      withMetadata(metadataAuthorization, "Bearer token"),
+			call:     get,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "other tenant",
+			ctx:      withMetadata(metadataAPIKey, readKey, metadataTenant, "globex"),
+			call:     get,
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "invalid tenant",
+			ctx:      withMetadata(metadataAPIKey, readKey, metadataTenant, "acme corp"),
+			call:     get,
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "missing scope",
+			ctx:      withMetadata(metadataAPIKey, readKey),
+			call:     create,
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "read",
+			ctx:      withMetadata(metadataAPIKey, readKey, metadataTenant, "acme"),
+			call:     get,
+			wantCode: codes.OK,
+		},
+		{
+			name:     "write",
+			ctx:      withMetadata(metadataAPIKey, writeKey),
+			call:     create,
+			wantCode: codes.OK,
+		},
+		{
+			name: "missing subscription",
+			ctx:  withMetadata(metadataAPIKey, readKey),
+			call: func(ctx context.Context) error {
+				_, err := client.GetSubscription(ctx, &pb.GetSubscriptionRequest{Id: 42})
+				return err
+			},
+			wantCode: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call(tt.ctx)); got != tt.wantCode {
+				t.Errorf("code = %s, want %s", got, tt.wantCode)
+			}
+		})
+	}
+
+	// Methods without an action, such as the health service, are not guarded.
+	if _, err := healthpb.NewHealthClient(newTestClient(t, storage, newGuard(t, &ratelimit.Config{}))).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("health Check() error = %v, want no credentials required", err)
+	}
+}
+
+func TestGuardRateLimits(t *testing.T) {
+	storage := &fakePostgresClient{records: []*postgresClient.SubscriptionRecord{
+		{ID: 1, Subscription: api.Subscription{ServiceName: "Netflix", Price: 400, UserID: "alice", StartDate: "07-2025"}},
+	}}
+	guard := newGuard(t, &ratelimit.Config{Enabled: true, DefaultRequests: 1, AuthRequests: 3})
+	client := pb.NewSubscriptionServiceClient(newTestClient(t, storage, guard))
+
+	steps := []struct {
+		name           string
+		ctx            context.Context
+		wantCode       codes.Code
+		wantRetryAfter bool
+	}{
+		{
+			name:     "first call of the key",
+			ctx:      withMetadata(metadataAPIKey, writeKey),
+			wantCode: codes.OK,
+		},
+		{
+			name:           "key over the limit of its group",
+			ctx:            withMetadata(metadataAPIKey, writeKey),
+			wantCode:       codes.ResourceExhausted,
+			wantRetryAfter: true,
+		},
+		{
+			name:     "other key has its own limit",
+			ctx:      withMetadata(metadataAPIKey, readKey),
+			wantCode: codes.OK,
+		},
+		{
+			// The address is limited before authentication, so the call is rejected without checking the credentials.
+			name:           "address over the limit of authentication",
+			ctx:            context.Background(),
+			wantCode:       codes.ResourceExhausted,
+			wantRetryAfter: true,
+		},
+	}
+
+	for _, step := range steps {
+		var header metadata.MD
+		_, err := client.GetSubscription(step.ctx, &pb.GetSubscriptionRequest{Id: 1}, grpc.Header(&header))
+
+		if got := status.Code(err); got != step.wantCode {
+			t.Fatalf("%s: code = %s, want %s", step.name, got, step.wantCode)
+		}
+
+		if got := len(header.Get("retry-after")) > 0; got != step.wantRetryAfter {
+			t.Errorf("%s: retry-after = %v, want sent = %v", step.name, header.Get("retry-after"), step.wantRetryAfter)
+		}
+
+		if step.wantCode == codes.OK && len(header.Get("ratelimit-remaining")) == 0 {
+			t.Errorf("%s: headers = %v, want the ratelimit-* headers", step.name, header)
+		}
+	}
+}
+
+func TestListSubscriptions(t *testing.T) {
+	storage := &fakePostgresClient{}
+	for _, start := range []string{"01-2025", "02-2025", "2025-03", "04-2025", "05-2025"} {
+		_, _ = storage.SaveSubscription(context.Background(), &api.Subscription{ServiceName: "Netflix", Price: 400, UserID: "alice", StartDate: start})
+	}
+
+	client := pb.NewSubscriptionServiceClient(newTestClient(t, storage, newGuard(t, &ratelimit.Config{})))
+	ctx := withMetadata(metadataAPIKey, readKey)
+
+	// The legacy subscription with a malformed start date is skipped without failing its page.
+	var pages [][]string
+	token := ""
+	for {
+		res, err := client.ListSubscriptions(ctx, &pb.ListSubscriptionsRequest{PageSize: 2, PageToken: token})
+		if err != nil {
+			t.Fatalf("ListSubscriptions() error = %v", err)
+		}
+
+		var page []string
+		for _, subscription := range res.GetSubscriptions() {
+			page = append(page, subscription.GetStartDate())
+		}
+		pages = append(pages, page)
+
+		token = res.GetNextPageToken()
+		if token == "" {
+			break
+		}
+
+		if len(pages) > 3 {
+			t.Fatalf("pages = %v, want the last one to have no next page token", pages)
+		}
+	}
+
+	want := [][]string{{"2025-01", "2025-02"}, {"2025-04"}, {"2025-05"}}
+	if !slices.EqualFunc(pages, want, slices.Equal) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+
+	invalid := []struct {
+		name string
+		req  *pb.ListSubscriptionsRequest
+	}{
+		{name: "negative page size", req: &pb.ListSubscriptionsRequest{PageSize: -1}},
+		{name: "malformed page token", req: &pb.ListSubscriptionsRequest{PageToken: "!"}},
+	}
+
+	for _, tt := range invalid {
+		if _, err := client.ListSubscriptions(ctx, tt.req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: ListSubscriptions() error = %v, want %s", tt.name, err, codes.InvalidArgument)
+		}
+	}
+}
+
+func TestPageToken(t *testing.T) {
+	encoded := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    int
+		wantErr bool
+	}{
+		{name: "first page", token: "", want: 0},
+		{name: "encoded id", token: encodePageToken(42), want: 42},
+		{name: "not base64", token: "!!", wantErr: true},
+		{name: "padded base64", token: base64.URLEncoding.EncodeToString([]byte("4")), wantErr: true},
+		{name: "not an id", token: encoded("abc"), wantErr: true},
+		{name: "negative id", token: encoded("-1"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodePageToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodePageToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("decodePageToken() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtoConversion(t *testing.T) {
+	tests := []struct {
+		name    string
+		proto   *pb.Subscription
+		want    *api.Subscription
+		wantErr bool
+	}{
+		{
+			name:  "without end date",
+			proto: &pb.Subscription{ServiceName: "Netflix", Price: 400, UserId: "alice", StartDate: "2025-07"},
+			want:  &api.Subscription{ServiceName: "Netflix", Price: 400, UserID: "alice", StartDate: "07-2025"},
+		},
+		{
+			name:  "with end date",
+			proto: &pb.Subscription{ServiceName: "Netflix", Price: 400, UserId: "alice", StartDate: "2025-07", EndDate: "2026-01"},
+			want:  &api.Subscription{ServiceName: "Netflix", Price: 400, UserID: "alice", StartDate: "07-2025", EndDate: "01-2026"},
+		},
+		{
+			name:    "missing subscription",
+			wantErr: true,
+		},
+		{
+			name:    "start date in the storage layout",
+			proto:   &pb.Subscription{ServiceName: "Netflix", Price: 400, UserId: "alice", StartDate: "07-2025"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid end date",
+			proto:   &pb.Subscription{ServiceName: "Netflix", Price: 400, UserId: "alice", StartDate: "2025-07", EndDate: "2026-13"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fromProto(tt.proto)
+			if tt.wantErr {
+				if status.Code(toStatus(err)) != codes.InvalidArgument {
+					t.Fatalf("fromProto() error = %v, want %s", err, codes.InvalidArgument)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("fromProto() error = %v", err)
+			}
+
+			if *got != *tt.want {
+				t.Fatalf("fromProto() = %+v, want %+v", got, tt.want)
+			}
+
+			// The stored subscription maps back to the subscription of the request.
+			back, err := toProto(got)
+			if err != nil {
+				t.Fatalf("toProto() error = %v", err)
+			}
+
+			if back.GetServiceName() != tt.proto.GetServiceName() || back.GetPrice() != tt.proto.GetPrice() || back.GetUserId() != tt.proto.GetUserId() ||
+				back.GetStartDate() != tt.proto.GetStartDate() || back.GetEndDate() != tt.proto.GetEndDate() {
+				t.Errorf("toProto() = %v, want %v", back, tt.proto)
+			}
+		})
+	}
+
+	if _, err := toProto(&api.Subscription{ServiceName: "Netflix", StartDate: "2025-07"}); err == nil {
+		t.Error("toProto() of a malformed stored start date error = nil, want an error")
+	}
+}
@@ -0,0 +1,210 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	pb "subscriptions/internal/grpcserver/subscriptionsv1"
+)
+
+func (s *subscriptionService) CreateSubscription(ctx context.Context, req *pb.CreateSubscriptionRequest) (*pb.CreateSubscriptionResponse, error) {
+	subscription, err := fromProto(req.GetSubscription())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	id, err := s.subscriptions.Create(ctx, subscription)
+	if err != nil {
+		s.logger.Error("CreateSubscription:", zap.Error(err))
+		return nil, toStatus(err)
+	}
+
+	return &pb.CreateSubscriptionResponse{Id: int64(id)}, nil
+}
+
+func (s *subscriptionService) GetSubscription(ctx context.Context, req *pb.GetSubscriptionRequest) (*pb.GetSubscriptionResponse, error) {
+	subscription, err := s.subscriptions.Get(ctx, int(req.GetId()))
+	if err != nil {
+		s.logger.Error("GetSubscription:", zap.Int64("id", req.GetId()), zap.Error(err))
+		return nil, toStatus(err)
+	}
+
+	res, err := toProto(subscription)
+	if err != nil {
+		s.logger.Error("GetSubscription: cannot encode subscription", zap.Int64("id", req.GetId()), zap.Error(err))
+		return nil, toStatus(err)
+	}
+
+	return &pb.GetSubscriptionResponse{Subscription: res}, nil
+}
+
+// ListSubscriptions pages through the subscriptions in the order of their ids. The page token carries the id
+// of the last subscription of the previous page, so the pages neither repeat nor skip subscriptions when others
+// are created or deleted in between.
+func (s *subscriptionService) ListSubscriptions(ctx context.Context, req *pb.ListSubscriptionsRequest) (*pb.ListSubscriptionsResponse, error) {
+	size := int(req.GetPageSize())
+	switch {
+	case size < 0:
+		return nil, toStatus(invalid("invalid 'page_size', must not be negative"))
+	case size == 0:
+		size = DefaultPageSize
+	case size > MaxPageSize:
+		size = MaxPageSize
+	}
+
+	after, err := decodePageToken(req.GetPageToken())
+	if err != nil {
+		return nil, toStatus(invalid("invalid 'page_token'"))
+	}
+
+	// One more subscription than fits in the page tells whether there is a next page.
+	records, err := s.subscriptions.ListAfter(ctx, after, size+1)
+	if err != nil {
+		s.logger.Error("ListSubscriptions:", zap.Error(err))
+		return nil, toStatus(err)
+	}
+
+	// Legacy subscriptions with malformed dates are skipped like the REST API does, without failing the page.
+	res := &pb.ListSubscriptionsResponse{}
+	for _, record := range records[:min(size, len(records))] {
+		item, err := toProto(&record.Subscription)
+		if err != nil {
+			s.logger.Warn("ListSubscriptions: skipping subscription which cannot be encoded", zap.Int("id", record.ID), zap.Error(err))
+			continue
+		}
+
+		res.Subscriptions = append(res.Subscriptions, item)
+	}
+
+	if len(records) > size {
+		res.NextPageToken = encodePageToken(records[size-1].ID)
+	}
+
+	return res, nil
+}
+
+func (s *subscriptionService) UpdateSubscription(ctx context.Context, req *pb.UpdateSubscriptionRequest) (*pb.UpdateSubscriptionResponse, error) {
+	subscription, err := fromProto(req.GetSubscription())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := s.subscriptions.Update(ctx, int(req.GetId()), subscription); err != nil {
+		s.logger.Error("UpdateSubscription:", zap.Int64("id", req.GetId()), zap.Error(err))
+		return nil, toStatus(err)
+	}
+
+	return &pb.UpdateSubscriptionResponse{}, nil
+}
+
+func (s *subscriptionService) DeleteSubscription(ctx context.Context, req *pb.DeleteSubscriptionRequest) (*pb.DeleteSubscriptionResponse, error) {
+	if err := s.subscriptions.Delete(ctx, int(req.GetId())); err != nil {
+		s.logger.Error("DeleteSubscription:", zap.Int64("id", req.GetId()), zap.Error(err))
+		return nil, toStatus(err)
+	}
+
+	return &pb.DeleteSubscriptionResponse{}, nil
+}
+
+func (s *subscriptionService) TotalPrice(ctx context.Context, req *pb.TotalPriceRequest) (*pb.TotalPriceResponse, error) {
+	start, err := api.ParseMonth(api.ISOMonthLayout, req.GetStartDate())
+	if err != nil {
+		return nil, toStatus(invalid("invalid 'start_date' date format. Use YYYY-MM"))
+	}
+
+	end, err := api.ParseMonth(api.ISOMonthLayout, req.GetEndDate())
+	if err != nil {
+		return nil, toStatus(invalid("invalid 'end_date' date format. Use YYYY-MM"))
+	}
+
+	total, err := s.subscriptions.TotalPrice(ctx, req.GetUserId(), req.GetServiceName(), start.Time, end.Time)
+	if err != nil {
+		s.logger.Error("TotalPrice: failed to calculate total price", zap.Error(err))
+		return nil, toStatus(err)
+	}
+
+	return &pb.TotalPriceResponse{Total: int64(total)}, nil
+}
+
+// fromProto maps a subscription of a request to the stored model.
+func fromProto(subscription *pb.Subscription) (*api.Subscription, error) {
+	if subscription == nil {
+		return nil, invalid("'subscription' is required")
+	}
+
+	res := &api.Subscription{
+		ServiceName: subscription.GetServiceName(),
+		Price:       int(subscription.GetPrice()),
+		UserID:      subscription.GetUserId(),
+	}
+
+	start, err := api.ParseMonth(api.ISOMonthLayout, subscription.GetStartDate())
+	if err != nil {
+		return nil, invalid("invalid 'start_date' date format. Use YYYY-MM")
+	}
+	res.StartDate = start.Format(api.MonthLayout)
+
+	if subscription.GetEndDate() != "" {
+		end, err := api.ParseMonth(api.ISOMonthLayout, subscription.GetEndDate())
+		if err != nil {
+			return nil, invalid("invalid 'end_date' date format. Use YYYY-MM")
+		}
+		res.EndDate = end.Format(api.MonthLayout)
+	}
+
+	return res, nil
+}
+
+// toProto maps a stored subscription to the one of a response.
+func toProto(subscription *api.Subscription) (*pb.Subscription, error) {
+	res := &pb.Subscription{
+		ServiceName: subscription.ServiceName,
+		Price:       int64(subscription.Price),
+		UserId:      subscription.UserID,
+	}
+
+	start, err := api.ParseMonth(api.MonthLayout, subscription.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	res.StartDate = start.Format(api.ISOMonthLayout)
+
+	if subscription.EndDate != "" {
+		end, err := api.ParseMonth(api.MonthLayout, subscription.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		res.EndDate = end.Format(api.ISOMonthLayout)
+	}
+
+	return res, nil
+}
+
+// encodePageToken returns the page token of the page following the subscription with the given id.
+func encodePageToken(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+// decodePageToken returns the id the page of the page token follows; an empty token is the first page.
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.Atoi(string(raw))
+	if err != nil || id < 0 {
+		return 0, errors.New("invalid id")
+	}
+
+	return id, nil
+}
@@ -0,0 +1,108 @@
+package grpcserver
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+
+	"subscriptions/internal/auth"
+	pb "subscriptions/internal/grpcserver/subscriptionsv1"
+	"subscriptions/internal/problem"
+	"subscriptions/internal/ratelimit"
+	"subscriptions/internal/rbac"
+	"subscriptions/internal/service"
+	"subscriptions/internal/tenant"
+)
+
+const (
+	// DefaultPageSize defines the number of subscriptions listed when the request does not set the page size.
+	DefaultPageSize = 50
+
+	// MaxPageSize defines the maximum number of subscriptions listed in a page.
+	MaxPageSize = 500
+
+	// Metadata keys carrying the credentials and the tenant of a call, like the headers of the REST API.
+	metadataAuthorization = "authorization"
+	metadataAPIKey        = "x-api-key"
+	metadataTenant        = "x-tenant-id"
+
+	// metadataReadYourWrites carries the pin of a client which has written to the primary, see readYourWrites.
+	metadataReadYourWrites = "x-read-your-writes"
+)
+
+// Config defines the gRPC server settings. The server is started only if it is enabled.
+type Config struct {
+	Enabled bool   `env:"GRPC_ENABLED"`
+	Host    string `env:"GRPC_HOST" env-default:"0.0.0.0"`
+	Port    int    `env:"GRPC_PORT" env-default:"9090"`
+}
+
+// Server is the gRPC server of the service. It serves the SubscriptionService together with
+// the standard health and reflection services.
+type Server struct {
+	server *grpc.Server
+	health *health.Server
+	addr   string
+	logger *zap.Logger
+}
+
+// Guard authenticates, binds to a tenant, rate limits and authorizes the calls the same way the REST API does.
+type Guard struct {
+	Authenticator *auth.Authenticator
+	Authorizer    *rbac.Authorizer
+	Limiter       *ratelimit.Limiter
+	Tenant        *tenant.Config
+}
+
+// CallObserver receives the status code and the processing time of every call.
+type CallObserver interface {
+	ObserveCall(method string, code string, duration time.Duration)
+}
+
+// subscriptionService implements pb.SubscriptionServiceServer on top of service.Subscriptions.
+type subscriptionService struct {
+	pb.UnimplementedSubscriptionServiceServer
+
+	subscriptions *service.Subscriptions
+	logger        *zap.Logger
+}
+
+// methodActions lists the action every method of the SubscriptionService is authorized for.
+var methodActions = map[string]string{
+	pb.SubscriptionService_CreateSubscription_FullMethodName: rbac.ActionSubscriptionsCreate,
+	pb.SubscriptionService_GetSubscription_FullMethodName:    rbac.ActionSubscriptionsRead,
+	pb.SubscriptionService_ListSubscriptions_FullMethodName:  rbac.ActionSubscriptionsRead,
+	pb.SubscriptionService_UpdateSubscription_FullMethodName: rbac.ActionSubscriptionsUpdate,
+	pb.SubscriptionService_DeleteSubscription_FullMethodName: rbac.ActionSubscriptionsDelete,
+	pb.SubscriptionService_TotalPrice_FullMethodName:         rbac.ActionReportsRead,
+}
+
+// methodGroups lists the rate limit group of every method of the SubscriptionService, like the routes of the REST API.
+var methodGroups = map[string]string{
+	pb.SubscriptionService_CreateSubscription_FullMethodName: ratelimit.GroupDefault,
+	pb.SubscriptionService_GetSubscription_FullMethodName:    ratelimit.GroupDefault,
+	pb.SubscriptionService_ListSubscriptions_FullMethodName:  ratelimit.GroupDefault,
+	pb.SubscriptionService_UpdateSubscription_FullMethodName: ratelimit.GroupDefault,
+	pb.SubscriptionService_DeleteSubscription_FullMethodName: ratelimit.GroupDefault,
+	pb.SubscriptionService_TotalPrice_FullMethodName:         ratelimit.GroupReports,
+}
+
+// statusCodes maps the problem codes shared with the REST API to the gRPC status codes.
+var statusCodes = map[problem.Code]codes.Code{
+	problem.CodeInvalidRequest:      codes.InvalidArgument,
+	problem.CodeInvalidBody:         codes.InvalidArgument,
+	problem.CodeBodyTooLarge:        codes.ResourceExhausted,
+	problem.CodeUnauthenticated:     codes.Unauthenticated,
+	problem.CodeForbidden:           codes.PermissionDenied,
+	problem.CodeNotFound:            codes.NotFound,
+	problem.CodeConflict:            codes.Aborted,
+	problem.CodeValidationFailed:    codes.InvalidArgument,
+	problem.CodeConstraintViolation: codes.FailedPrecondition,
+	problem.CodeRateLimited:         codes.ResourceExhausted,
+	problem.CodeTimeout:             codes.DeadlineExceeded,
+	problem.CodeCanceled:            codes.Canceled,
+	problem.CodeInternal:            codes.Internal,
+}
@@ -0,0 +1,117 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Checker serves the liveness and readiness endpoints.
+type Checker struct {
+	storage       Storage
+	logger        *zap.Logger
+	checkTimeout  time.Duration
+	shutdownDelay time.Duration
+	shuttingDown  atomic.Bool
+}
+
+// New creates and returns a new Checker instance, applying defaults to the unset config fields.
+func New(config *Config, storage Storage, logger *zap.Logger) *Checker {
+	if config.CheckTimeout == 0 {
+		config.CheckTimeout = DefaultCheckTimeout
+	}
+
+	return &Checker{
+		storage:       storage,
+		logger:        logger,
+		checkTimeout:  config.CheckTimeout,
+		shutdownDelay: config.ShutdownDelay,
+	}
+}
+
+// Shutdown makes the readiness checks fail from now on and waits for the shutdown delay,
+// so load balancers stop routing requests before the server stops accepting them.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+
+	time.Sleep(c.shutdownDelay)
+}
+
+// LivenessHandler reports that the process is alive and able to serve requests.
+//
+// @Summary Liveness probe
+// @Description Reports that the process is alive.
+// @Tags health
+// @Produce json
+// @Success 200 {object} report
+// @Router /healthz [get]
+func (c *Checker) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.write(w, http.StatusOK, &report{Status: statusOK})
+	}
+}
+
+// ReadinessHandler reports whether the service can take traffic: the database is reachable,
+// its schema is up to date and the service is not shutting down.
+//
+// @Summary Readiness probe
+// @Description Reports whether the service can take traffic: the database is reachable, its schema is up to date
+// @Description and the service is not shutting down.
+// @Tags health
+// @Produce json
+// @Success 200 {object} report
+// @Failure 503 {object} report
+// @Router /readyz [get]
+func (c *Checker) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), c.checkTimeout)
+		defer cancel()
+
+		res := &report{Status: statusOK, Checks: map[string]string{}}
+
+		check := func(name string, err error) {
+			if err != nil {
+				c.logger.Warn("Checker: readiness check failed", zap.String("check", name), zap.Error(err))
+				res.Checks[name] = statusFailing
+				res.Status = statusFailing
+				return
+			}
+
+			res.Checks[name] = statusOK
+		}
+
+		if c.shuttingDown.Load() {
+			res.Checks["shutdown"] = statusFailing
+			res.Status = statusFailing
+		} else {
+			res.Checks["shutdown"] = statusOK
+		}
+
+		check("postgres", c.storage.Ping(ctx))
+		if res.Checks["postgres"] == statusOK {
+			check("migrations", c.storage.CheckMigrations(ctx))
+		}
+
+		statusCode := http.StatusOK
+		if res.Status != statusOK {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		c.write(w, statusCode, res)
+	}
+}
+
+// write sends the report to the caller.
+func (c *Checker) write(w http.ResponseWriter, statusCode int, res *report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		c.logger.Warn("Checker: cannot send report to caller", zap.Error(err))
+	}
+}
@@ -0,0 +1,38 @@
+package health
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultCheckTimeout defines how long the readiness checks may take altogether.
+	DefaultCheckTimeout = 2 * time.Second
+
+	// statusOK marks a passing check and a healthy service.
+	statusOK = "ok"
+
+	// statusFailing marks a failing check and a service which is not ready.
+	statusFailing = "failing"
+)
+
+// Config defines the health check settings.
+// ShutdownDelay is how long the service keeps serving after it starts failing readiness on shutdown,
+// giving load balancers the time to stop routing requests to it. It defaults to 5s, longer than the usual period
+// of the readiness probes; 0 stops the service right away, which suits deployments without a load balancer.
+type Config struct {
+	CheckTimeout  time.Duration `env:"HEALTH_CHECK_TIMEOUT" env-default:"2s"`
+	ShutdownDelay time.Duration `env:"HEALTH_SHUTDOWN_DELAY" env-default:"5s"`
+}
+
+// Storage defines the storage operations required by the Checker.
+type Storage interface {
+	Ping(context.Context) error
+	CheckMigrations(context.Context) error
+}
+
+// report is the response of the health endpoints, listing the outcome of every check.
+type report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
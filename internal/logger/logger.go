@@ -1,19 +1,21 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 // Config defines the logger settings, (field "output" is optional and intended for tests).
 type Config struct {
-	Env    string `yaml:"ENV" env:"LOGGER"`
+	Env    string `yaml:"ENV" env:"LOGGER" env-default:"prod"`
 	output io.Writer
 }
 
@@ -82,19 +84,27 @@ func New(cfg *Config) (*zap.Logger, error) {
 	}
 }
 
+// RequestObserver receives the response status and the processing time of every request seen by MiddlewareLogger.
+type RequestObserver interface {
+	ObserveRequest(r *http.Request, status int, duration time.Duration)
+}
+
 // MiddlewareLogger creates an HTTP middleware that logs details about incoming HTTP request.
 // In "dev" mode, it logs basic information: HTTP method, path and response status.
 // In prod mode (and others), it logs extended details such as:
 // method, path, remote address, user agent, request id, processing time and response status.
-func MiddlewareLogger(logger *zap.Logger, cfg *Config) func(http.Handler) http.Handler {
+// Requests which are part of a trace are logged with its trace and span ids.
+// The status and processing time of every request are also reported to the given observers.
+func MiddlewareLogger(logger *zap.Logger, cfg *Config, observers ...RequestObserver) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			entry := logger.With()
 			start := time.Now()
+			traced := logger.With(TraceFields(r.Context())...)
 
 			switch cfg.Env {
 			case "dev":
-				entry = logger.With(
+				entry = traced.With(
 					zap.String("method", r.Method),
 					zap.String("path", r.URL.Path),
 				)
@@ -102,7 +112,7 @@ func MiddlewareLogger(logger *zap.Logger, cfg *Config) func(http.Handler) http.H
 				entry.Info("new request")
 
 			default:
-				entry = logger.With(
+				entry = traced.With(
 					zap.String("method", r.Method),
 					zap.String("path", r.URL.Path),
 					zap.String("remote_addr", r.RemoteAddr),
@@ -116,6 +126,12 @@ func MiddlewareLogger(logger *zap.Logger, cfg *Config) func(http.Handler) http.H
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			defer func() {
+				duration := time.Since(start)
+
+				for _, observer := range observers {
+					observer.ObserveRequest(r, ww.Status(), duration)
+				}
+
 				switch cfg.Env {
 				case "dev":
 					entry.Info(
@@ -127,7 +143,7 @@ func MiddlewareLogger(logger *zap.Logger, cfg *Config) func(http.Handler) http.H
 					entry.Info(
 						"request completed",
 						zap.Int("status", ww.Status()),
-						zap.Duration("duration", time.Since(start)),
+						zap.Duration("duration", duration),
 					)
 				}
 			}()
@@ -138,3 +154,17 @@ func MiddlewareLogger(logger *zap.Logger, cfg *Config) func(http.Handler) http.H
 		return http.HandlerFunc(fn)
 	}
 }
+
+// TraceFields returns the trace and span ids of the span in ctx as log fields,
+// or no fields if ctx carries no valid span.
+func TraceFields(ctx context.Context) []zap.Field {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String("trace_id", spanContext.TraceID().String()),
+		zap.String("span_id", spanContext.SpanID().String()),
+	}
+}
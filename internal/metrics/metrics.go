@@ -0,0 +1,266 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+	"subscriptions/internal/tenant"
+)
+
+// Metrics collects the HTTP, gRPC, database and business metrics of the service and exposes them to Prometheus.
+type Metrics struct {
+	registry         *prometheus.Registry
+	requests         *prometheus.CounterVec
+	requestTimes     *prometheus.HistogramVec
+	calls            *prometheus.CounterVec
+	callTimes        *prometheus.HistogramVec
+	queryTimes       *prometheus.HistogramVec
+	cacheLookups     *prometheus.CounterVec
+	cacheInvalidated prometheus.Counter
+	storage          Storage
+	logger           *zap.Logger
+	activeCount      *prometheus.Desc
+	activeMonthly    *prometheus.Desc
+}
+
+// New creates and returns a new Metrics instance registering all collectors.
+func New(storage Storage, logger *zap.Logger) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of handled HTTP requests.",
+		}, []string{"method", "route", "status"}),
+		requestTimes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of handled HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "Number of handled gRPC calls.",
+		}, []string{"method", "code"}),
+		callTimes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of handled gRPC calls.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		queryTimes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "postgres",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of database operations.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "status"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "lookups_total",
+			Help:      "Number of lookups of cached aggregates, by result (hit or miss).",
+		}, []string{"aggregate", "result"}),
+		cacheInvalidated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "invalidated_total",
+			Help:      "Number of cached aggregates invalidated by changes of subscriptions.",
+		}),
+		storage: storage,
+		logger:  logger,
+		activeCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "active"),
+			"Number of subscriptions active in the current month.",
+			[]string{"tenant_id"}, nil,
+		),
+		activeMonthly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "active_monthly_price"),
+			"Total monthly price of the subscriptions active in the current month.",
+			[]string{"tenant_id"}, nil,
+		),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestTimes,
+		m.calls,
+		m.callTimes,
+		m.queryTimes,
+		m.cacheLookups,
+		m.cacheInvalidated,
+		poolCollector(storage),
+		m,
+	)
+
+	return m
+}
+
+// Handler returns the handler serving the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a handled request, labelled by its chi route pattern rather than its path.
+func (m *Metrics) ObserveRequest(r *http.Request, status int, duration time.Duration) {
+	route := unmatchedRoute
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	labels := prometheus.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+
+	m.requests.With(labels).Inc()
+	m.requestTimes.With(labels).Observe(duration.Seconds())
+}
+
+// ObserveCall records a handled gRPC call, labelled by its full method name and status code.
+func (m *Metrics) ObserveCall(method string, code string, duration time.Duration) {
+	m.calls.WithLabelValues(method, code).Inc()
+	m.callTimes.WithLabelValues(method, code).Observe(duration.Seconds())
+}
+
+// ObserveQuery records a database operation, labelling operations which did not find their record as successful.
+func (m *Metrics) ObserveQuery(operation string, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil && !isNotFound(err) {
+		status = "error"
+	}
+
+	m.queryTimes.WithLabelValues(operation, status).Observe(duration.Seconds())
+}
+
+// ObserveCacheLookup records a lookup of a cached aggregate.
+func (m *Metrics) ObserveCacheLookup(aggregate string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	m.cacheLookups.WithLabelValues(aggregate, result).Inc()
+}
+
+// ObserveCacheInvalidation records the number of cached aggregates removed by an invalidation.
+func (m *Metrics) ObserveCacheInvalidation(removed int) {
+	m.cacheInvalidated.Add(float64(removed))
+}
+
+// Describe implements prometheus.Collector for the business metrics.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.activeCount
+	ch <- m.activeMonthly
+}
+
+// Collect implements prometheus.Collector, loading the business metrics of all tenants on every scrape.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(tenant.WithTenant(context.Background(), tenant.All), collectTimeout)
+	defer cancel()
+
+	stats, err := m.storage.ListActiveSubscriptionStats(ctx)
+	if err != nil {
+		m.logger.Error("Metrics: cannot load active subscriptions", zap.Error(err))
+		return
+	}
+
+	for _, s := range stats {
+		ch <- prometheus.MustNewConstMetric(m.activeCount, prometheus.GaugeValue, float64(s.Count), s.TenantID)
+		ch <- prometheus.MustNewConstMetric(m.activeMonthly, prometheus.GaugeValue, float64(s.MonthlyPrice), s.TenantID)
+	}
+}
+
+// poolCollector returns the collector of the connection pool statistics.
+func poolCollector(storage Storage) prometheus.Collector {
+	gauge := func(name, help string, value func() float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "postgres_pool",
+			Name:      name,
+			Help:      help,
+		}, value)
+	}
+
+	counter := func(name, help string, value func() float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "postgres_pool",
+			Name:      name,
+			Help:      help,
+		}, value)
+	}
+
+	return &collectorList{
+		gauge("acquired_connections", "Number of connections currently in use.", func() float64 {
+			return float64(storage.Stat().AcquiredConns())
+		}),
+		gauge("idle_connections", "Number of idle connections.", func() float64 {
+			return float64(storage.Stat().IdleConns())
+		}),
+		gauge("total_connections", "Total number of open connections.", func() float64 {
+			return float64(storage.Stat().TotalConns())
+		}),
+		gauge("max_connections", "Maximum size of the pool.", func() float64 {
+			return float64(storage.Stat().MaxConns())
+		}),
+		counter("acquires_total", "Number of connections acquired from the pool.", func() float64 {
+			return float64(storage.Stat().AcquireCount())
+		}),
+		counter("empty_acquires_total", "Number of acquires which had to wait for a connection.", func() float64 {
+			return float64(storage.Stat().EmptyAcquireCount())
+		}),
+		counter("acquire_wait_seconds_total", "Total time spent waiting for connections.", func() float64 {
+			return storage.Stat().EmptyAcquireWaitTime().Seconds()
+		}),
+		counter("acquire_duration_seconds_total", "Total duration of acquiring connections.", func() float64 {
+			return storage.Stat().AcquireDuration().Seconds()
+		}),
+	}
+}
+
+// collectorList combines several collectors into one.
+type collectorList []prometheus.Collector
+
+// Describe implements prometheus.Collector.
+func (l *collectorList) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range *l {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (l *collectorList) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range *l {
+		c.Collect(ch)
+	}
+}
+
+// isNotFound reports whether err only means that the requested record does not exist.
+func isNotFound(err error) bool {
+	return errors.Is(err, pgx.ErrNoRows) ||
+		errors.Is(err, postgresClient.ErrSubscriptionNotFound) ||
+		errors.Is(err, postgresClient.ErrWebhookNotFound) ||
+		errors.Is(err, postgresClient.ErrEventNotFound) ||
+		errors.Is(err, postgresClient.ErrAPIKeyNotFound) ||
+		errors.Is(err, postgresClient.ErrRoleAssignmentNotFound)
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+const (
+	// namespace prefixes the names of all metrics of the service.
+	namespace = "subscriptions"
+
+	// unmatchedRoute labels the requests which did not match any route, so unknown paths do not create new series.
+	unmatchedRoute = "unmatched"
+
+	// collectTimeout limits the time the business metrics may take to load on every scrape.
+	collectTimeout = 5 * time.Second
+)
+
+// Config defines the metrics settings.
+type Config struct {
+	Enabled bool `env:"METRICS_ENABLED"`
+}
+
+// Storage defines the storage operations required by the Metrics.
+type Storage interface {
+	Stat() *pgxpool.Stat
+	ListActiveSubscriptionStats(context.Context) ([]*postgresClient.ActiveSubscriptionStats, error)
+}
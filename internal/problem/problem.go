@@ -0,0 +1,100 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// internalDetail is the detail of every internal error sent to clients.
+const internalDetail = "internal server error"
+
+// New creates and returns a new Error with the given code and detail.
+func New(code Code, detail string) *Error {
+	return &Error{Code: code, Detail: detail}
+}
+
+// Wrap creates and returns a new Error with the given code and detail, caused by err.
+func Wrap(code Code, detail string, err error) *Error {
+	return &Error{Code: code, Detail: detail, Err: err}
+}
+
+// Invalid creates and returns a new Error with CodeValidationFailed, listing the rejected fields in its detail.
+func Invalid(subject string, fields []FieldError) *Error {
+	details := make([]string, 0, len(fields))
+	for _, field := range fields {
+		details = append(details, field.Field+": "+field.Detail)
+	}
+
+	return &Error{Code: CodeValidationFailed, Detail: "invalid " + subject + ": " + strings.Join(details, "; "), Fields: fields}
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Detail + ": " + e.Err.Error()
+	}
+
+	return string(e.Code) + ": " + e.Detail
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status the error is reported with.
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+// Write writes err to the client of the request as problem details. Errors which are not an Error
+// are reported as internal ones, without their message.
+func Write(logger *zap.Logger, w http.ResponseWriter, r *http.Request, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = Wrap(CodeInternal, internalDetail, err)
+	}
+
+	status := e.Status()
+
+	detail := e.Detail
+	if e.Code == CodeInternal {
+		detail = internalDetail
+	}
+
+	p := &Problem{
+		Type:      typePrefix + string(e.Code),
+		Title:     title(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      e.Code,
+		Errors:    e.Fields,
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logger.Warn("Write: cannot send problem to caller", zap.Error(err))
+	}
+}
+
+// title returns the title of problems reported with the given status.
+func title(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+
+	return http.StatusText(status)
+}
@@ -0,0 +1,104 @@
+package problem
+
+import (
+	"net/http"
+)
+
+// ContentType is the media type of problem details responses, as defined by RFC 7807.
+const ContentType = "application/problem+json"
+
+// StatusClientClosedRequest is the non-standard status of requests abandoned by their client.
+const StatusClientClosedRequest = 499
+
+// typePrefix is prepended to the code to build the type URI of a problem.
+const typePrefix = "/problems/"
+
+// Code is a stable machine-readable error code. Clients may rely on the codes, unlike the details.
+type Code string
+
+const (
+	// CodeInvalidRequest marks a request with invalid parameters.
+	CodeInvalidRequest Code = "invalid_request"
+
+	// CodeInvalidBody marks a request body which cannot be decoded.
+	CodeInvalidBody Code = "invalid_body"
+
+	// CodeBodyTooLarge marks a request body exceeding the limit of the route.
+	CodeBodyTooLarge Code = "body_too_large"
+
+	// CodeUnauthenticated marks a request with missing or invalid credentials.
+	CodeUnauthenticated Code = "unauthenticated"
+
+	// CodeForbidden marks a request the caller is not allowed to make.
+	CodeForbidden Code = "forbidden"
+
+	// CodeNotFound marks a request for a resource which does not exist or is not visible to the caller.
+	CodeNotFound Code = "not_found"
+
+	// CodeConflict marks a request conflicting with an existing resource or a concurrent change.
+	CodeConflict Code = "conflict"
+
+	// CodeValidationFailed marks a well-formed request whose content is not acceptable.
+	CodeValidationFailed Code = "validation_failed"
+
+	// CodeConstraintViolation marks a request rejected by a constraint of the storage.
+	CodeConstraintViolation Code = "constraint_violation"
+
+	// CodeRateLimited marks a request over the rate limit of the caller.
+	CodeRateLimited Code = "rate_limited"
+
+	// CodeTimeout marks a request which could not be completed in time.
+	CodeTimeout Code = "timeout"
+
+	// CodeCanceled marks a request abandoned by its client before it was completed.
+	CodeCanceled Code = "canceled"
+
+	// CodeInternal marks an unexpected failure; its details are never sent to clients.
+	CodeInternal Code = "internal"
+)
+
+// statuses maps every code to the HTTP status it is reported with.
+var statuses = map[Code]int{
+	CodeInvalidRequest:      http.StatusBadRequest,
+	CodeInvalidBody:         http.StatusBadRequest,
+	CodeBodyTooLarge:        http.StatusRequestEntityTooLarge,
+	CodeUnauthenticated:     http.StatusUnauthorized,
+	CodeForbidden:           http.StatusForbidden,
+	CodeNotFound:            http.StatusNotFound,
+	CodeConflict:            http.StatusConflict,
+	CodeValidationFailed:    http.StatusUnprocessableEntity,
+	CodeConstraintViolation: http.StatusUnprocessableEntity,
+	CodeRateLimited:         http.StatusTooManyRequests,
+	CodeTimeout:             http.StatusGatewayTimeout,
+	CodeCanceled:            StatusClientClosedRequest,
+	CodeInternal:            http.StatusInternalServerError,
+}
+
+// Error is an error reported to clients with a stable code. Detail is sent to clients and must not carry
+// internal information; the underlying error Err is only logged.
+// Fields lists the rejected fields of a request which failed validation.
+type Error struct {
+	Code   Code
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+// FieldError describes why the value of a field of the request was rejected.
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// Problem is the problem details body of an error response (RFC 7807), extended with the error code,
+// the rejected fields and the id of the request.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/auth"
+	"subscriptions/internal/problem"
+)
+
+// Limiter limits the rate of requests of every client with token buckets, separately per route group.
+type Limiter struct {
+	store   store
+	logger  *zap.Logger
+	enabled bool
+	limits  map[string]Limit
+}
+
+// New creates and returns a new Limiter instance, applying defaults to the unset config fields.
+// The storage is only used by the Postgres backend.
+func New(config *Config, storage Storage, logger *zap.Logger) (*Limiter, error) {
+	if config.Backend == "" {
+		config.Backend = BackendMemory
+	}
+
+	if config.DefaultRequests == 0 {
+		config.DefaultRequests = DefaultRequests
+	}
+
+	if config.DefaultPeriod == 0 {
+		config.DefaultPeriod = DefaultPeriod
+	}
+
+	if config.ReportsRequests == 0 {
+		config.ReportsRequests = DefaultReportsRequests
+	}
+
+	if config.ReportsPeriod == 0 {
+		config.ReportsPeriod = DefaultPeriod
+	}
+
+	if config.AuthRequests == 0 {
+		config.AuthRequests = DefaultAuthRequests
+	}
+
+	if config.AuthPeriod == 0 {
+		config.AuthPeriod = DefaultPeriod
+	}
+
+	limits := map[string]Limit{
+		GroupDefault: {Requests: config.DefaultRequests, Period: config.DefaultPeriod},
+		GroupReports: {Requests: config.ReportsRequests, Period: config.ReportsPeriod},
+		GroupAuth:    {Requests: config.AuthRequests, Period: config.AuthPeriod},
+	}
+
+	limiter := &Limiter{
+		logger:  logger,
+		enabled: config.Enabled,
+		limits:  limits,
+	}
+
+	switch config.Backend {
+	case BackendMemory:
+		limiter.store = newMemoryStore()
+	case BackendPostgres:
+		limiter.store = &postgresStore{
+			storage: storage,
+			logger:  logger,
+			maxAge:  max(config.DefaultPeriod, config.ReportsPeriod, config.AuthPeriod),
+		}
+	default:
+		return nil, fmt.Errorf("New: unknown rate limit backend: %s", config.Backend)
+	}
+
+	return limiter, nil
+}
+
+// Limit returns a middleware limiting the requests of the given route group, see Allow. It must run after
+// middleware.RealIP, and after authentication for every group but GroupAuth. Every response carries the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers; requests over the limit are rejected with 429 and a Retry-After header.
+func (l *Limiter) Limit(group string) func(http.Handler) http.Handler {
+	l.limit(group)
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			decision := l.Allow(r.Context(), group, r.RemoteAddr)
+			if decision == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", decision.Policy)
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(decision.Reset))
+
+			if !decision.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(decision.RetryAfter))
+				problem.Write(l.logger, w, r, problem.New(problem.CodeRateLimited, "rate limit exceeded"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Allow takes a token of the given group for the client of ctx, identified by its API key or user,
+// falling back to its address. GroupAuth is always taken by the address. It returns nil if rate limiting is disabled or the limit cannot be checked,
+// so the call is let through.
+func (l *Limiter) Allow(ctx context.Context, group string, address string) *Decision {
+	limit := l.limit(group)
+
+	if !l.enabled {
+		return nil
+	}
+
+	client := addressKey(address)
+	if group != GroupAuth {
+		client = clientKey(ctx, address)
+	}
+
+	tokens, allowed, err := l.store.take(ctx, group+":"+client, limit)
+	if err != nil {
+		l.logger.Error("Limiter: cannot check rate limit", zap.String("client", client), zap.Error(err))
+		return nil
+	}
+
+	decision := &Decision{
+		Policy:    fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Period.Seconds())),
+		Limit:     limit.Requests,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     int(math.Ceil((float64(limit.Requests) - tokens) / limit.rate())),
+		Allowed:   allowed,
+	}
+
+	if !allowed {
+		decision.RetryAfter = int(math.Ceil((1 - tokens) / limit.rate()))
+		l.logger.Warn("Limiter: rate limit exceeded", zap.String("group", group), zap.String("client", client))
+	}
+
+	return decision
+}
+
+// limit returns the limit of the given route group. It panics if the group is unknown.
+func (l *Limiter) limit(group string) Limit {
+	limit, ok := l.limits[group]
+	if !ok {
+		panic(fmt.Sprintf("ratelimit: unknown route group: %s", group))
+	}
+
+	return limit
+}
+
+// clientKey identifies the client of ctx by its API key, its user or its address, in that order.
+func clientKey(ctx context.Context, address string) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		switch {
+		case principal.KeyID != 0:
+			return "key:" + strconv.Itoa(principal.KeyID)
+		case principal.UserID != "":
+			return "user:" + principal.TenantID + "/" + principal.UserID
+		default:
+			return "principal:" + principal.Name
+		}
+	}
+
+	return addressKey(address)
+}
+
+// addressKey identifies the client by the host of its address.
+func addressKey(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	return "ip:" + host
+}
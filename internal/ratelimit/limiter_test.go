@@ -0,0 +1,304 @@
+package ratelimit
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/auth"
+)
+
+// fakeClock is the time of a memoryStore, moved forward by the tests.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// newTestLimiter returns an enabled Limiter with the memory backend and the clock driving its store.
+func newTestLimiter(t *testing.T, config *Config) (*Limiter, *fakeClock) {
+	t.Helper()
+
+	config.Enabled = true
+
+	l, err := New(config, nil, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	s := l.store.(*memoryStore)
+	s.now = func() time.Time { return clock.now }
+	s.lastSweep = clock.now
+
+	return l, clock
+}
+
+func TestAllow(t *testing.T) {
+	// Two requests per two seconds refill the bucket with a token per second.
+	l, clock := newTestLimiter(t, &Config{DefaultRequests: 2, DefaultPeriod: 2 * time.Second})
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		want    Decision
+	}{
+		{
+			name: "full bucket",
+			want: Decision{Policy: "2;w=2", Limit: 2, Remaining: 1, Reset: 1, Allowed: true},
+		},
+		{
+			name: "last token",
+			want: Decision{Policy: "2;w=2", Limit: 2, Remaining: 0, Reset: 2, Allowed: true},
+		},
+		{
+			name: "empty bucket",
+			want: Decision{Policy: "2;w=2", Limit: 2, Remaining: 0, Reset: 2, RetryAfter: 1},
+		},
+		{
+			name:    "half a token",
+			advance: 500 * time.Millisecond,
+			want:    Decision{Policy: "2;w=2", Limit: 2, Remaining: 0, Reset: 2, RetryAfter: 1},
+		},
+		{
+			name:    "refilled token",
+			advance: 500 * time.Millisecond,
+			want:    Decision{Policy: "2;w=2", Limit: 2, Remaining: 0, Reset: 2, Allowed: true},
+		},
+		{
+			name:    "a token and a half",
+			advance: 1500 * time.Millisecond,
+			want:    Decision{Policy: "2;w=2", Limit: 2, Remaining: 0, Reset: 2, Allowed: true},
+		},
+		{
+			name:    "refill is capped at the limit",
+			advance: time.Hour,
+			want:    Decision{Policy: "2;w=2", Limit: 2, Remaining: 1, Reset: 1, Allowed: true},
+		},
+	}
+
+	for _, step := range steps {
+		clock.advance(step.advance)
+
+		got := l.Allow(context.Background(), GroupDefault, "10.0.0.1:4321")
+		if got == nil || *got != step.want {
+			t.Fatalf("%s: Allow() = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestAllowDisabled(t *testing.T) {
+	l, err := New(&Config{}, nil, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := l.Allow(context.Background(), GroupReports, "10.0.0.1:4321"); got != nil {
+		t.Errorf("Allow() = %+v, want nil while disabled", got)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	l, clock := newTestLimiter(t, &Config{DefaultRequests: 1, DefaultPeriod: 4 * time.Second})
+
+	calls := 0
+	h := l.Limit(GroupDefault)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{Name: "alice", KeyID: 1})
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{Name: "bob", KeyID: 2})
+
+	steps := []struct {
+		name    string
+		ctx     context.Context
+		advance time.Duration
+		status  int
+		headers map[string]string
+	}{
+		{
+			name:    "allowed",
+			ctx:     alice,
+			status:  http.StatusOK,
+			headers: map[string]string{"RateLimit-Policy": "1;w=4", "RateLimit-Limit": "1", "RateLimit-Remaining": "0", "RateLimit-Reset": "4"},
+		},
+		{
+			name:    "rejected",
+			ctx:     alice,
+			advance: time.Second,
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "3", "Retry-After": "3"},
+		},
+		{
+			name:    "another client of the same address",
+			ctx:     bob,
+			status:  http.StatusOK,
+			headers: map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "4"},
+		},
+		{
+			name:    "allowed again after the refill",
+			ctx:     alice,
+			advance: 3 * time.Second,
+			status:  http.StatusOK,
+			headers: map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "4"},
+		},
+	}
+
+	for _, step := range steps {
+		clock.advance(step.advance)
+
+		before := calls
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/subscriptions", nil).WithContext(step.ctx)
+		req.RemoteAddr = "10.0.0.1:4321"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != step.status {
+			t.Fatalf("%s: status = %d, want %d", step.name, rec.Code, step.status)
+		}
+
+		for name, want := range step.headers {
+			if got := rec.Header().Get(name); got != want {
+				t.Errorf("%s: %s = %q, want %q", step.name, name, got, want)
+			}
+		}
+
+		if served := calls > before; served != (step.status == http.StatusOK) {
+			t.Errorf("%s: handler served = %v with status %d", step.name, served, step.status)
+		}
+	}
+}
+
+func TestLimitAuth(t *testing.T) {
+	l, _ := newTestLimiter(t, &Config{AuthRequests: 1})
+
+	h := l.Limit(GroupAuth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// The principals are ignored, so the clients of an address share its bucket.
+	var codes []int
+	for _, principal := range []*auth.Principal{nil, {Name: "alice", KeyID: 1}} {
+		ctx := context.Background()
+		if principal != nil {
+			ctx = auth.WithPrincipal(ctx, principal)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/subscriptions", nil).WithContext(ctx)
+		req.RemoteAddr = "10.0.0.1:4321"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		codes = append(codes, rec.Code)
+	}
+
+	if want := []int{http.StatusOK, http.StatusTooManyRequests}; !slices.Equal(codes, want) {
+		t.Errorf("statuses = %v, want %v", codes, want)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	s := newMemoryStore()
+	s.now = func() time.Time { return clock.now }
+	s.lastSweep = clock.now
+
+	minute := Limit{Requests: 10, Period: time.Minute}
+	hour := Limit{Requests: 10, Period: time.Hour}
+
+	take := func(key string, limit Limit) {
+		t.Helper()
+
+		if _, _, err := s.take(context.Background(), key, limit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := func() []string {
+		return slices.Sorted(maps.Keys(s.buckets))
+	}
+
+	take("idle", minute)
+	take("slow", hour)
+
+	// Idle buckets are kept until the next sweep.
+	clock.advance(sweepInterval - time.Second)
+	take("active", minute)
+
+	if got, want := keys(), []string{"active", "idle", "slow"}; !slices.Equal(got, want) {
+		t.Fatalf("buckets before the sweep = %v, want %v", got, want)
+	}
+
+	// The sweep removes the buckets idle for their whole period, which are full again.
+	clock.advance(time.Second)
+	take("new", minute)
+
+	if got, want := keys(), []string{"active", "new", "slow"}; !slices.Equal(got, want) {
+		t.Errorf("buckets after the sweep = %v, want %v", got, want)
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		address   string
+		want      string
+	}{
+		{
+			name:      "API key",
+			principal: &auth.Principal{Name: "ci", KeyID: 7, TenantID: "acme", UserID: "alice"},
+			address:   "10.0.0.1:4321",
+			want:      "key:7",
+		},
+		{
+			name:      "user",
+			principal: &auth.Principal{Name: "alice", TenantID: "acme", UserID: "alice"},
+			address:   "10.0.0.1:4321",
+			want:      "user:acme/alice",
+		},
+		{
+			name:      "principal",
+			principal: &auth.Principal{Name: "admin"},
+			address:   "10.0.0.1:4321",
+			want:      "principal:admin",
+		},
+		{
+			name:    "address",
+			address: "10.0.0.1:4321",
+			want:    "ip:10.0.0.1",
+		},
+		{
+			name:    "IPv6 address",
+			address: "[2001:db8::1]:4321",
+			want:    "ip:2001:db8::1",
+		},
+		{
+			name:    "address without a port",
+			address: "10.0.0.1",
+			want:    "ip:10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+
+			if got := clientKey(ctx, tt.address); got != tt.want {
+				t.Errorf("clientKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// memoryStore keeps the token buckets in memory, so every replica limits the clients on its own.
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// bucket is a token bucket as of the time it was last updated at.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// newMemoryStore creates and returns an empty memoryStore.
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (s *memoryStore) take(_ context.Context, key string, limit Limit) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), updated: now, limit: limit}
+		s.buckets[key] = b
+	}
+
+	b.tokens = min(float64(limit.Requests), b.tokens+now.Sub(b.updated).Seconds()*limit.rate())
+	b.updated = now
+
+	if b.tokens < 1 {
+		return b.tokens, false, nil
+	}
+
+	b.tokens--
+
+	return b.tokens, true, nil
+}
+
+// sweep removes the buckets which have been refilled completely, as they are no different from new ones.
+func (s *memoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.limit.Period {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastSweep = now
+}
+
+// postgresStore keeps the token buckets in Postgres, so the limits hold across replicas.
+type postgresStore struct {
+	storage Storage
+	logger  *zap.Logger
+	maxAge  time.Duration
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func (s *postgresStore) take(ctx context.Context, key string, limit Limit) (float64, bool, error) {
+	s.sweep()
+
+	return s.storage.TakeRateLimitToken(ctx, key, float64(limit.Requests), limit.rate())
+}
+
+// sweep removes the buckets of inactive clients in background, at most once per sweepInterval.
+func (s *postgresStore) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastSweep) < sweepInterval {
+		return
+	}
+
+	s.lastSweep = time.Now()
+
+	go func() {
+		if err := s.storage.DeleteRateLimitsBefore(context.Background(), time.Now().Add(-s.maxAge)); err != nil {
+			s.logger.Warn("Limiter: cannot remove inactive buckets", zap.Error(err))
+		}
+	}()
+}
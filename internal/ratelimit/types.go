@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// GroupDefault is the route group of the regular API calls.
+	GroupDefault = "default"
+
+	// GroupReports is the route group of the aggregate reports, which are more expensive to serve.
+	GroupReports = "reports"
+
+	// GroupAuth is the route group of all calls before authentication. It is limited by the address of the client,
+	// so failed authentication attempts are limited too.
+	GroupAuth = "auth"
+
+	// BackendMemory keeps the buckets in the memory of every replica.
+	BackendMemory = "memory"
+
+	// BackendPostgres keeps the buckets in Postgres, so the limits are shared by all replicas.
+	BackendPostgres = "postgres"
+
+	// DefaultRequests defines how many requests of the default group a client may send per period.
+	DefaultRequests = 600
+
+	// DefaultReportsRequests defines how many reports a client may request per period.
+	DefaultReportsRequests = 30
+
+	// DefaultAuthRequests defines how many calls an address may send per period, authenticated or not.
+	DefaultAuthRequests = 1200
+
+	// DefaultPeriod defines the period the limits are defined for.
+	DefaultPeriod = time.Minute
+
+	// sweepInterval defines how often the buckets of inactive clients are removed.
+	sweepInterval = 10 * time.Minute
+)
+
+// Config defines the rate limiting settings.
+// Every client gets a token bucket per route group holding up to Requests tokens,
+// which is refilled at Requests tokens per Period.
+type Config struct {
+	Enabled         bool          `env:"RATELIMIT_ENABLED"`
+	Backend         string        `env:"RATELIMIT_BACKEND" env-default:"memory"`
+	DefaultRequests int           `env:"RATELIMIT_DEFAULT_REQUESTS" env-default:"600"`
+	DefaultPeriod   time.Duration `env:"RATELIMIT_DEFAULT_PERIOD" env-default:"1m"`
+	ReportsRequests int           `env:"RATELIMIT_REPORTS_REQUESTS" env-default:"30"`
+	ReportsPeriod   time.Duration `env:"RATELIMIT_REPORTS_PERIOD" env-default:"1m"`
+	AuthRequests    int           `env:"RATELIMIT_AUTH_REQUESTS" env-default:"1200"`
+	AuthPeriod      time.Duration `env:"RATELIMIT_AUTH_PERIOD" env-default:"1m"`
+}
+
+// Limit is the size of a token bucket and the period it is refilled in.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// rate returns the number of tokens the bucket is refilled with per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Decision is the outcome of taking a token of a client: its limit, the tokens remaining, the seconds until its bucket
+// is full again and, if no token was taken, the seconds until one is available.
+type Decision struct {
+	Policy     string
+	Limit      int
+	Remaining  int
+	Reset      int
+	RetryAfter int
+	Allowed    bool
+}
+
+// Storage defines the storage operations required by the Postgres backend.
+type Storage interface {
+	TakeRateLimitToken(context.Context, string, float64, float64) (float64, bool, error)
+	DeleteRateLimitsBefore(context.Context, time.Time) error
+}
+
+// store takes tokens from the buckets, returning the tokens left and whether a token was taken.
+type store interface {
+	take(ctx context.Context, key string, limit Limit) (float64, bool, error)
+}
@@ -0,0 +1,191 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+	"subscriptions/internal/problem"
+)
+
+// Authorizer checks whether the caller of a request may perform the action of the route.
+// Users are authorized by the roles listed in their token together with the roles assigned in storage,
+// API keys are authorized by their scopes.
+type Authorizer struct {
+	storage     Storage
+	logger      *zap.Logger
+	defaultRole string
+}
+
+// New creates and returns a new Authorizer instance, applying the default role if not set.
+func New(config *Config, storage Storage, logger *zap.Logger) (*Authorizer, error) {
+	if config.DefaultRole == "" {
+		config.DefaultRole = DefaultRole
+	}
+
+	if _, ok := FindRole(config.DefaultRole); !ok {
+		return nil, fmt.Errorf("New: unknown default role: %s", config.DefaultRole)
+	}
+
+	return &Authorizer{
+		storage:     storage,
+		logger:      logger,
+		defaultRole: config.DefaultRole,
+	}, nil
+}
+
+// Require creates a middleware rejecting with 403 the requests whose caller may not perform the action, see Authorize.
+// It must be used after auth.Authenticator.Middleware.
+func (a *Authorizer) Require(action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx, err := a.Authorize(r.Context(), action)
+			if err != nil {
+				problem.Write(a.logger, w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Authorize checks whether the caller may perform the action and returns a copy of ctx restricted to the caller's
+// own data unless one of its roles grants the action for all users. The errors are *problem.Error values.
+// Callers without a Principal, which only happens when authentication is disabled, are allowed.
+func (a *Authorizer) Authorize(ctx context.Context, action string) (context.Context, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+
+	if principal.UserID == "" {
+		if !principal.HasScope(actionScopes[action]) {
+			return nil, problem.New(problem.CodeForbidden, fmt.Sprintf("missing scope: %s", actionScopes[action]))
+		}
+
+		return auth.WithUserScope(ctx, ""), nil
+	}
+
+	roles, err := a.roles(ctx, principal)
+	if err != nil {
+		a.logger.Error("Authorizer: cannot load roles", zap.String("subject", principal.UserID), zap.Error(err))
+		return nil, problem.Wrap(problem.CodeInternal, "cannot load roles", err)
+	}
+
+	allowed, allUsers := Decide(roles, action)
+	if !allowed {
+		return nil, problem.New(problem.CodeForbidden, fmt.Sprintf("action is not permitted: %s", action))
+	}
+
+	scope := principal.UserID
+	if allUsers {
+		scope = ""
+	}
+
+	return auth.WithUserScope(ctx, scope), nil
+}
+
+// GrantableScopes returns the API key scopes the caller may grant to the keys it creates: the scopes of an API key,
+// or, for users, the scopes whose actions their roles permit on the data of all users, as keys are not restricted
+// to a user. Callers without a Principal, which only happens when authentication is disabled, may grant every scope.
+// The errors are *problem.Error values.
+func (a *Authorizer) GrantableScopes(ctx context.Context) ([]string, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return auth.Scopes, nil
+	}
+
+	if principal.UserID == "" {
+		return principal.Scopes, nil
+	}
+
+	roles, err := a.roles(ctx, principal)
+	if err != nil {
+		a.logger.Error("Authorizer: cannot load roles", zap.String("subject", principal.UserID), zap.Error(err))
+		return nil, problem.Wrap(problem.CodeInternal, "cannot load roles", err)
+	}
+
+	var res []string
+	for _, scope := range auth.Scopes {
+		if grantsScope(roles, scope) {
+			res = append(res, scope)
+		}
+	}
+
+	return res, nil
+}
+
+// grantsScope reports whether the roles permit every action of the scope on the data of all users.
+func grantsScope(roles []string, scope string) bool {
+	for action, actionScope := range actionScopes {
+		if actionScope != scope {
+			continue
+		}
+
+		if allowed, allUsers := Decide(roles, action); !allowed || !allUsers {
+			return false
+		}
+	}
+
+	return true
+}
+
+// roles returns the roles of the user from its token and from storage, or the default role if there are none.
+func (a *Authorizer) roles(ctx context.Context, principal *auth.Principal) ([]string, error) {
+	assignments, err := a.storage.ListRoleAssignments(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := slices.Clone(principal.Roles)
+	for _, assignment := range assignments {
+		roles = append(roles, assignment.Role)
+	}
+
+	if len(roles) == 0 {
+		roles = []string{a.defaultRole}
+	}
+
+	return roles, nil
+}
+
+// Decide reports whether any of the roles permits the action and whether it permits it for all users.
+// Unknown roles grant nothing.
+func Decide(roles []string, action string) (bool, bool) {
+	allowed, allUsers := false, false
+
+	for _, name := range roles {
+		role, ok := FindRole(name)
+		if !ok {
+			continue
+		}
+
+		for _, permission := range role.Permissions {
+			if permission.Action == action {
+				allowed = true
+				allUsers = allUsers || permission.AllUsers
+			}
+		}
+	}
+
+	return allowed, allUsers
+}
+
+// FindRole returns the role with the given name.
+func FindRole(name string) (api.Role, bool) {
+	for _, role := range Roles {
+		if role.Name == name {
+			return role, true
+		}
+	}
+
+	return api.Role{}, false
+}
@@ -0,0 +1,341 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+	"subscriptions/internal/problem"
+)
+
+// actions lists every action routes are authorized for.
+var actions = []string{
+	ActionSubscriptionsRead,
+	ActionSubscriptionsCreate,
+	ActionSubscriptionsUpdate,
+	ActionSubscriptionsDelete,
+	ActionReportsRead,
+	ActionWebhooksManage,
+	ActionKeysManage,
+	ActionRolesManage,
+}
+
+// fakeStorage returns the role assignments of the subjects, or err.
+type fakeStorage struct {
+	assignments map[string][]string
+	err         error
+}
+
+func (f *fakeStorage) ListRoleAssignments(_ context.Context, subject string) ([]*api.RoleAssignment, error) {
+	var res []*api.RoleAssignment
+	for _, role := range f.assignments[subject] {
+		res = append(res, &api.RoleAssignment{Subject: subject, Role: role})
+	}
+
+	return res, f.err
+}
+
+func TestDecide(t *testing.T) {
+	// grants maps every role to the actions it permits, and whether it permits them for all users.
+	grants := map[string]map[string]bool{
+		RoleAdmin: {
+			ActionSubscriptionsRead:   true,
+			ActionSubscriptionsCreate: true,
+			ActionSubscriptionsUpdate: true,
+			ActionSubscriptionsDelete: true,
+			ActionReportsRead:         true,
+			ActionWebhooksManage:      true,
+			ActionKeysManage:          true,
+			ActionRolesManage:         true,
+		},
+		RoleOwner: {
+			ActionSubscriptionsRead:   false,
+			ActionSubscriptionsCreate: false,
+			ActionSubscriptionsUpdate: false,
+			ActionSubscriptionsDelete: false,
+			ActionReportsRead:         false,
+		},
+		RoleFinanceAuditor: {
+			ActionSubscriptionsRead: true,
+			ActionReportsRead:       true,
+		},
+		RoleSupport: {
+			ActionSubscriptionsRead:   true,
+			ActionSubscriptionsUpdate: true,
+		},
+		"unknown": {},
+	}
+
+	for role, granted := range grants {
+		for _, action := range actions {
+			t.Run(role+"/"+action, func(t *testing.T) {
+				wantAllUsers, wantAllowed := granted[action]
+
+				allowed, allUsers := Decide([]string{role}, action)
+				if allowed != wantAllowed || allUsers != wantAllUsers {
+					t.Errorf("Decide() = %t, %t, want %t, %t", allowed, allUsers, wantAllowed, wantAllUsers)
+				}
+			})
+		}
+	}
+}
+
+func TestDecideRoles(t *testing.T) {
+	tests := []struct {
+		name     string
+		roles    []string
+		action   string
+		allowed  bool
+		allUsers bool
+	}{
+		{
+			name:   "no roles",
+			action: ActionSubscriptionsRead,
+		},
+		{
+			name:     "own and all users",
+			roles:    []string{RoleOwner, RoleFinanceAuditor},
+			action:   ActionSubscriptionsRead,
+			allowed:  true,
+			allUsers: true,
+		},
+		{
+			name:    "own only",
+			roles:   []string{RoleOwner, RoleFinanceAuditor},
+			action:  ActionSubscriptionsCreate,
+			allowed: true,
+		},
+		{
+			name:    "unknown role next to a known one",
+			roles:   []string{"root", RoleOwner},
+			action:  ActionSubscriptionsDelete,
+			allowed: true,
+		},
+		{
+			name:   "not granted by any role",
+			roles:  []string{RoleOwner, RoleSupport},
+			action: ActionKeysManage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, allUsers := Decide(tt.roles, tt.action)
+			if allowed != tt.allowed || allUsers != tt.allUsers {
+				t.Errorf("Decide() = %t, %t, want %t, %t", allowed, allUsers, tt.allowed, tt.allUsers)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	storage := &fakeStorage{assignments: map[string][]string{
+		"auditor": {RoleFinanceAuditor},
+		"support": {RoleSupport},
+	}}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		storage   *fakeStorage
+		action    string
+		code      problem.Code
+		scope     string
+	}{
+		{
+			name:   "no principal",
+			action: ActionKeysManage,
+		},
+		{
+			name:      "key with the scope",
+			principal: &auth.Principal{Name: "ci", Scopes: []string{auth.ScopeSubscriptionsWrite}},
+			action:    ActionSubscriptionsDelete,
+		},
+		{
+			name:      "key without the scope",
+			principal: &auth.Principal{Name: "ci", Scopes: []string{auth.ScopeSubscriptionsRead}},
+			action:    ActionSubscriptionsDelete,
+			code:      problem.CodeForbidden,
+		},
+		{
+			name:      "key ignores roles",
+			principal: &auth.Principal{Name: "ci", Roles: []string{RoleAdmin}, Scopes: []string{auth.ScopeReportsRead}},
+			action:    ActionRolesManage,
+			code:      problem.CodeForbidden,
+		},
+		{
+			name:      "default role",
+			principal: &auth.Principal{UserID: "alice"},
+			action:    ActionSubscriptionsRead,
+			scope:     "alice",
+		},
+		{
+			name:      "default role is not granted the action",
+			principal: &auth.Principal{UserID: "alice"},
+			action:    ActionWebhooksManage,
+			code:      problem.CodeForbidden,
+		},
+		{
+			name:      "token role for all users",
+			principal: &auth.Principal{UserID: "root", Roles: []string{RoleAdmin}},
+			action:    ActionSubscriptionsDelete,
+		},
+		{
+			name:      "assigned role for all users",
+			principal: &auth.Principal{UserID: "auditor"},
+			action:    ActionReportsRead,
+		},
+		{
+			name:      "assigned role replaces the default role",
+			principal: &auth.Principal{UserID: "support"},
+			action:    ActionSubscriptionsCreate,
+			code:      problem.CodeForbidden,
+		},
+		{
+			name:      "user scopes are ignored",
+			principal: &auth.Principal{UserID: "alice", Scopes: []string{auth.ScopeKeysManage}},
+			action:    ActionKeysManage,
+			code:      problem.CodeForbidden,
+		},
+		{
+			name:      "roles cannot be loaded",
+			principal: &auth.Principal{UserID: "alice"},
+			storage:   &fakeStorage{err: errors.New("connection refused")},
+			action:    ActionSubscriptionsRead,
+			code:      problem.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := storage
+			if tt.storage != nil {
+				s = tt.storage
+			}
+
+			a, err := New(&Config{}, s, zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+
+			ctx, err = a.Authorize(ctx, tt.action)
+			if tt.code != "" {
+				var perr *problem.Error
+				if !errors.As(err, &perr) || perr.Code != tt.code {
+					t.Fatalf("Authorize() error = %v, want code %s", err, tt.code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+
+			if scope := auth.UserScope(ctx); scope != tt.scope {
+				t.Errorf("UserScope() = %q, want %q", scope, tt.scope)
+			}
+		})
+	}
+}
+
+func TestGrantableScopes(t *testing.T) {
+	storage := &fakeStorage{assignments: map[string][]string{
+		"auditor": {RoleFinanceAuditor},
+		"support": {RoleSupport},
+	}}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		storage   *fakeStorage
+		scopes    []string
+		code      problem.Code
+	}{
+		{
+			name:   "no principal",
+			scopes: auth.Scopes,
+		},
+		{
+			name:      "key grants its own scopes",
+			principal: &auth.Principal{Name: "ci", Scopes: []string{auth.ScopeKeysManage, auth.ScopeReportsRead}},
+			scopes:    []string{auth.ScopeKeysManage, auth.ScopeReportsRead},
+		},
+		{
+			name:      "admin role",
+			principal: &auth.Principal{UserID: "root", Roles: []string{RoleAdmin}},
+			scopes:    auth.Scopes,
+		},
+		{
+			name:      "default role is restricted to the own data",
+			principal: &auth.Principal{UserID: "alice"},
+		},
+		{
+			name:      "read-only role",
+			principal: &auth.Principal{UserID: "auditor"},
+			scopes:    []string{auth.ScopeSubscriptionsRead, auth.ScopeReportsRead},
+		},
+		{
+			name:      "role without every action of a scope",
+			principal: &auth.Principal{UserID: "support"},
+			scopes:    []string{auth.ScopeSubscriptionsRead},
+		},
+		{
+			name:      "roles cannot be loaded",
+			principal: &auth.Principal{UserID: "alice"},
+			storage:   &fakeStorage{err: errors.New("connection refused")},
+			code:      problem.CodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := storage
+			if tt.storage != nil {
+				s = tt.storage
+			}
+
+			a, err := New(&Config{}, s, zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+
+			scopes, err := a.GrantableScopes(ctx)
+			if tt.code != "" {
+				var perr *problem.Error
+				if !errors.As(err, &perr) || perr.Code != tt.code {
+					t.Fatalf("GrantableScopes() error = %v, want code %s", err, tt.code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("GrantableScopes() error = %v", err)
+			}
+
+			if !slices.Equal(scopes, tt.scopes) {
+				t.Errorf("GrantableScopes() = %v, want %v", scopes, tt.scopes)
+			}
+		})
+	}
+}
+
+func TestNewUnknownDefaultRole(t *testing.T) {
+	if _, err := New(&Config{DefaultRole: "root"}, &fakeStorage{}, zap.NewNop()); err == nil {
+		t.Error("New() error = nil, want an error for an unknown default role")
+	}
+}
@@ -0,0 +1,100 @@
+package rbac
+
+import (
+	"context"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+)
+
+const (
+	// RoleAdmin grants every action on the data of all users.
+	RoleAdmin = auth.RoleAdmin
+
+	// RoleOwner grants managing and reporting on the caller's own subscriptions.
+	RoleOwner = "owner"
+
+	// RoleFinanceAuditor grants read-only access to subscriptions and reports of all users.
+	RoleFinanceAuditor = "finance-auditor"
+
+	// RoleSupport grants reading and updating, but not creating or deleting, subscriptions of all users.
+	RoleSupport = "support"
+
+	// DefaultRole defines the role of users which were not granted any role.
+	DefaultRole = RoleOwner
+)
+
+// Actions which routes are authorized for.
+const (
+	ActionSubscriptionsRead   = "subscriptions:read"
+	ActionSubscriptionsCreate = "subscriptions:create"
+	ActionSubscriptionsUpdate = "subscriptions:update"
+	ActionSubscriptionsDelete = "subscriptions:delete"
+	ActionReportsRead         = "reports:read"
+	ActionWebhooksManage      = "webhooks:manage"
+	ActionKeysManage          = "keys:manage"
+	ActionRolesManage         = "roles:manage"
+)
+
+// actionScopes maps every action to the API key scope required to perform it.
+var actionScopes = map[string]string{
+	ActionSubscriptionsRead:   auth.ScopeSubscriptionsRead,
+	ActionSubscriptionsCreate: auth.ScopeSubscriptionsWrite,
+	ActionSubscriptionsUpdate: auth.ScopeSubscriptionsWrite,
+	ActionSubscriptionsDelete: auth.ScopeSubscriptionsWrite,
+	ActionReportsRead:         auth.ScopeReportsRead,
+	ActionWebhooksManage:      auth.ScopeWebhooksManage,
+	ActionKeysManage:          auth.ScopeKeysManage,
+	ActionRolesManage:         auth.ScopeRolesManage,
+}
+
+// Roles defines the permissions granted by every role.
+var Roles = []api.Role{
+	{
+		Name: RoleAdmin,
+		Permissions: []api.Permission{
+			{Action: ActionSubscriptionsRead, AllUsers: true},
+			{Action: ActionSubscriptionsCreate, AllUsers: true},
+			{Action: ActionSubscriptionsUpdate, AllUsers: true},
+			{Action: ActionSubscriptionsDelete, AllUsers: true},
+			{Action: ActionReportsRead, AllUsers: true},
+			{Action: ActionWebhooksManage, AllUsers: true},
+			{Action: ActionKeysManage, AllUsers: true},
+			{Action: ActionRolesManage, AllUsers: true},
+		},
+	},
+	{
+		Name: RoleOwner,
+		Permissions: []api.Permission{
+			{Action: ActionSubscriptionsRead},
+			{Action: ActionSubscriptionsCreate},
+			{Action: ActionSubscriptionsUpdate},
+			{Action: ActionSubscriptionsDelete},
+			{Action: ActionReportsRead},
+		},
+	},
+	{
+		Name: RoleFinanceAuditor,
+		Permissions: []api.Permission{
+			{Action: ActionSubscriptionsRead, AllUsers: true},
+			{Action: ActionReportsRead, AllUsers: true},
+		},
+	},
+	{
+		Name: RoleSupport,
+		Permissions: []api.Permission{
+			{Action: ActionSubscriptionsRead, AllUsers: true},
+			{Action: ActionSubscriptionsUpdate, AllUsers: true},
+		},
+	},
+}
+
+// Config defines the authorization settings.
+type Config struct {
+	DefaultRole string `env:"RBAC_DEFAULT_ROLE" env-default:"owner"`
+}
+
+// Storage defines the storage operations required by the Authorizer.
+type Storage interface {
+	ListRoleAssignments(context.Context, string) ([]*api.RoleAssignment, error)
+}
@@ -0,0 +1,98 @@
+package rollup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+// New creates and returns a new Refresher instance, applying default horizon and interval if not set.
+func New(config *Config, storage Storage, logger *zap.Logger) *Refresher {
+	if config.Horizon == 0 {
+		config.Horizon = DefaultHorizon
+	}
+
+	if config.Interval == 0 {
+		config.Interval = DefaultInterval
+	}
+
+	return &Refresher{
+		storage:  storage,
+		logger:   logger,
+		horizon:  config.Horizon,
+		interval: config.Interval,
+		now:      time.Now,
+	}
+}
+
+// Start runs the refresher loop in background until Stop is called or ctx is done.
+func (r *Refresher) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := r.RunOnce(ctx); err != nil {
+				r.logger.Error("Refresher: run failed", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the refresher loop and waits for the refresh in flight to finish.
+func (r *Refresher) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	r.wg.Wait()
+}
+
+// RunOnce extends the rollup of all tenants through the horizon.
+func (r *Refresher) RunOnce(ctx context.Context) error {
+	through := r.Through()
+
+	rows, err := r.storage.ExtendMonthlySpend(tenant.WithTenant(ctx, tenant.All), through)
+	if err != nil {
+		return fmt.Errorf("RunOnce: %w", err)
+	}
+
+	if rows > 0 {
+		r.logger.Info("Refresher: rollup extended", zap.String("through", through.Format(api.ISOMonthLayout)), zap.Int64("rows", rows))
+	}
+
+	return nil
+}
+
+// Rebuild recomputes the rollup of the tenant of ctx, or of all tenants, from scratch through the horizon
+// and returns the number of rolled up rows.
+func (r *Refresher) Rebuild(ctx context.Context) (int64, error) {
+	rows, err := r.storage.RebuildMonthlySpend(ctx, r.Through())
+	if err != nil {
+		return 0, fmt.Errorf("Rebuild: %w", err)
+	}
+
+	return rows, nil
+}
+
+// Through returns the last month the rollup covers: the month Horizon months after the current one.
+func (r *Refresher) Through() time.Time {
+	now := r.now().UTC()
+	return time.Date(now.Year(), now.Month()+time.Month(r.horizon), 1, 0, 0, 0, 0, time.UTC)
+}
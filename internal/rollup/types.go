@@ -0,0 +1,45 @@
+package rollup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultHorizon defines how many months after the current one the rollup covers by default.
+	DefaultHorizon = 24
+
+	// DefaultInterval defines how often the refresher extends the rollup by default.
+	DefaultInterval = time.Hour
+)
+
+// Config defines the monthly spend rollup settings. The rollup covers the months through Horizon months after
+// the current one; the total prices and the monthly costs of the periods it covers are read from it.
+// Subscriptions without an end date are rolled up through the last covered month, so the refresher extends
+// the coverage every Interval as time goes by, and rolls up the tenants which have no rollup yet.
+type Config struct {
+	Enabled  bool          `env:"ROLLUP_ENABLED"`
+	Horizon  int           `env:"ROLLUP_HORIZON" env-default:"24"`
+	Interval time.Duration `env:"ROLLUP_REFRESH_INTERVAL" env-default:"1h"`
+}
+
+// Storage defines the storage operations required by the Refresher.
+type Storage interface {
+	RebuildMonthlySpend(context.Context, time.Time) (int64, error)
+	ExtendMonthlySpend(context.Context, time.Time) (int64, error)
+}
+
+// Refresher keeps the monthly spend rollup covering the configured horizon.
+type Refresher struct {
+	storage  Storage
+	logger   *zap.Logger
+	horizon  int
+	interval time.Duration
+	now      func() time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
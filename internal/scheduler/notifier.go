@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// webhookTimeout defines the maximum duration of a single webhook delivery.
+const webhookTimeout = 10 * time.Second
+
+// NewNotifier creates the Notifier selected by the "notifier" config field: "log" (default), "webhook" or "smtp".
+func NewNotifier(config *Config, logger *zap.Logger) (Notifier, error) {
+	switch config.Notifier {
+	case "", "log":
+		return &LogNotifier{logger: logger}, nil
+
+	case "webhook":
+		if config.WebhookURL == "" {
+			return nil, fmt.Errorf("NewNotifier: webhook url is not set")
+		}
+
+		return &WebhookNotifier{
+			url:    config.WebhookURL,
+			client: &http.Client{Timeout: webhookTimeout},
+		}, nil
+
+	case "smtp":
+		if config.SMTPHost == "" || config.SMTPFrom == "" || config.SMTPTo == "" {
+			return nil, fmt.Errorf("NewNotifier: smtp host, sender and recipient must be set")
+		}
+
+		return &SMTPNotifier{
+			addr: net.JoinHostPort(config.SMTPHost, strconv.Itoa(config.SMTPPort)),
+			from: config.SMTPFrom,
+			to:   config.SMTPTo,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("NewNotifier: unknown notifier: %s", config.Notifier)
+	}
+}
+
+// LogNotifier writes reminders to the application log.
+type LogNotifier struct {
+	logger *zap.Logger
+}
+
+// Notify logs the reminder.
+func (n *LogNotifier) Notify(_ context.Context, reminder *Reminder) error {
+	n.logger.Info("subscription reminder",
+		zap.String("kind", reminder.Kind),
+		zap.Int("subscription_id", reminder.SubscriptionID),
+		zap.String("user_id", reminder.Subscription.UserID),
+		zap.String("service_name", reminder.Subscription.ServiceName),
+		zap.Time("due_date", reminder.DueDate),
+	)
+
+	return nil
+}
+
+// WebhookNotifier posts reminders as JSON to the configured URL.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// Notify sends the reminder to the webhook, treating any non-2xx status as a failure.
+func (n *WebhookNotifier) Notify(ctx context.Context, reminder *Reminder) error {
+	body, err := json.Marshal(reminder)
+	if err != nil {
+		return fmt.Errorf("WebhookNotifier: cannot encode reminder: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("WebhookNotifier: cannot create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("WebhookNotifier: cannot send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("WebhookNotifier: unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// SMTPNotifier sends reminders by email through an SMTP server without authentication,
+// which is intended for a local relay or a stub such as MailHog.
+type SMTPNotifier struct {
+	addr string
+	from string
+	to   string
+}
+
+// Notify sends the reminder as a plain text email.
+func (n *SMTPNotifier) Notify(_ context.Context, reminder *Reminder) error {
+	err := smtp.SendMail(n.addr, nil, n.from, []string{n.to}, n.message(reminder))
+	if err != nil {
+		return fmt.Errorf("SMTPNotifier: cannot send email: %w", err)
+	}
+
+	return nil
+}
+
+// message returns the email of the reminder. The service name and the user id come from the clients, so they are
+// kept on a single line in the body and the subject is Q-encoded, leaving no way to inject headers.
+func (n *SMTPNotifier) message(reminder *Reminder) []byte {
+	subject := fmt.Sprintf("Subscription %s reminder: %s", reminder.Kind, reminder.Subscription.ServiceName)
+	text := fmt.Sprintf("Subscription %d of user %s to %s is due for %s on %s.",
+		reminder.SubscriptionID,
+		singleLine(reminder.Subscription.UserID),
+		singleLine(reminder.Subscription.ServiceName),
+		reminder.Kind,
+		reminder.DueDate.Format(time.DateOnly),
+	)
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		n.from, n.to, mime.QEncoding.Encode("utf-8", subject), text)
+
+	return []byte(msg)
+}
+
+// lineBreaks replaces the line breaks of a client supplied value with spaces.
+var lineBreaks = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// singleLine returns s with its line breaks replaced by spaces.
+func singleLine(s string) string {
+	return lineBreaks.Replace(s)
+}
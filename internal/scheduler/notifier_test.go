@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"subscriptions/internal/api"
+)
+
+func TestSMTPMessage(t *testing.T) {
+	n := &SMTPNotifier{from: "billing@example.com", to: "ops@example.com"}
+
+	tests := []struct {
+		name        string
+		serviceName string
+		userID      string
+		wantSubject string
+		wantBody    string
+	}{
+		{
+			name:        "plain",
+			serviceName: "Netflix",
+			userID:      "alice",
+			wantSubject: "Subscription renewal reminder: Netflix",
+			wantBody:    "Subscription 7 of user alice to Netflix is due for renewal on 2025-02-01.\r\n",
+		},
+		{
+			name:        "header injection",
+			serviceName: "Netflix\r\nBcc: victim@example.com",
+			userID:      "alice\nX-Injected: yes",
+			wantSubject: "Subscription renewal reminder: Netflix\r\nBcc: victim@example.com",
+			wantBody:    "Subscription 7 of user alice X-Injected: yes to Netflix Bcc: victim@example.com is due for renewal on 2025-02-01.\r\n",
+		},
+		{
+			name:        "non-ASCII",
+			serviceName: "Кинопоиск",
+			userID:      "alice",
+			wantSubject: "Subscription renewal reminder: Кинопоиск",
+			wantBody:    "Subscription 7 of user alice to Кинопоиск is due for renewal on 2025-02-01.\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := n.message(&Reminder{
+				Kind:           KindRenewal,
+				SubscriptionID: 7,
+				Subscription:   api.Subscription{ServiceName: tt.serviceName, UserID: tt.userID},
+				DueDate:        time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+			})
+
+			parsed, err := mail.ReadMessage(strings.NewReader(string(msg)))
+			if err != nil {
+				t.Fatalf("cannot parse message: %v", err)
+			}
+
+			if len(parsed.Header) != 5 || parsed.Header.Get("Bcc") != "" || parsed.Header.Get("X-Injected") != "" {
+				t.Errorf("headers = %v, want From, To, Subject, MIME-Version and Content-Type", parsed.Header)
+			}
+
+			subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+			if err != nil || subject != tt.wantSubject {
+				t.Errorf("subject = %q, %v, want %q", subject, err, tt.wantSubject)
+			}
+
+			body, err := io.ReadAll(parsed.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+	"subscriptions/internal/tenant"
+)
+
+// Scheduler periodically looks for subscriptions ending or renewing within the configured window
+// and dispatches a reminder for each of them through the Notifier.
+type Scheduler struct {
+	storage  Storage
+	notifier Notifier
+	logger   *zap.Logger
+	interval time.Duration
+	window   time.Duration
+	now      func() time.Time
+	batch    int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates and returns a new Scheduler instance, applying default interval and window if not set.
+func New(config *Config, storage Storage, notifier Notifier, logger *zap.Logger) *Scheduler {
+	if config.Interval == 0 {
+		config.Interval = DefaultInterval
+	}
+
+	if config.Window == 0 {
+		config.Window = DefaultWindow
+	}
+
+	return &Scheduler{
+		storage:  storage,
+		notifier: notifier,
+		logger:   logger,
+		interval: config.Interval,
+		window:   config.Window,
+		now:      time.Now,
+		batch:    batchSize,
+	}
+}
+
+// Start runs the scheduler loop in background until Stop is called or ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.RunOnce(ctx); err != nil {
+				s.logger.Error("Scheduler: run failed", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler loop and waits for the reminders in flight to be dispatched.
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.wg.Wait()
+}
+
+// RunOnce finds due reminders of all tenants and dispatches those which were not dispatched before.
+// Only the subscriptions with a reminder due within the window that was not claimed yet are loaded, in batches.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	ctx = tenant.WithTenant(ctx, tenant.All)
+
+	now := s.now()
+	deadline := now.Add(s.window)
+
+	afterID := 0
+	for ctx.Err() == nil {
+		records, err := s.storage.ListReminderCandidates(ctx, afterID, now, deadline, nextCharge(now), s.batch)
+		if err != nil {
+			return fmt.Errorf("RunOnce: failed to load subscriptions: %w", err)
+		}
+
+		for _, reminder := range collectReminders(records, now, s.window) {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			s.dispatch(ctx, reminder)
+		}
+
+		if len(records) < s.batch {
+			break
+		}
+
+		afterID = records[len(records)-1].ID
+	}
+
+	return nil
+}
+
+// dispatch claims the reminder and sends it, releasing the claim if sending fails so it is retried later.
+func (s *Scheduler) dispatch(ctx context.Context, reminder *Reminder) {
+	logger := s.logger.With(
+		zap.String("kind", reminder.Kind),
+		zap.String("tenant_id", reminder.TenantID),
+		zap.Int("subscription_id", reminder.SubscriptionID),
+		zap.String("period", reminder.Period),
+	)
+
+	claimed, err := s.storage.ClaimReminder(ctx, reminder.SubscriptionID, reminder.Kind, reminder.Period)
+	if err != nil {
+		logger.Error("Scheduler: cannot claim reminder", zap.Error(err))
+		return
+	}
+
+	if !claimed {
+		return
+	}
+
+	err = s.notifier.Notify(ctx, reminder)
+	if err != nil {
+		logger.Error("Scheduler: cannot send reminder", zap.Error(err))
+
+		if err = s.storage.ReleaseReminder(ctx, reminder.SubscriptionID, reminder.Kind, reminder.Period); err != nil {
+			logger.Error("Scheduler: cannot release reminder", zap.Error(err))
+		}
+
+		return
+	}
+
+	logger.Info("Scheduler: reminder sent")
+}
+
+// collectReminders returns reminders for subscriptions ending or renewing in (now, now+window].
+// A subscription is active through its end_date month, so it ends at the beginning of the next month,
+// and every active subscription is charged at the beginning of each month.
+func collectReminders(records []*postgresClient.SubscriptionRecord, now time.Time, window time.Duration) []*Reminder {
+	var res []*Reminder
+
+	deadline := now.Add(window)
+	nextCharge := nextCharge(now)
+
+	for _, record := range records {
+		start, err := time.Parse(dateLayout, record.Subscription.StartDate)
+		if err != nil {
+			continue
+		}
+
+		var end time.Time
+		if record.Subscription.EndDate != "" {
+			end, err = time.Parse(dateLayout, record.Subscription.EndDate)
+			if err != nil {
+				continue
+			}
+
+			expiry := end.AddDate(0, 1, 0)
+			if expiry.After(now) && !expiry.After(deadline) {
+				res = append(res, newReminder(KindExpiry, record, expiry))
+			}
+		}
+
+		active := !start.After(nextCharge) && (end.IsZero() || !end.Before(nextCharge))
+		if active && nextCharge.After(now) && !nextCharge.After(deadline) {
+			res = append(res, newReminder(KindRenewal, record, nextCharge))
+		}
+	}
+
+	return res
+}
+
+// nextCharge returns the beginning of the month after now, when the active subscriptions are charged next.
+func nextCharge(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+}
+
+// newReminder creates a reminder of the given kind due at the specified date.
+func newReminder(kind string, record *postgresClient.SubscriptionRecord, due time.Time) *Reminder {
+	return &Reminder{
+		Kind:           kind,
+		TenantID:       record.TenantID,
+		SubscriptionID: record.ID,
+		Subscription:   record.Subscription,
+		DueDate:        due,
+		Period:         due.Format(dateLayout),
+	}
+}
@@ -0,0 +1,228 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+func record(id int, startDate string, endDate string) *postgresClient.SubscriptionRecord {
+	return &postgresClient.SubscriptionRecord{
+		ID:           id,
+		TenantID:     "acme",
+		Subscription: api.Subscription{ServiceName: "Netflix", Price: 400, UserID: "alice", StartDate: startDate, EndDate: endDate},
+	}
+}
+
+// describe returns "<kind> <subscription id> <period>" for every reminder.
+func describe(reminders []*Reminder) []string {
+	var res []string
+	for _, reminder := range reminders {
+		res = append(res, fmt.Sprintf("%s %d %s", reminder.Kind, reminder.SubscriptionID, reminder.Period))
+	}
+
+	return res
+}
+
+func TestCollectReminders(t *testing.T) {
+	week := 7 * 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		window  time.Duration
+		records []*postgresClient.SubscriptionRecord
+		want    []string
+	}{
+		{
+			name:   "end of month",
+			now:    time.Date(2025, 1, 28, 12, 0, 0, 0, time.UTC),
+			window: week,
+			records: []*postgresClient.SubscriptionRecord{
+				record(1, "01-2024", ""),
+				record(2, "06-2024", "01-2025"),
+				record(3, "06-2024", "02-2025"),
+				record(4, "02-2025", ""),
+				record(5, "03-2025", ""),
+			},
+			want: []string{"renewal 1 02-2025", "expiry 2 02-2025", "renewal 3 02-2025", "renewal 4 02-2025"},
+		},
+		{
+			name:    "middle of month",
+			now:     time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC),
+			window:  week,
+			records: []*postgresClient.SubscriptionRecord{record(1, "01-2024", ""), record(2, "06-2024", "01-2025")},
+		},
+		{
+			name:    "window ends at the beginning of the month",
+			now:     time.Date(2025, 1, 25, 0, 0, 0, 0, time.UTC),
+			window:  week,
+			records: []*postgresClient.SubscriptionRecord{record(1, "01-2024", ""), record(2, "06-2024", "01-2025")},
+			want:    []string{"renewal 1 02-2025", "expiry 2 02-2025"},
+		},
+		{
+			name:    "window ends just before the beginning of the month",
+			now:     time.Date(2025, 1, 25, 0, 0, 0, 0, time.UTC),
+			window:  week - time.Second,
+			records: []*postgresClient.SubscriptionRecord{record(1, "01-2024", ""), record(2, "06-2024", "01-2025")},
+		},
+		{
+			name:    "beginning of the month has passed",
+			now:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+			window:  week,
+			records: []*postgresClient.SubscriptionRecord{record(1, "01-2024", ""), record(2, "06-2024", "01-2025")},
+		},
+		{
+			name:    "end of year",
+			now:     time.Date(2025, 12, 29, 9, 0, 0, 0, time.UTC),
+			window:  week,
+			records: []*postgresClient.SubscriptionRecord{record(1, "01-2024", ""), record(2, "06-2024", "12-2025")},
+			want:    []string{"renewal 1 01-2026", "expiry 2 01-2026"},
+		},
+		{
+			name:    "end of leap february",
+			now:     time.Date(2024, 2, 27, 0, 0, 0, 0, time.UTC),
+			window:  3 * 24 * time.Hour,
+			records: []*postgresClient.SubscriptionRecord{record(1, "01-2024", ""), record(2, "06-2023", "02-2024")},
+			want:    []string{"renewal 1 03-2024", "expiry 2 03-2024"},
+		},
+		{
+			name:    "window spanning two months",
+			now:     time.Date(2025, 1, 28, 0, 0, 0, 0, time.UTC),
+			window:  40 * 24 * time.Hour,
+			records: []*postgresClient.SubscriptionRecord{record(1, "06-2024", "02-2025")},
+			want:    []string{"expiry 1 03-2025", "renewal 1 02-2025"},
+		},
+		{
+			name:    "invalid dates",
+			now:     time.Date(2025, 1, 28, 0, 0, 0, 0, time.UTC),
+			window:  week,
+			records: []*postgresClient.SubscriptionRecord{record(1, "2024-01", ""), record(2, "01-2024", "2025-01")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describe(collectReminders(tt.records, tt.now, tt.window))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("collectReminders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeStorage serves the records and keeps the claimed reminders like the reminders table does.
+type fakeStorage struct {
+	records  []*postgresClient.SubscriptionRecord
+	claimed  map[string]bool
+	claimErr error
+	queries  int
+}
+
+// ListReminderCandidates pages through the records by id, leaving the window to collectReminders.
+func (f *fakeStorage) ListReminderCandidates(_ context.Context, afterID int, _, _, _ time.Time, limit int) ([]*postgresClient.SubscriptionRecord, error) {
+	f.queries++
+
+	var res []*postgresClient.SubscriptionRecord
+	for _, record := range f.records {
+		if record.ID > afterID && len(res) < limit {
+			res = append(res, record)
+		}
+	}
+
+	return res, nil
+}
+
+func (f *fakeStorage) ClaimReminder(_ context.Context, id int, kind string, period string) (bool, error) {
+	if f.claimErr != nil {
+		return false, f.claimErr
+	}
+
+	key := fmt.Sprintf("%s %d %s", kind, id, period)
+	if f.claimed[key] {
+		return false, nil
+	}
+
+	f.claimed[key] = true
+	return true, nil
+}
+
+func (f *fakeStorage) ReleaseReminder(_ context.Context, id int, kind string, period string) error {
+	delete(f.claimed, fmt.Sprintf("%s %d %s", kind, id, period))
+	return nil
+}
+
+// fakeNotifier records the sent reminders, failing while err is set.
+type fakeNotifier struct {
+	sent []*Reminder
+	err  error
+}
+
+func (f *fakeNotifier) Notify(_ context.Context, reminder *Reminder) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	f.sent = append(f.sent, reminder)
+	return nil
+}
+
+func TestRunOnce(t *testing.T) {
+	storage := &fakeStorage{
+		records: []*postgresClient.SubscriptionRecord{record(1, "01-2024", ""), record(2, "06-2024", "01-2025")},
+		claimed: make(map[string]bool),
+	}
+	notifier := &fakeNotifier{}
+
+	s := New(&Config{}, storage, notifier, zap.NewNop())
+	s.now = func() time.Time { return time.Date(2025, 1, 28, 0, 0, 0, 0, time.UTC) }
+	s.batch = 1
+
+	ctx := context.Background()
+	run := func() {
+		t.Helper()
+
+		if err := s.RunOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A failed notification releases the claim, so the reminders are sent by the next run.
+	notifier.err = errors.New("smtp: connection refused")
+	run()
+	if len(storage.claimed) != 0 {
+		t.Fatalf("claimed = %v after failed notifications, want none", storage.claimed)
+	}
+
+	// The records are loaded a batch at a time, until a batch is not full.
+	if storage.queries != 3 {
+		t.Errorf("queries = %d, want 3 batches of a single record", storage.queries)
+	}
+
+	notifier.err = nil
+	run()
+	run()
+
+	want := []string{"renewal 1 02-2025", "expiry 2 02-2025"}
+	if got := describe(notifier.sent); !slices.Equal(got, want) {
+		t.Errorf("sent = %v, want %v, each once", got, want)
+	}
+
+	// Reminders which cannot be claimed are not sent.
+	storage.claimed = make(map[string]bool)
+	storage.claimErr = errors.New("connection refused")
+	notifier.sent = nil
+	run()
+
+	if len(notifier.sent) != 0 {
+		t.Errorf("sent = %v without a claim, want none", describe(notifier.sent))
+	}
+}
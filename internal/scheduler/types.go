@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+const (
+	// DefaultInterval defines how often the scheduler looks for upcoming reminders.
+	DefaultInterval = time.Hour
+
+	// DefaultWindow defines how far ahead the scheduler looks for expiring or renewing subscriptions.
+	DefaultWindow = 7 * 24 * time.Hour
+
+	// KindExpiry marks a reminder about a subscription reaching its end_date.
+	KindExpiry = "expiry"
+
+	// KindRenewal marks a reminder about an upcoming monthly charge.
+	KindRenewal = "renewal"
+
+	// dateLayout is the MM-YYYY layout used for subscription dates.
+	dateLayout = "01-2006"
+
+	// batchSize defines how many subscriptions with due reminders are loaded per query.
+	batchSize = 500
+)
+
+// Config defines the scheduler settings and the notifier used to dispatch reminders.
+type Config struct {
+	Enabled    bool          `env:"SCHEDULER_ENABLED"`
+	Interval   time.Duration `env:"SCHEDULER_INTERVAL" env-default:"1h"`
+	Window     time.Duration `env:"SCHEDULER_WINDOW" env-default:"168h"`
+	Notifier   string        `env:"SCHEDULER_NOTIFIER" env-default:"log"`
+	WebhookURL string        `env:"SCHEDULER_WEBHOOK_URL"`
+	SMTPHost   string        `env:"SCHEDULER_SMTP_HOST"`
+	SMTPPort   int           `env:"SCHEDULER_SMTP_PORT" env-default:"25"`
+	SMTPFrom   string        `env:"SCHEDULER_SMTP_FROM"`
+	SMTPTo     string        `env:"SCHEDULER_SMTP_TO"`
+}
+
+// Reminder describes a single notification about an expiring or renewing subscription.
+type Reminder struct {
+	Kind           string           `json:"kind"`
+	TenantID       string           `json:"tenant_id"`
+	SubscriptionID int              `json:"subscription_id"`
+	Subscription   api.Subscription `json:"subscription"`
+	DueDate        time.Time        `json:"due_date"`
+	Period         string           `json:"period"`
+}
+
+// Storage defines the storage operations required by the Scheduler.
+type Storage interface {
+	ListReminderCandidates(context.Context, int, time.Time, time.Time, time.Time, int) ([]*postgresClient.SubscriptionRecord, error)
+	ClaimReminder(context.Context, int, string, string) (bool, error)
+	ReleaseReminder(context.Context, int, string, string) error
+}
+
+// Notifier delivers reminders to their recipients.
+type Notifier interface {
+	Notify(context.Context, *Reminder) error
+}
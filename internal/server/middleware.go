@@ -0,0 +1,34 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/problem"
+)
+
+// LimitBody returns a middleware rejecting request bodies larger than limit bytes with 413.
+// Bodies announcing a larger Content-Length are rejected right away; others fail when read past the limit.
+// A limit of zero or less disables the check.
+func LimitBody(limit int64, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				problem.Write(logger, w, r, problem.New(problem.CodeBodyTooLarge, fmt.Sprintf("request body must not exceed %d bytes", limit)))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
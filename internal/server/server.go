@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+)
+
+// New creates and returns a new Server instance serving handler with the timeouts and limits of config.
+// If TLS is configured, the certificate is loaded right away, so a broken one fails the startup.
+func New(config *api.HttpServer, handler http.Handler, logger *zap.Logger) (*Server, error) {
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return nil, fmt.Errorf("New: both the TLS certificate and key files must be set")
+	}
+
+	s := &Server{
+		server: &http.Server{
+			Addr:              fmt.Sprintf("%s:%d", config.Host, config.Port),
+			Handler:           handler,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+			ReadTimeout:       config.ReadTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
+			MaxHeaderBytes:    config.MaxHeaderBytes,
+			ErrorLog:          zap.NewStdLog(logger.Named("http")),
+		},
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+
+	if config.TLSCertFile != "" {
+		s.reloader = newCertReloader(config.TLSCertFile, config.TLSKeyFile, config.TLSReloadInterval, logger)
+		if err := s.reloader.reload(); err != nil {
+			return nil, fmt.Errorf("New: %w", err)
+		}
+
+		s.server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: s.reloader.getCertificate,
+		}
+	}
+
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.server.Addr
+}
+
+// Start starts serving in the background. The process exits if the server cannot be started.
+func (s *Server) Start() {
+	if s.reloader != nil {
+		go s.reloader.run(s.stop)
+	}
+
+	go func() {
+		s.logger.Info("starting http server", zap.String("addr", s.server.Addr), zap.Bool("tls", s.reloader != nil))
+
+		var err error
+		if s.reloader != nil {
+			err = s.server.ListenAndServeTLS("", "")
+		} else {
+			err = s.server.ListenAndServe()
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Fatal("cannot start http server", zap.Error(err))
+		}
+	}()
+}
+
+// Shutdown gracefully stops the server, waiting for the active requests until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	close(s.stop)
+
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newCertReloader creates and returns a new certReloader for the given certificate and key files.
+func newCertReloader(certFile string, keyFile string, interval time.Duration, logger *zap.Logger) *certReloader {
+	return &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// getCertificate returns the current certificate, for use as tls.Config.GetCertificate.
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cert, nil
+}
+
+// run checks the certificate files every interval until stop is closed, reloading them when they change.
+// A certificate which fails to load is logged and the previous one is kept.
+func (c *certReloader) run(stop <-chan struct{}) {
+	if c.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case <-ticker.C:
+			modTime, err := c.latestModTime()
+			if err != nil {
+				c.logger.Error("cannot stat tls certificate", zap.Error(err))
+				continue
+			}
+
+			c.mu.RLock()
+			changed := modTime.After(c.modTime)
+			c.mu.RUnlock()
+
+			if !changed {
+				continue
+			}
+
+			if err = c.reload(); err != nil {
+				c.logger.Error("cannot reload tls certificate, keeping the previous one", zap.Error(err))
+				continue
+			}
+
+			c.logger.Info("reloaded tls certificate", zap.String("cert", c.certFile))
+		}
+	}
+}
+
+// reload loads the certificate and key files and makes them the current certificate.
+func (c *certReloader) reload() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load tls certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+
+	return nil
+}
+
+// latestModTime returns the modification time of the certificate or key file, whichever changed last.
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Server is the HTTP server of the service. It serves TLS when a certificate is configured.
+type Server struct {
+	server   *http.Server
+	reloader *certReloader
+	logger   *zap.Logger
+	stop     chan struct{}
+}
+
+// certReloader serves the TLS certificate loaded from disk, reloading it when its files change.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"subscriptions/internal/problem"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// Postgres error codes and classes distinguished by Classify, see https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	pgUniqueViolation      = "23505"
+	pgExclusionViolation   = "23P01"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgQueryCanceled        = "57014"
+
+	pgClassIntegrityConstraint = "23"
+	pgClassDataException       = "22"
+)
+
+// notFoundErrors lists the storage errors reporting a missing resource; their messages are safe to send to clients.
+var notFoundErrors = []error{
+	postgresClient.ErrSubscriptionNotFound,
+	postgresClient.ErrEventNotFound,
+	postgresClient.ErrAPIKeyNotFound,
+	postgresClient.ErrRoleAssignmentNotFound,
+	postgresClient.ErrWebhookNotFound,
+}
+
+// Classify maps err to the problem reported to the client. Errors of the storage are mapped by their kind:
+// missing resources to 404, conflicts to 409, constraint violations and invalid values to 422, timeouts to 504
+// and requests abandoned by the client to 499. Anything else is an internal error, so clients never see
+// the SQL errors themselves.
+func Classify(err error) *problem.Error {
+	var e *problem.Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	for _, notFound := range notFoundErrors {
+		if errors.Is(err, notFound) {
+			return problem.Wrap(problem.CodeNotFound, notFound.Error(), err)
+		}
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return problem.Wrap(problem.CodeCanceled, "the request was canceled", err)
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return problem.Wrap(problem.CodeTimeout, "the request could not be completed in time", err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation, pgExclusionViolation:
+			return problem.Wrap(problem.CodeConflict, "the request conflicts with an existing resource", err)
+		case pgSerializationFailure, pgDeadlockDetected:
+			return problem.Wrap(problem.CodeConflict, "the request conflicts with a concurrent change, retry it", err)
+		case pgQueryCanceled:
+			return problem.Wrap(problem.CodeTimeout, "the request could not be completed in time", err)
+		}
+
+		switch pgErr.Code[:2] {
+		case pgClassIntegrityConstraint, pgClassDataException:
+			return problem.Wrap(problem.CodeConstraintViolation, "the request violates a constraint of the stored data", err)
+		}
+	}
+
+	return problem.Wrap(problem.CodeInternal, "", err)
+}
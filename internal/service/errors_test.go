@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"subscriptions/internal/problem"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   problem.Code
+		wantStatus int
+	}{
+		{
+			name:       "problem is kept",
+			err:        problem.New(problem.CodeForbidden, "no"),
+			wantCode:   problem.CodeForbidden,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "subscription not found",
+			err:        postgresClient.ErrSubscriptionNotFound,
+			wantCode:   problem.CodeNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "wrapped webhook not found",
+			err:        fmt.Errorf("GetWebhook: %w", postgresClient.ErrWebhookNotFound),
+			wantCode:   problem.CodeNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unique violation",
+			err:        fmt.Errorf("SaveWebhook: %w", &pgconn.PgError{Code: "23505"}),
+			wantCode:   problem.CodeConflict,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "serialization failure",
+			err:        &pgconn.PgError{Code: "40001"},
+			wantCode:   problem.CodeConflict,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "check violation",
+			err:        &pgconn.PgError{Code: "23514"},
+			wantCode:   problem.CodeConstraintViolation,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid text representation",
+			err:        &pgconn.PgError{Code: "22P02"},
+			wantCode:   problem.CodeConstraintViolation,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "statement timeout",
+			err:        &pgconn.PgError{Code: "57014"},
+			wantCode:   problem.CodeTimeout,
+			wantStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "deadline exceeded",
+			err:        fmt.Errorf("ListSubscriptions: %w", context.DeadlineExceeded),
+			wantCode:   problem.CodeTimeout,
+			wantStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "canceled",
+			err:        fmt.Errorf("ListSubscriptions: %w", context.Canceled),
+			wantCode:   problem.CodeCanceled,
+			wantStatus: problem.StatusClientClosedRequest,
+		},
+		{
+			name:       "syntax error",
+			err:        &pgconn.PgError{Code: "42601"},
+			wantCode:   problem.CodeInternal,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "unknown error",
+			err:        errors.New("boom"),
+			wantCode:   problem.CodeInternal,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err)
+
+			if got.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", got.Code, tt.wantCode)
+			}
+
+			if got.Status() != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got.Status(), tt.wantStatus)
+			}
+		})
+	}
+}
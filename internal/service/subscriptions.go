@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/auth"
+	"subscriptions/internal/cache"
+	"subscriptions/internal/problem"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// Subscriptions implements the subscription operations shared by the REST and gRPC APIs.
+// Callers restricted to their own data by authorization (see auth.UserScope) only see and change
+// their own subscriptions; the subscriptions of other users are reported as missing.
+type Subscriptions struct {
+	storage    postgresClient.PostgresClient
+	aggregates *cache.Cache
+	rollups    postgresClient.RollupClient
+}
+
+// NewSubscriptions creates and returns a new Subscriptions instance backed by the given storage.
+func NewSubscriptions(storage postgresClient.PostgresClient) *Subscriptions {
+	return &Subscriptions{storage: storage}
+}
+
+// WithCache returns a copy of s caching the total prices in aggregates.
+func (s *Subscriptions) WithCache(aggregates *cache.Cache) *Subscriptions {
+	c := *s
+	c.aggregates = aggregates
+	return &c
+}
+
+// WithRollups returns a copy of s reading the total prices of the periods covered by the monthly spend rollup
+// from rollups. A nil rollups reads them from the subscriptions.
+func (s *Subscriptions) WithRollups(rollups postgresClient.RollupClient) *Subscriptions {
+	c := *s
+	c.rollups = rollups
+	return &c
+}
+
+// Create validates and saves the subscription and returns its id. Restricted callers create subscriptions for themselves.
+func (s *Subscriptions) Create(ctx context.Context, subscription *api.Subscription) (int, error) {
+	if owner := auth.UserScope(ctx); owner != "" {
+		if subscription.UserID == "" {
+			subscription.UserID = owner
+		}
+
+		if subscription.UserID != owner {
+			return 0, problem.New(problem.CodeForbidden, "cannot create subscription for another user")
+		}
+	}
+
+	if err := validate(subscription); err != nil {
+		return 0, err
+	}
+
+	return s.storage.SaveSubscription(ctx, subscription)
+}
+
+// Get returns the subscription with the given id.
+func (s *Subscriptions) Get(ctx context.Context, id int) (*api.Subscription, error) {
+	subscription, err := s.storage.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canAccess(ctx, subscription.UserID) {
+		return nil, postgresClient.ErrSubscriptionNotFound
+	}
+
+	return subscription, nil
+}
+
+// List returns all subscriptions the caller may access.
+// If there are none, returns postgresClient.ErrSubscriptionNotFound.
+func (s *Subscriptions) List(ctx context.Context) ([]*api.Subscription, error) {
+	owner := auth.UserScope(ctx)
+	if owner == "" {
+		return s.storage.ListSubscriptions(ctx)
+	}
+
+	subscriptions, err := s.storage.ListFilteredSubscriptions(ctx, owner, "")
+	if err == nil && len(subscriptions) == 0 {
+		err = postgresClient.ErrSubscriptionNotFound
+	}
+
+	return subscriptions, err
+}
+
+// ListAfter returns up to limit subscriptions with ids above afterID, ordered by id. Restricted callers
+// only see their own subscriptions.
+func (s *Subscriptions) ListAfter(ctx context.Context, afterID int, limit int) ([]*postgresClient.SubscriptionRecord, error) {
+	return s.storage.ListSubscriptionsAfter(ctx, afterID, auth.UserScope(ctx), limit)
+}
+
+// Update validates the subscription and replaces the one with the given id. Restricted callers may not transfer it to another user.
+func (s *Subscriptions) Update(ctx context.Context, id int, subscription *api.Subscription) error {
+	if owner := auth.UserScope(ctx); owner != "" {
+		if _, err := s.Get(ctx, id); err != nil {
+			return err
+		}
+
+		if subscription.UserID == "" {
+			subscription.UserID = owner
+		}
+
+		if subscription.UserID != owner {
+			return problem.New(problem.CodeForbidden, "cannot transfer subscription to another user")
+		}
+	}
+
+	if err := validate(subscription); err != nil {
+		return err
+	}
+
+	return s.storage.UpdateSubscription(ctx, id, subscription)
+}
+
+// Delete deletes the subscription with the given id.
+func (s *Subscriptions) Delete(ctx context.Context, id int) error {
+	if auth.UserScope(ctx) != "" {
+		if _, err := s.Get(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return s.storage.DeleteSubscription(ctx, id)
+}
+
+// TotalPrice calculates the total price of the subscriptions filtered by userID and/or serviceName
+// for the months from start to end. Restricted callers may only calculate it for their own subscriptions.
+func (s *Subscriptions) TotalPrice(ctx context.Context, userID string, serviceName string, start time.Time, end time.Time) (int, error) {
+	if owner := auth.UserScope(ctx); owner != "" {
+		if userID != "" && userID != owner {
+			return 0, problem.New(problem.CodeForbidden, "cannot calculate total price for another user")
+		}
+
+		userID = owner
+	}
+
+	query := cache.Query{Aggregate: cache.AggregateTotal, UserID: userID, ServiceName: serviceName, Start: start, End: end}
+	if total, ok := s.aggregates.Get(ctx, query); ok {
+		return total.(int), nil
+	}
+
+	// The total is cached until a change of the subscriptions invalidates it, so it must not be read from a replica
+	// which has not seen the change yet.
+	if s.aggregates.Enabled() {
+		ctx = postgresClient.WithPrimary(ctx)
+	}
+
+	generation := s.aggregates.Generation(ctx)
+
+	total, err := s.totalPrice(ctx, userID, serviceName, start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	s.aggregates.Set(ctx, query, generation, total)
+
+	return total, nil
+}
+
+// totalPrice calculates the total price from the monthly spend rollup if it covers the period,
+// or from the subscriptions otherwise.
+func (s *Subscriptions) totalPrice(ctx context.Context, userID string, serviceName string, start time.Time, end time.Time) (int, error) {
+	if s.rollups != nil {
+		through, ok, err := s.rollups.GetMonthlySpendCoverage(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		if ok && !end.After(through) {
+			costs, err := s.rollups.GetMonthlySpend(ctx, userID, serviceName, start, end)
+			if err != nil {
+				return 0, err
+			}
+
+			total := 0
+			for _, cost := range costs {
+				total += cost.Total
+			}
+
+			return total, nil
+		}
+	}
+
+	subscriptions, err := s.storage.ListFilteredSubscriptions(ctx, userID, serviceName)
+	if err != nil {
+		return 0, err
+	}
+
+	return api.TotalPrice(start, end, subscriptions), nil
+}
+
+// canAccess reports whether the caller may access the subscriptions of the given user.
+func canAccess(ctx context.Context, userID string) bool {
+	owner := auth.UserScope(ctx)
+	return owner == "" || owner == userID
+}
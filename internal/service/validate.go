@@ -0,0 +1,56 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/problem"
+)
+
+// validate checks the subscription before it is saved: the service and the user are required, the price must not be
+// negative, and the months must be in the MM-YYYY layout of the storage, with the end month not before the start one.
+// Every rejected field is reported, with CodeValidationFailed.
+func validate(subscription *api.Subscription) error {
+	var fields []problem.FieldError
+
+	reject := func(field string, detail string) {
+		fields = append(fields, problem.FieldError{Field: field, Detail: detail})
+	}
+
+	if strings.TrimSpace(subscription.ServiceName) == "" {
+		reject("service_name", "is required")
+	}
+
+	if strings.TrimSpace(subscription.UserID) == "" {
+		reject("user_id", "is required")
+	}
+
+	if subscription.Price < 0 {
+		reject("price", "must not be negative")
+	}
+
+	start, startErr := time.Parse(api.MonthLayout, subscription.StartDate)
+	switch {
+	case subscription.StartDate == "":
+		reject("start_date", "is required")
+	case startErr != nil:
+		reject("start_date", "must be a month in the MM-YYYY format")
+	}
+
+	if subscription.EndDate != "" {
+		end, err := time.Parse(api.MonthLayout, subscription.EndDate)
+		switch {
+		case err != nil:
+			reject("end_date", "must be a month in the MM-YYYY format")
+		case startErr == nil && end.Before(start):
+			reject("end_date", "must not be before start_date")
+		}
+	}
+
+	if len(fields) > 0 {
+		return problem.Invalid("subscription", fields)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/problem"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func(change func(*api.Subscription)) *api.Subscription {
+		subscription := &api.Subscription{ServiceName: "Netflix", Price: 400, UserID: "alice", StartDate: "01-2025", EndDate: "12-2025"}
+		change(subscription)
+		return subscription
+	}
+
+	tests := []struct {
+		name         string
+		subscription *api.Subscription
+		fields       []string
+	}{
+		{
+			name:         "valid",
+			subscription: valid(func(*api.Subscription) {}),
+		},
+		{
+			name:         "without end date",
+			subscription: valid(func(s *api.Subscription) { s.EndDate = "" }),
+		},
+		{
+			name:         "free",
+			subscription: valid(func(s *api.Subscription) { s.Price = 0 }),
+		},
+		{
+			name:         "single month",
+			subscription: valid(func(s *api.Subscription) { s.EndDate = s.StartDate }),
+		},
+		{
+			name:         "missing service name",
+			subscription: valid(func(s *api.Subscription) { s.ServiceName = " " }),
+			fields:       []string{"service_name"},
+		},
+		{
+			name:         "missing user id",
+			subscription: valid(func(s *api.Subscription) { s.UserID = "" }),
+			fields:       []string{"user_id"},
+		},
+		{
+			name:         "negative price",
+			subscription: valid(func(s *api.Subscription) { s.Price = -1 }),
+			fields:       []string{"price"},
+		},
+		{
+			name:         "missing start date",
+			subscription: valid(func(s *api.Subscription) { s.StartDate = "" }),
+			fields:       []string{"start_date"},
+		},
+		{
+			name:         "start date in the v2 layout",
+			subscription: valid(func(s *api.Subscription) { s.StartDate = "2025-01" }),
+			fields:       []string{"start_date"},
+		},
+		{
+			name:         "start month out of range",
+			subscription: valid(func(s *api.Subscription) { s.StartDate = "13-2025" }),
+			fields:       []string{"start_date"},
+		},
+		{
+			name:         "end date without leading zero",
+			subscription: valid(func(s *api.Subscription) { s.EndDate = "2-2025" }),
+			fields:       []string{"end_date"},
+		},
+		{
+			name:         "end before start",
+			subscription: valid(func(s *api.Subscription) { s.StartDate, s.EndDate = "06-2025", "05-2025" }),
+			fields:       []string{"end_date"},
+		},
+		{
+			name:         "every field",
+			subscription: &api.Subscription{Price: -400, StartDate: "january", EndDate: "never"},
+			fields:       []string{"service_name", "user_id", "price", "start_date", "end_date"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(tt.subscription)
+			if tt.fields == nil {
+				if err != nil {
+					t.Fatalf("validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var e *problem.Error
+			if !errors.As(err, &e) || e.Code != problem.CodeValidationFailed {
+				t.Fatalf("validate() error = %v, want %s", err, problem.CodeValidationFailed)
+			}
+
+			var fields []string
+			for _, field := range e.Fields {
+				fields = append(fields, field.Field)
+			}
+
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("validate() fields = %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package postgresClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+// SaveAPIKey inserts the given API key with the specified key hash into the database,
+// filling in its generated ID and creation time.
+func (ps *PostgresService) SaveAPIKey(ctx context.Context, key *api.APIKey, hash string) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	err := ps.inTenant(ctx, "SaveAPIKey", func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, queryForSaveAPIKey, key.Name, key.Prefix, hash, scopes).Scan(&key.ID, &key.CreatedAt)
+	})
+	if err != nil {
+		ps.logger.Error("SaveAPIKey: failed to save api key", zap.Error(err))
+		return fmt.Errorf("SaveAPIKey: failed to save api key: %w", err)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey returns the active API key with the specified hash and records the time it was used.
+// The key is looked up across all tenants, as the tenant of the caller is only known from the key itself.
+// If there was no such key or it was revoked, returns ErrAPIKeyNotFound.
+func (ps *PostgresService) AuthenticateAPIKey(ctx context.Context, hash string) (*api.APIKey, error) {
+	ctx, cancel := context.WithTimeout(tenant.WithTenant(ctx, tenant.All), ps.timeout)
+	defer cancel()
+
+	res := &api.APIKey{}
+
+	err := ps.inTenant(ctx, "AuthenticateAPIKey", func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, queryForAuthenticateAPIKey, hash).Scan(
+			&res.ID,
+			&res.TenantID,
+			&res.Name,
+			&res.Prefix,
+			&res.Scopes,
+			&res.CreatedAt,
+			&res.LastUsedAt,
+		)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		ps.logger.Error("AuthenticateAPIKey: failed to retrieve api key", zap.Error(err))
+		return nil, fmt.Errorf("AuthenticateAPIKey: failed to retrieve api key: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetAPIKey returns a stored API key by specified id, including revoked ones.
+// If there was no key, returns ErrAPIKeyNotFound.
+func (ps *PostgresService) GetAPIKey(ctx context.Context, id int) (*api.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	res := &api.APIKey{}
+
+	err := ps.inTenant(ctx, "GetAPIKey", func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, queryForGetAPIKey, id).Scan(
+			&res.ID,
+			&res.Name,
+			&res.Prefix,
+			&res.Scopes,
+			&res.CreatedAt,
+			&res.LastUsedAt,
+			&res.RevokedAt,
+		)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ps.logger.Error(ErrAPIKeyNotFound.Error())
+			return nil, ErrAPIKeyNotFound
+		}
+		ps.logger.Error("GetAPIKey: failed to retrieve api key", zap.Error(err))
+		return nil, fmt.Errorf("GetAPIKey: failed to retrieve api key: %w", err)
+	}
+
+	return res, nil
+}
+
+// ListAPIKeys returns all stored API keys, including revoked ones.
+func (ps *PostgresService) ListAPIKeys(ctx context.Context) ([]*api.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	res := []*api.APIKey{}
+
+	err := ps.inTenant(ctx, "ListAPIKeys", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForListAPIKeys)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			key := &api.APIKey{}
+			err = rows.Scan(&key.ID, &key.Name, &key.Prefix, &key.Scopes, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+			if err != nil {
+				return err
+			}
+
+			res = append(res, key)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		ps.logger.Error("ListAPIKeys: failed to retrieve api keys", zap.Error(err))
+		return nil, fmt.Errorf("ListAPIKeys: failed to retrieve api keys: %w", err)
+	}
+
+	return res, nil
+}
+
+// RevokeAPIKey revokes the active API key with the specified id.
+// If there was no active key, returns ErrAPIKeyNotFound.
+func (ps *PostgresService) RevokeAPIKey(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	err := ps.inTenant(ctx, "RevokeAPIKey", func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryForRevokeAPIKey, id)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrAPIKeyNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			ps.logger.Error(ErrAPIKeyNotFound.Error())
+			return ErrAPIKeyNotFound
+		}
+		ps.logger.Error("RevokeAPIKey: failed to revoke api key", zap.Error(err), zap.Int("id", id))
+		return fmt.Errorf("RevokeAPIKey: failed to revoke api key: %w", err)
+	}
+
+	return nil
+}
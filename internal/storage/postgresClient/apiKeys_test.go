@@ -0,0 +1,60 @@
+package postgresClient
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+func TestAPIKeyRevocation(t *testing.T) {
+	ps := newIntegrationService(t)
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	ctx := tenant.WithTenant(context.Background(), "keys-"+suffix)
+	hash := "hash-" + suffix
+
+	key := &api.APIKey{Name: "ci", Prefix: "sk_" + suffix, Scopes: []string{"subscriptions:read"}}
+	if err := ps.SaveAPIKey(ctx, key, hash); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key is found by its hash alone, with the tenant it was saved in.
+	got, err := ps.AuthenticateAPIKey(context.Background(), hash)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey() error = %v", err)
+	}
+
+	if got.ID != key.ID || got.TenantID != "keys-"+suffix {
+		t.Errorf("AuthenticateAPIKey() = %+v, want key %d of tenant %q", got, key.ID, "keys-"+suffix)
+	}
+
+	if _, err = ps.AuthenticateAPIKey(context.Background(), "other-"+hash); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("AuthenticateAPIKey() of an unknown hash error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+
+	if err = ps.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+
+	if _, err = ps.AuthenticateAPIKey(context.Background(), hash); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("AuthenticateAPIKey() of a revoked key error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+
+	if err = ps.RevokeAPIKey(ctx, key.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("RevokeAPIKey() of a revoked key error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+
+	revoked, err := ps.GetAPIKey(ctx, key.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if revoked.RevokedAt == nil {
+		t.Errorf("GetAPIKey() = %+v, want the revocation time", revoked)
+	}
+}
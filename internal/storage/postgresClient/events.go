@@ -0,0 +1,155 @@
+package postgresClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+)
+
+// GetEvent returns the outbox event with the specified id.
+// If there was no event, returns ErrEventNotFound.
+func (ps *PostgresService) GetEvent(ctx context.Context, id int) (*api.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	res := &api.Event{}
+
+	err := ps.inTenant(ctx, "GetEvent", func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, queryForGetEvent, id).Scan(
+			&res.ID,
+			&res.TenantID,
+			&res.Type,
+			&res.SubscriptionID,
+			&res.Data,
+			&res.OccurredAt,
+		)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ps.logger.Error(ErrEventNotFound.Error())
+			return nil, ErrEventNotFound
+		}
+		ps.logger.Error("GetEvent: failed to retrieve event", zap.Error(err))
+		return nil, fmt.Errorf("GetEvent: failed to retrieve event: %w", err)
+	}
+
+	return res, nil
+}
+
+// ListEventsAfter returns up to limit events following the event with the specified id,
+// optionally filtered by userID (an empty userID returns events of all users).
+func (ps *PostgresService) ListEventsAfter(ctx context.Context, afterID int, userID string, limit int) ([]*api.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var res []*api.Event
+
+	err := ps.inTenant(ctx, "ListEventsAfter", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForListEventsAfter, afterID, userID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			event := &api.Event{}
+			err = rows.Scan(&event.ID, &event.TenantID, &event.Type, &event.SubscriptionID, &event.Data, &event.OccurredAt)
+			if err != nil {
+				return err
+			}
+
+			res = append(res, event)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		ps.logger.Error("ListEventsAfter: failed to retrieve events", zap.Error(err))
+		return nil, fmt.Errorf("ListEventsAfter: failed to retrieve events: %w", err)
+	}
+
+	return res, nil
+}
+
+// EventReplayStart returns the id to replay the events from when resuming after the event with the specified id:
+// the id preceding the oldest event with a lower id created at most window before it, or id itself if there is none.
+// Ids are taken when the events are inserted rather than committed, so the events with ids just below id may
+// have been committed after it.
+func (ps *PostgresService) EventReplayStart(ctx context.Context, id int, window time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var res int
+
+	err := ps.inTenant(ctx, "EventReplayStart", func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, queryForEventReplayStart, id, window).Scan(&res)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("EventReplayStart: %w", err)
+	}
+
+	return res, nil
+}
+
+// PruneEvents deletes up to limit outbox events created more than retention ago, together with their webhook
+// deliveries, and returns how many were deleted. The events with pending deliveries are kept, and so are the events
+// not fanned out to the webhooks yet, unless undispatched is set.
+func (ps *PostgresService) PruneEvents(ctx context.Context, retention time.Duration, undispatched bool, limit int) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var res int64
+
+	err := ps.inTenant(ctx, "PruneEvents", func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryForPruneEvents, retention, undispatched, limit)
+		res = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("PruneEvents: %w", err)
+	}
+
+	return res, nil
+}
+
+// ListenEvents blocks until ctx is done, calling notify with the id of every new outbox event.
+// It holds a dedicated connection taken out of the pool, which is closed on return.
+func (ps *PostgresService) ListenEvents(ctx context.Context, notify func(int)) error {
+	pooled, err := ps.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("ListenEvents: failed to acquire connection: %w", err)
+	}
+
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, queryForListenEvents)
+	if err != nil {
+		return fmt.Errorf("ListenEvents: failed to listen: %w", err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("ListenEvents: failed to wait for notification: %w", err)
+		}
+
+		id, err := strconv.Atoi(notification.Payload)
+		if err != nil {
+			ps.logger.Warn("ListenEvents: unexpected notification payload", zap.String("payload", notification.Payload))
+			continue
+		}
+
+		notify(id)
+	}
+}
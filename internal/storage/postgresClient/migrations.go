@@ -0,0 +1,138 @@
+package postgresClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"go.uber.org/zap"
+)
+
+// Migrator applies the schema migrations found at the migrations path.
+// Every change is made while golang-migrate holds its advisory lock, so replicas starting at the same time
+// apply the migrations one after another.
+type Migrator struct {
+	migrate *migrate.Migrate
+}
+
+// NewMigrator creates and returns a new Migrator instance for the database given by config.
+// Close must be called to release its connections.
+func NewMigrator(_ context.Context, config *Config, migrationsPath string, logger *zap.Logger) (*Migrator, error) {
+	url := buildURL(config)
+
+	m, err := migrate.New(migrationsPath, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration: %w", err)
+	}
+
+	m.Log = &migrateLogger{logger: logger}
+
+	return &Migrator{migrate: m}, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(_ context.Context) error {
+	return m.run("Up", m.migrate.Up)
+}
+
+// Down rolls back the given number of the latest applied migrations.
+func (m *Migrator) Down(_ context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("Down: steps must be positive")
+	}
+
+	return m.run("Down", func() error {
+		return m.migrate.Steps(-steps)
+	})
+}
+
+// To applies or rolls back migrations until the schema is at the given version.
+func (m *Migrator) To(_ context.Context, version uint) error {
+	return m.run("To", func() error {
+		return m.migrate.Migrate(version)
+	})
+}
+
+// Force sets the schema version without running any migration and clears the dirty flag,
+// for recovering from a migration which failed halfway. A version of -1 means no migration was applied.
+func (m *Migrator) Force(_ context.Context, version int) error {
+	return m.run("Force", func() error {
+		return m.migrate.Force(version)
+	})
+}
+
+// Version returns the current schema version and whether the last migration failed halfway.
+// Zero means no migration was applied yet.
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.migrate.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("Version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+// Close releases the connections of the Migrator.
+func (m *Migrator) Close() {
+	m.migrate.Close()
+}
+
+// run runs fn, treating the absence of changes as success.
+func (m *Migrator) run(operation string, fn func() error) error {
+	err := fn()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// LatestMigration returns the version of the latest migration found at the migrations path.
+func LatestMigration(migrationsPath string) (uint, error) {
+	src, err := source.Open(migrationsPath)
+	if err != nil {
+		return 0, fmt.Errorf("LatestMigration: failed to open migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("LatestMigration: failed to read migrations: %w", err)
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("LatestMigration: failed to read migrations: %w", err)
+		}
+
+		version = next
+	}
+}
+
+// migrateLogger reports the progress of the migrations through zap.
+type migrateLogger struct {
+	logger *zap.Logger
+}
+
+// Printf implements migrate.Logger.
+func (l *migrateLogger) Printf(format string, v ...interface{}) {
+	l.logger.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+// Verbose implements migrate.Logger.
+func (l *migrateLogger) Verbose() bool {
+	return false
+}
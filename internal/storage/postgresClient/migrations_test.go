@@ -0,0 +1,72 @@
+package postgresClient
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+// TestMigrationsDownUp rolls back every migration and applies them again. It drops all data of the database,
+// like the integration tests are expected to be run against a scratch one.
+func TestMigrationsDownUp(t *testing.T) {
+	ps := newIntegrationService(t)
+
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	// The same role of the same subject in two tenants cannot be kept once the role assignments lose their tenants.
+	for _, tenantID := range []string{"acme-" + suffix, "globex-" + suffix} {
+		assignment := &api.RoleAssignment{Subject: "alice-" + suffix, Role: "viewer"}
+		if err := ps.SaveRoleAssignment(tenant.WithTenant(ctx, tenantID), assignment); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var config Config
+	if err := cleanenv.ReadEnv(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigrator(ctx, &config, integrationMigrations, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(migrator.Close)
+
+	latest, err := LatestMigration(integrationMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(phase string, want uint) {
+		t.Helper()
+
+		version, dirty, err := migrator.Version()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if version != want || dirty {
+			t.Fatalf("%s: version = %d, dirty = %v, want %d", phase, version, dirty, want)
+		}
+	}
+
+	check("migrated", latest)
+
+	if err = migrator.Down(ctx, int(latest)); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	check("rolled back", 0)
+
+	if err = migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	check("migrated again", latest)
+}
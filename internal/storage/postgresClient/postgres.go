@@ -3,14 +3,11 @@ package postgresClient
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
-	"github.com/golang-migrate/migrate"
-	_ "github.com/golang-migrate/migrate/database/postgres"
-	_ "github.com/golang-migrate/migrate/source/file"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
@@ -19,89 +16,200 @@ import (
 )
 
 // New creates and returns a new PostgresService instance, applies default timeout if not set,
-// establishes a connection pool, and runs the migration located at migrationsPath.
+// establishes a connection pool and, unless auto-migration is disabled, runs the migrations located at migrationsPath.
+// It fails if the tenant-bound transactions would bypass row-level security.
+// If replicas are configured, it connects to them as well and checks their health until Close is called.
 func New(ctx context.Context, config *Config, logger *zap.Logger, migrationsPath string) (*PostgresService, error) {
 	if config.Timeout == 0 {
 		config.Timeout = DefaultPostgresTimeout
 	}
 
-	url := buildURL(config)
+	if config.ReplicaCheckInterval == 0 {
+		config.ReplicaCheckInterval = DefaultReplicaCheckInterval
+	}
+
+	if config.ReadYourWritesWindow == 0 {
+		config.ReadYourWritesWindow = DefaultReadYourWritesWindow
+	}
+
 	dsn := buildDSN(config)
 
-	pool, err := pgxpool.New(ctx, dsn)
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig.ConnConfig.Tracer = newQueryTracer()
 
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	err = upMigration(url, migrationsPath)
+	if config.AutoMigrate {
+		if err = migrateUp(ctx, config, migrationsPath, logger); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
+	if err = checkRowLevelSecurity(ctx, pool, config.TenantRole, config.Timeout); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	version, err := LatestMigration(migrationsPath)
 	if err != nil {
+		pool.Close()
 		return nil, err
 	}
 
-	return &PostgresService{
-		pool:    pool,
-		logger:  logger,
-		timeout: config.Timeout,
-	}, nil
+	replicas, err := newReplicas(ctx, config)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	ps := &PostgresService{
+		pool:           pool,
+		logger:         logger,
+		timeout:        config.Timeout,
+		tenantRole:     config.TenantRole,
+		migration:      version,
+		replicas:       replicas,
+		maxLag:         config.ReplicaMaxLag,
+		readYourWrites: config.ReadYourWrites,
+		pinWindow:      config.ReadYourWritesWindow,
+	}
+
+	if len(replicas) > 0 {
+		ps.startReplicaChecks(ctx, config.ReplicaCheckInterval)
+	}
+
+	return ps, nil
+}
+
+// migrateUp applies the pending migrations found at migrationsPath.
+func migrateUp(ctx context.Context, config *Config, migrationsPath string, logger *zap.Logger) error {
+	migrator, err := NewMigrator(ctx, config, migrationsPath, logger)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if err = migrator.Up(ctx); err != nil {
+		return fmt.Errorf("failed to run migration: %w", err)
+	}
+
+	return nil
 }
 
 // SaveSubscription inserts the given subscription into the database and returns its generated ID.
-func (ps *PostgresService) SaveSubscription(subscription *api.Subscription) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), ps.timeout)
+// The subscription.created event is written to the outbox and the monthly spend rollup is updated in the same transaction.
+func (ps *PostgresService) SaveSubscription(ctx context.Context, subscription *api.Subscription) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
 	defer cancel()
 
 	var id int
 
-	err := ps.pool.QueryRow(ctx, queryForSaveSubscription,
-		subscription.ServiceName,
-		subscription.Price,
-		subscription.UserID,
-		subscription.StartDate,
-		subscription.EndDate,
-	).Scan(&id)
+	ps.pin(ctx)
+
+	err := ps.inTenant(ctx, "SaveSubscription", func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, queryForSaveSubscription,
+			subscription.ServiceName,
+			subscription.Price,
+			subscription.UserID,
+			subscription.StartDate,
+			subscription.EndDate,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		if err := addMonthlySpend(ctx, tx, id, 1); err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, api.EventSubscriptionCreated, id, subscription)
+	})
 	if err != nil {
 		ps.logger.Error("SaveSubscription: failed to save subscription", zap.Error(err))
 		return 0, fmt.Errorf("SaveSubscription: failed to save subscription: %w", err)
 	}
 
+	ps.changed(ctx, subscription)
+
 	return id, nil
 }
 
 // DeleteSubscription deletes a subscription by specified id.
-func (ps *PostgresService) DeleteSubscription(id int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), ps.timeout)
+// The subscription.deleted event is written to the outbox and the monthly spend rollup is updated in the same transaction.
+func (ps *PostgresService) DeleteSubscription(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
 	defer cancel()
 
-	tag, err := ps.pool.Exec(ctx, queryForDeleteSubscription, id)
+	subscription := &api.Subscription{}
+
+	ps.pin(ctx)
+
+	err := ps.inTenant(ctx, "DeleteSubscription", func(tx pgx.Tx) error {
+		if err := addMonthlySpend(ctx, tx, id, -1); err != nil {
+			return err
+		}
+
+		var endDate sql.NullString
+
+		err := tx.QueryRow(ctx, queryForDeleteSubscription, id).Scan(
+			&subscription.ServiceName,
+			&subscription.Price,
+			&subscription.UserID,
+			&subscription.StartDate,
+			&endDate,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrSubscriptionNotFound
+			}
+			return err
+		}
+
+		if endDate.Valid {
+			subscription.EndDate = endDate.String
+		}
+
+		return insertEvent(ctx, tx, api.EventSubscriptionDeleted, id, subscription)
+	})
 	if err != nil {
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			ps.logger.Error(ErrSubscriptionNotFound.Error())
+			return ErrSubscriptionNotFound
+		}
 		ps.logger.Error("DeleteSubscription: failed to delete subscription", zap.Error(err), zap.Int("id", id))
 		return fmt.Errorf("DeleteSubscription: failed to delete subscription: %w", err)
 	}
 
-	if tag.RowsAffected() == 0 {
-		ps.logger.Error(ErrSubscriptionNotFound.Error())
-		return ErrSubscriptionNotFound
-	}
+	ps.changed(ctx, subscription)
 
 	return nil
 }
 
 // GetSubscription return a stored subscription by specified id.
 // If there was no subscription, returns ErrSubscriptionNotFound.
-func (ps *PostgresService) GetSubscription(id int) (*api.Subscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), ps.timeout)
+func (ps *PostgresService) GetSubscription(ctx context.Context, id int) (*api.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
 	defer cancel()
 
 	res := &api.Subscription{}
 
-	err := ps.pool.QueryRow(ctx, queryForGetSubscription, id).Scan(
-		&res.ServiceName,
-		&res.Price,
-		&res.UserID,
-		&res.StartDate,
-		&res.EndDate,
-	)
+	err := ps.readInTenant(ctx, "GetSubscription", func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, queryForGetSubscription, id).Scan(
+			&res.ServiceName,
+			&res.Price,
+			&res.UserID,
+			&res.StartDate,
+			&res.EndDate,
+		)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			ps.logger.Error(ErrSubscriptionNotFound.Error())
@@ -116,93 +224,325 @@ func (ps *PostgresService) GetSubscription(id int) (*api.Subscription, error) {
 
 // ListSubscriptions returns all stored subscriptions.
 // If there were no subscriptions, returns ErrSubscriptionNotFound.
-func (ps *PostgresService) ListSubscriptions() ([]*api.Subscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), ps.timeout)
+func (ps *PostgresService) ListSubscriptions(ctx context.Context) ([]*api.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
 	defer cancel()
 
 	var res []*api.Subscription
 
-	rows, err := ps.pool.Query(ctx, queryForListSubscriptions)
-	defer rows.Close()
+	err := ps.readInTenant(ctx, "ListSubscriptions", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForListSubscriptions)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		res, err = scanSubscriptions(rows)
+		return err
+	})
 	if err != nil {
 		ps.logger.Error("ListSubscription: failed to retrieve subscriptions", zap.Error(err))
 		return nil, fmt.Errorf("ListSubscription: failed to retrieve subscriptions: %w", err)
 	}
 
-	for rows.Next() {
-		var serviceName, userId, startDate string
+	if len(res) == 0 {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	return res, nil
+}
+
+// UpdateSubscription updates specified record by given id.
+// The subscription.updated event is written to the outbox and the monthly spend rollup is updated in the same transaction.
+func (ps *PostgresService) UpdateSubscription(ctx context.Context, id int, subscription *api.Subscription) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	previous := &api.Subscription{}
+
+	ps.pin(ctx)
+
+	err := ps.inTenant(ctx, "UpdateSubscription", func(tx pgx.Tx) error {
+		if err := addMonthlySpend(ctx, tx, id, -1); err != nil {
+			return err
+		}
+
 		var endDate sql.NullString
-		var price int
 
-		err = rows.Scan(&serviceName, &price, &userId, &startDate, &endDate)
+		err := tx.QueryRow(ctx, queryForUpdateSubscription,
+			id, subscription.ServiceName, subscription.Price, subscription.UserID, subscription.StartDate, subscription.EndDate,
+		).Scan(
+			&previous.ServiceName,
+			&previous.Price,
+			&previous.UserID,
+			&previous.StartDate,
+			&endDate,
+		)
 		if err != nil {
-			ps.logger.Error("ListSubscription: failed to fetch subscriptions", zap.Error(err))
-			return nil, fmt.Errorf("ListSubscription: failed to fetch subscriptions: %w", err)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrSubscriptionNotFound
+			}
+			return err
 		}
 
-		subscription := &api.Subscription{
-			ServiceName: serviceName,
-			Price:       price,
-			UserID:      userId,
-			StartDate:   startDate,
+		if endDate.Valid {
+			previous.EndDate = endDate.String
 		}
 
-		if endDate.Valid {
-			subscription.EndDate = endDate.String
+		if err := addMonthlySpend(ctx, tx, id, 1); err != nil {
+			return err
 		}
 
-		res = append(res, subscription)
+		return insertEvent(ctx, tx, api.EventSubscriptionUpdated, id, subscription)
+	})
+	if err != nil {
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			ps.logger.Error(ErrSubscriptionNotFound.Error())
+			return ErrSubscriptionNotFound
+		}
+		ps.logger.Error("UpdateSubscription: failed to update subscription", zap.Error(err))
+		return fmt.Errorf("UpdateSubscription: failed to update subscription: %w", err)
 	}
 
-	if rows.Err() != nil {
-		ps.logger.Error("ListSubscription: rows error", zap.Error(rows.Err()))
-		return nil, fmt.Errorf("ListSubscription: rows error: %w", rows.Err())
+	ps.changed(ctx, previous, subscription)
+
+	return nil
+}
+
+// ListFilteredSubscriptions retrieves a list of subscriptions filtered by user_id and/or service_name.
+func (ps *PostgresService) ListFilteredSubscriptions(ctx context.Context, userID string, serviceName string) ([]*api.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var res []*api.Subscription
+
+	err := ps.readInTenant(ctx, "ListFilteredSubscriptions", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForListFilteredSubscriptions, userID, serviceName)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		res, err = scanSubscriptions(rows)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListFilteredSubscriptions: %w", err)
 	}
 
-	if len(res) == 0 {
-		return nil, ErrSubscriptionNotFound
+	return res, nil
+}
+
+// ListReminderCandidates returns up to limit stored subscriptions of the tenant with ids above afterID, ordered by id,
+// which have a reminder due after now and not after deadline that was not claimed yet: their expiry, or their renewal
+// at nextCharge. The times are compared in UTC, like the months of the subscriptions.
+func (ps *PostgresService) ListReminderCandidates(ctx context.Context, afterID int, now time.Time, deadline time.Time, nextCharge time.Time, limit int) ([]*SubscriptionRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var res []*SubscriptionRecord
+
+	err := ps.inTenant(ctx, "ListReminderCandidates", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForListReminderCandidates, afterID, now.UTC(), deadline.UTC(), nextCharge, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		res, err = scanSubscriptionRecords(rows)
+		return err
+	})
+	if err != nil {
+		ps.logger.Error("ListReminderCandidates: failed to retrieve subscriptions", zap.Error(err))
+		return nil, fmt.Errorf("ListReminderCandidates: failed to retrieve subscriptions: %w", err)
 	}
 
 	return res, nil
 }
 
-// UpdateSubscription updates specified record by given id.
-func (ps *PostgresService) UpdateSubscription(id int, subscription *api.Subscription) error {
-	ctx, cancel := context.WithTimeout(context.Background(), ps.timeout)
+// ListSubscriptionsAfter returns up to limit stored subscriptions of the tenant with ids above afterID, ordered by id.
+// If userID is not empty, only the subscriptions of that user are returned.
+func (ps *PostgresService) ListSubscriptionsAfter(ctx context.Context, afterID int, userID string, limit int) ([]*SubscriptionRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
 	defer cancel()
 
-	tag, err := ps.pool.Exec(ctx, queryForUpdateSubscription,
-		id, subscription.ServiceName, subscription.Price, subscription.UserID, subscription.StartDate, subscription.EndDate,
-	)
+	var res []*SubscriptionRecord
+
+	err := ps.readInTenant(ctx, "ListSubscriptionsAfter", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForListSubscriptionsAfter, afterID, userID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		res, err = scanSubscriptionRecords(rows)
+		return err
+	})
 	if err != nil {
-		ps.logger.Error("UpdateSubscription: failed to update subscription", zap.Error(err))
-		return fmt.Errorf("UpdateSubscription: failed to update subscription: %w", err)
+		return nil, fmt.Errorf("ListSubscriptionsAfter: %w", err)
 	}
 
-	if tag.RowsAffected() == 0 {
-		ps.logger.Error(ErrSubscriptionNotFound.Error())
-		return ErrSubscriptionNotFound
+	return res, nil
+}
+
+// ClaimReminder records that the reminder of the given kind for the given period is being dispatched.
+// Returns false if the reminder was already claimed earlier, so each reminder fires only once.
+func (ps *PostgresService) ClaimReminder(ctx context.Context, subscriptionID int, kind string, period string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var claimed bool
+
+	err := ps.inTenant(ctx, "ClaimReminder", func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryForClaimReminder, subscriptionID, kind, period)
+		claimed = tag.RowsAffected() == 1
+		return err
+	})
+	if err != nil {
+		ps.logger.Error("ClaimReminder: failed to claim reminder", zap.Error(err), zap.Int("id", subscriptionID))
+		return false, fmt.Errorf("ClaimReminder: failed to claim reminder: %w", err)
+	}
+
+	return claimed, nil
+}
+
+// ReleaseReminder removes a previously claimed reminder, so it is dispatched again on the next run.
+func (ps *PostgresService) ReleaseReminder(ctx context.Context, subscriptionID int, kind string, period string) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	err := ps.inTenant(ctx, "ReleaseReminder", func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, queryForReleaseReminder, subscriptionID, kind, period)
+		return err
+	})
+	if err != nil {
+		ps.logger.Error("ReleaseReminder: failed to release reminder", zap.Error(err), zap.Int("id", subscriptionID))
+		return fmt.Errorf("ReleaseReminder: failed to release reminder: %w", err)
 	}
 
 	return nil
 }
 
-// ListFilteredSubscriptions retrieves a list of subscriptions filtered by user_id and/or service_name.
-func (ps *PostgresService) ListFilteredSubscriptions(userID string, serviceName string) ([]*api.Subscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), ps.timeout)
+// Close stops checking the replicas and closes the connection pools.
+func (ps *PostgresService) Close() {
+	if ps.stopChecks != nil {
+		ps.stopChecks()
+		<-ps.checksDone
+	}
+
+	closeReplicas(ps.replicas)
+	ps.pool.Close()
+}
+
+// Ping checks that the database is reachable.
+func (ps *PostgresService) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	if err := ps.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("Ping: %w", err)
+	}
+
+	return nil
+}
+
+// CheckMigrations checks that the database schema is not behind the migrations applied at startup
+// and that no migration was left half-applied.
+func (ps *PostgresService) CheckMigrations(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
 	defer cancel()
 
-	rows, err := ps.pool.Query(ctx, queryForListFilteredSubscriptions, userID, serviceName)
+	var version uint
+	var dirty bool
+
+	err := ps.pool.QueryRow(ctx, queryForMigrationVersion).Scan(&version, &dirty)
 	if err != nil {
-		return nil, fmt.Errorf("ListFilteredSubscriptions: %w", err)
+		return fmt.Errorf("CheckMigrations: failed to read migration version: %w", err)
 	}
-	defer rows.Close()
 
+	if dirty {
+		return fmt.Errorf("CheckMigrations: migration %d is dirty", version)
+	}
+
+	if version < ps.migration {
+		return fmt.Errorf("CheckMigrations: schema version %d is behind %d", version, ps.migration)
+	}
+
+	return nil
+}
+
+// SetQueryObserver sets the observer receiving the durations of all subsequent operations.
+func (ps *PostgresService) SetQueryObserver(observer QueryObserver) {
+	ps.observer = observer
+}
+
+// SetChangeObserver sets the observer notified of all subsequent changes of subscriptions.
+func (ps *PostgresService) SetChangeObserver(observer ChangeObserver) {
+	ps.changes = observer
+}
+
+// Stat returns the statistics of the connection pool.
+func (ps *PostgresService) Stat() *pgxpool.Stat {
+	return ps.pool.Stat()
+}
+
+// ListActiveSubscriptionStats returns the statistics of the subscriptions active in the current month,
+// one entry per tenant having any.
+func (ps *PostgresService) ListActiveSubscriptionStats(ctx context.Context) ([]*ActiveSubscriptionStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var res []*ActiveSubscriptionStats
+
+	err := ps.readInTenant(ctx, "ListActiveSubscriptionStats", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForListActiveSubscriptionStats)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			stats := &ActiveSubscriptionStats{}
+			if err = rows.Scan(&stats.TenantID, &stats.Count, &stats.MonthlyPrice); err != nil {
+				return err
+			}
+
+			res = append(res, stats)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		ps.logger.Error("ListActiveSubscriptionStats: failed to retrieve stats", zap.Error(err))
+		return nil, fmt.Errorf("ListActiveSubscriptionStats: failed to retrieve stats: %w", err)
+	}
+
+	return res, nil
+}
+
+// insertEvent writes a subscription lifecycle event to the outbox within the given transaction.
+func insertEvent(ctx context.Context, tx pgx.Tx, eventType string, id int, subscription *api.Subscription) error {
+	payload, err := json.Marshal(subscription)
+	if err != nil {
+		return fmt.Errorf("failed to encode event payload: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, queryForInsertEvent, eventType, id, payload)
+	if err != nil {
+		return fmt.Errorf("failed to write event to outbox: %w", err)
+	}
+
+	return nil
+}
+
+// scanSubscriptions reads subscriptions from the rows returned by a listing query.
+func scanSubscriptions(rows pgx.Rows) ([]*api.Subscription, error) {
 	var res []*api.Subscription
+
 	for rows.Next() {
 		var subscription api.Subscription
 		var endDate sql.NullString
-		err = rows.Scan(
+		err := rows.Scan(
 			&subscription.ServiceName,
 			&subscription.Price,
 			&subscription.UserID,
@@ -218,12 +558,45 @@ func (ps *PostgresService) ListFilteredSubscriptions(userID string, serviceName
 		}
 		res = append(res, &subscription)
 	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
 	return res, nil
 }
 
-// Close closes a connections pool.
-func (ps *PostgresService) Close() {
-	ps.pool.Close()
+// scanSubscriptionRecords reads the subscription records selected together with their ids and tenants.
+func scanSubscriptionRecords(rows pgx.Rows) ([]*SubscriptionRecord, error) {
+	var res []*SubscriptionRecord
+
+	for rows.Next() {
+		var record SubscriptionRecord
+		var endDate sql.NullString
+		err := rows.Scan(
+			&record.ID,
+			&record.TenantID,
+			&record.Subscription.ServiceName,
+			&record.Subscription.Price,
+			&record.Subscription.UserID,
+			&record.Subscription.StartDate,
+			&endDate,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if endDate.Valid {
+			record.Subscription.EndDate = endDate.String
+		}
+		res = append(res, &record)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return res, nil
 }
 
 // buildURL creates a PostgreSQL URL by specified parameters on Config, for perform migrations.
@@ -253,18 +626,3 @@ func buildDSN(config *Config) string {
 
 	return dsn
 }
-
-// upMigration applies database migrations using the specified file path and connection URL.
-func upMigration(url string, path string) error {
-	migration, err := migrate.New(path, url)
-	if err != nil {
-		return fmt.Errorf("failed to create migration: %w", err)
-	}
-
-	err = migration.Up()
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("failed to run migration: %w", err)
-	}
-
-	return nil
-}
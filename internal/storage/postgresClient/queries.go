@@ -1,13 +1,24 @@
 package postgresClient
 
 const (
+	// queryForSetTenant binds the current transaction to the tenant $1 and, unless $2 is empty, switches it to role $2.
+	queryForSetTenant = `
+	SELECT set_config('app.tenant_id', $1, true), CASE WHEN $2 <> '' THEN set_config('role', $2, true) END`
+
+	// queryForBypassesRowLevelSecurity selects the role $1, or the connected role if $1 is empty, and whether it is
+	// a superuser or has BYPASSRLS, so that the row-level security policies do not apply to it.
+	queryForBypassesRowLevelSecurity = `
+	SELECT rolname, rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = COALESCE(NULLIF($1::text, ''), current_user)`
+
 	// queryForSaveSubscription inserts a new subscription into the database.
 	queryForSaveSubscription = `
 	INSERT INTO schema_subscriptions.subscriptions (service_name, price, user_id, start_date, end_date)
 	VALUES ($1, $2, $3, $4, $5) RETURNING id`
 
-	// queryForDeleteSubscription deletes a subscription record with the given id from the database.
-	queryForDeleteSubscription = `DELETE FROM schema_subscriptions.subscriptions WHERE id = $1`
+	// queryForDeleteSubscription deletes a subscription record with the given id from the database and returns it.
+	queryForDeleteSubscription = `
+	DELETE FROM schema_subscriptions.subscriptions WHERE id = $1
+	RETURNING service_name, price, user_id, start_date, end_date`
 
 	// queryForGetSubscription selects subscription record with the given id from the database.
 	queryForGetSubscription = `
@@ -17,13 +28,335 @@ const (
 	queryForListSubscriptions = `
 	SELECT service_name, price, user_id, start_date, end_date FROM schema_subscriptions.subscriptions`
 
-	// queryForUpdateSubscription updates a subscription record with the given id from the database.
+	// queryForUpdateSubscription updates a subscription record with the given id and returns the record before the update.
 	queryForUpdateSubscription = `
-	UPDATE schema_subscriptions.subscriptions SET service_name=$2, price=$3, user_id=$4, start_date=$5, end_date=$6 WHERE id = $1`
+	UPDATE schema_subscriptions.subscriptions s SET service_name=$2, price=$3, user_id=$4, start_date=$5, end_date=$6
+	FROM schema_subscriptions.subscriptions old WHERE s.id = $1 AND old.id = s.id
+	RETURNING old.service_name, old.price, old.user_id, old.start_date, old.end_date`
 
 	// queryForListFilteredSubscriptions retrieves subscription records filtered by optional user_id and service_name.
 	// If user_id or service_name are empty, the corresponding filter is ignored (i.e., returns all).
 	queryForListFilteredSubscriptions = `
 	SELECT service_name, price, user_id, start_date, end_date 
 	FROM schema_subscriptions.subscriptions WHERE ($1 = '' OR user_id = $1) AND ($2 = '' OR service_name = $2)`
+
+	// queryForListSubscriptionsOfUsers selects the subscription records of any of the users $1.
+	queryForListSubscriptionsOfUsers = `
+	SELECT service_name, price, user_id, start_date, end_date
+	FROM schema_subscriptions.subscriptions WHERE user_id = ANY($1)`
+
+	// queryForListSubscriptionsOfServices selects the subscription records of any of the services $1.
+	queryForListSubscriptionsOfServices = `
+	SELECT service_name, price, user_id, start_date, end_date
+	FROM schema_subscriptions.subscriptions WHERE service_name = ANY($1)`
+
+	// queryForListUserIDs selects the distinct users having subscriptions.
+	queryForListUserIDs = `
+	SELECT DISTINCT user_id FROM schema_subscriptions.subscriptions ORDER BY user_id`
+
+	// queryForListServiceNames selects the distinct services having subscriptions.
+	queryForListServiceNames = `
+	SELECT DISTINCT service_name FROM schema_subscriptions.subscriptions ORDER BY service_name`
+
+	// queryForListReminderCandidates selects, in the order of their ids, up to $5 subscription records with ids above $1
+	// which have a reminder due in ($2, $3] that was not claimed yet: an expiry at the beginning of the month after
+	// end_date, or a renewal at the next charge $4 while the subscription is active.
+	queryForListReminderCandidates = `
+	SELECT s.id, s.tenant_id, s.service_name, s.price, s.user_id, s.start_date, s.end_date
+	FROM schema_subscriptions.subscriptions s
+	WHERE s.id > $1 AND (
+		(s.end_month + interval '1 month' > $2::timestamp AND s.end_month + interval '1 month' <= $3::timestamp
+			AND NOT EXISTS (
+				SELECT 1 FROM schema_subscriptions.reminders r WHERE r.subscription_id = s.id AND r.kind = 'expiry'
+				AND r.due_period = to_char(s.end_month + interval '1 month', 'MM-YYYY')))
+		OR ($4::date > $2::timestamp AND $4::date <= $3::timestamp AND s.start_month <= $4::date
+			AND (coalesce(s.end_date, '') = '' OR s.end_month >= $4::date)
+			AND NOT EXISTS (
+				SELECT 1 FROM schema_subscriptions.reminders r WHERE r.subscription_id = s.id AND r.kind = 'renewal'
+				AND r.due_period = to_char($4::date, 'MM-YYYY'))))
+	ORDER BY s.id LIMIT $5`
+
+	// queryForListSubscriptionsAfter selects, in the order of their ids, up to $3 subscription records with ids above $1,
+	// together with their ids and tenants. If user_id $2 is empty, the records of all users are selected.
+	queryForListSubscriptionsAfter = `
+	SELECT id, tenant_id, service_name, price, user_id, start_date, end_date FROM schema_subscriptions.subscriptions
+	WHERE id > $1 AND ($2 = '' OR user_id = $2) ORDER BY id LIMIT $3`
+
+	// queryForClaimReminder records that a reminder was dispatched, doing nothing if it already was.
+	queryForClaimReminder = `
+	INSERT INTO schema_subscriptions.reminders (subscription_id, kind, due_period, tenant_id)
+	SELECT id, $2, $3, tenant_id FROM schema_subscriptions.subscriptions WHERE id = $1
+	ON CONFLICT DO NOTHING`
+
+	// queryForReleaseReminder removes a reminder record so that it can be dispatched again.
+	queryForReleaseReminder = `
+	DELETE FROM schema_subscriptions.reminders WHERE subscription_id = $1 AND kind = $2 AND due_period = $3`
+
+	// queryForInsertEvent writes a subscription lifecycle event to the outbox.
+	queryForInsertEvent = `
+	INSERT INTO schema_subscriptions.outbox (event_type, subscription_id, payload) VALUES ($1, $2, $3)`
+
+	// queryForEnqueueExpiredEvents writes subscription.expired events to the outbox for subscriptions
+	// whose end_date month has passed, once per end_date, recording the end_date the event was written for.
+	queryForEnqueueExpiredEvents = `
+	WITH expired AS (
+		UPDATE schema_subscriptions.subscriptions SET expired_end_date = end_date
+		WHERE end_month IS NOT NULL AND expired_end_date IS DISTINCT FROM end_date
+		AND end_month < date_trunc('month', now())::date
+		RETURNING id, tenant_id, service_name, price, user_id, start_date, end_date
+	)
+	INSERT INTO schema_subscriptions.outbox (event_type, subscription_id, payload, tenant_id)
+	SELECT 'subscription.expired', id, jsonb_build_object(
+		'service_name', service_name, 'price', price, 'user_id', user_id,
+		'start_date', start_date, 'end_date', end_date), tenant_id
+	FROM expired`
+
+	// queryForSaveWebhook inserts a new webhook into the database.
+	queryForSaveWebhook = `
+	INSERT INTO schema_subscriptions.webhooks (url, secret, events) VALUES ($1, $2, $3) RETURNING id`
+
+	// queryForGetWebhook selects webhook record with the given id from the database.
+	queryForGetWebhook = `
+	SELECT id, url, secret, events FROM schema_subscriptions.webhooks WHERE id = $1`
+
+	// queryForListWebhooks selects all webhook records from the database.
+	queryForListWebhooks = `
+	SELECT id, url, secret, events FROM schema_subscriptions.webhooks ORDER BY id`
+
+	// queryForUpdateWebhook updates a webhook record with the given id.
+	queryForUpdateWebhook = `
+	UPDATE schema_subscriptions.webhooks SET url=$2, secret=$3, events=$4 WHERE id = $1`
+
+	// queryForDeleteWebhook deletes a webhook record with the given id together with its deliveries.
+	queryForDeleteWebhook = `DELETE FROM schema_subscriptions.webhooks WHERE id = $1`
+
+	// queryForListWebhookDeliveries selects the latest deliveries of the webhook with the given id.
+	queryForListWebhookDeliveries = `
+	SELECT d.id, d.webhook_id, d.event_id, e.event_type, d.status, d.attempts, d.status_code, d.last_error,
+		d.next_attempt_at, d.delivered_at, d.created_at
+	FROM schema_subscriptions.webhook_deliveries d
+	JOIN schema_subscriptions.outbox e ON e.id = d.event_id
+	WHERE d.webhook_id = $1 ORDER BY d.id DESC LIMIT $2`
+
+	// queryForEnqueueDeliveries fans out unprocessed outbox events to the matching webhooks of the same tenant
+	// and marks those events as processed.
+	queryForEnqueueDeliveries = `
+	WITH events AS (
+		SELECT id, tenant_id, event_type FROM schema_subscriptions.outbox
+		WHERE processed_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+	), fanout AS (
+		INSERT INTO schema_subscriptions.webhook_deliveries (webhook_id, event_id, tenant_id)
+		SELECT w.id, e.id, e.tenant_id FROM events e
+		JOIN schema_subscriptions.webhooks w
+		ON w.tenant_id = e.tenant_id AND (cardinality(w.events) = 0 OR e.event_type = ANY(w.events))
+	)
+	UPDATE schema_subscriptions.outbox o SET processed_at = now() FROM events e WHERE o.id = e.id`
+
+	// queryForClaimDeliveries selects pending deliveries which are due and postpones them by the given lease,
+	// so that concurrent dispatchers do not send them twice.
+	queryForClaimDeliveries = `
+	WITH due AS (
+		SELECT id FROM schema_subscriptions.webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= now()
+		ORDER BY next_attempt_at LIMIT $1 FOR UPDATE SKIP LOCKED
+	)
+	UPDATE schema_subscriptions.webhook_deliveries d SET next_attempt_at = now() + make_interval(secs => $2)
+	FROM due, schema_subscriptions.webhooks w, schema_subscriptions.outbox e
+	WHERE d.id = due.id AND w.id = d.webhook_id AND e.id = d.event_id
+	RETURNING d.id, d.attempts, w.url, w.secret, e.id, e.tenant_id, e.event_type, e.subscription_id, e.payload, e.created_at`
+
+	// queryForCompleteDelivery marks a delivery as delivered.
+	queryForCompleteDelivery = `
+	UPDATE schema_subscriptions.webhook_deliveries
+	SET status = 'delivered', attempts = attempts + 1, status_code = $2, last_error = NULL, delivered_at = now()
+	WHERE id = $1`
+
+	// queryForFailDelivery records a failed delivery attempt and schedules the next one.
+	queryForFailDelivery = `
+	UPDATE schema_subscriptions.webhook_deliveries
+	SET status = $2, attempts = attempts + 1, status_code = $3, last_error = $4, next_attempt_at = $5
+	WHERE id = $1`
+
+	// queryForListenEvents subscribes the connection to notifications about new outbox events.
+	queryForListenEvents = `LISTEN subscription_events`
+
+	// queryForGetEvent selects the outbox event with the given id.
+	queryForGetEvent = `
+	SELECT id, tenant_id, event_type, subscription_id, payload, created_at FROM schema_subscriptions.outbox WHERE id = $1`
+
+	// queryForListEventsAfter selects up to $3 outbox events following the given id,
+	// optionally filtered by user_id (an empty user_id returns events of all users).
+	queryForListEventsAfter = `
+	SELECT id, tenant_id, event_type, subscription_id, payload, created_at FROM schema_subscriptions.outbox
+	WHERE id > $1 AND ($2 = '' OR payload->>'user_id' = $2) ORDER BY id LIMIT $3`
+
+	// queryForEventReplayStart selects the id preceding the oldest outbox event with an id not above $1
+	// created at most $2 before the event $1, or $1 if there is none.
+	queryForEventReplayStart = `
+	SELECT COALESCE(MIN(id), $1 + 1) - 1 FROM schema_subscriptions.outbox
+	WHERE id <= $1 AND created_at >= (SELECT created_at FROM schema_subscriptions.outbox WHERE id = $1) - $2::interval`
+
+	// queryForPruneEvents deletes up to $3 outbox events created more than $1 ago which have no pending
+	// webhook deliveries, together with their deliveries. Unless $2 is set, the events not fanned out to
+	// the webhooks yet are kept.
+	queryForPruneEvents = `
+	DELETE FROM schema_subscriptions.outbox WHERE id IN (
+		SELECT o.id FROM schema_subscriptions.outbox o
+		WHERE o.created_at < now() - $1::interval AND ($2::boolean OR o.processed_at IS NOT NULL)
+		AND NOT EXISTS (
+			SELECT 1 FROM schema_subscriptions.webhook_deliveries d WHERE d.event_id = o.id AND d.status = 'pending')
+		ORDER BY o.created_at LIMIT $3)`
+
+	// queryForSaveAPIKey inserts a new API key into the database.
+	queryForSaveAPIKey = `
+	INSERT INTO schema_subscriptions.api_keys (name, prefix, key_hash, scopes) VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at`
+
+	// queryForAuthenticateAPIKey selects the active API key with the given hash and records its usage.
+	queryForAuthenticateAPIKey = `
+	UPDATE schema_subscriptions.api_keys SET last_used_at = now()
+	WHERE key_hash = $1 AND revoked_at IS NULL
+	RETURNING id, tenant_id, name, prefix, scopes, created_at, last_used_at`
+
+	// queryForGetAPIKey selects API key record with the given id from the database.
+	queryForGetAPIKey = `
+	SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM schema_subscriptions.api_keys WHERE id = $1`
+
+	// queryForListAPIKeys selects all API key records from the database.
+	queryForListAPIKeys = `
+	SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM schema_subscriptions.api_keys ORDER BY id`
+
+	// queryForRevokeAPIKey revokes the active API key with the given id.
+	queryForRevokeAPIKey = `
+	UPDATE schema_subscriptions.api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`
+
+	// queryForSaveRoleAssignment grants a role to a subject, doing nothing if it was already granted.
+	queryForSaveRoleAssignment = `
+	INSERT INTO schema_subscriptions.role_assignments (subject, role) VALUES ($1, $2)
+	ON CONFLICT (tenant_id, subject, role) DO UPDATE SET subject = EXCLUDED.subject
+	RETURNING created_at`
+
+	// queryForDeleteRoleAssignment revokes a role from a subject.
+	queryForDeleteRoleAssignment = `
+	DELETE FROM schema_subscriptions.role_assignments WHERE subject = $1 AND role = $2`
+
+	// queryForListRoleAssignments selects role assignments, optionally filtered by subject.
+	queryForListRoleAssignments = `
+	SELECT subject, role, created_at FROM schema_subscriptions.role_assignments
+	WHERE ($1 = '' OR subject = $1) ORDER BY subject, role`
+
+	// queryForTakeRateLimitToken refills the token bucket with the given key at $3 tokens per second
+	// up to $2 tokens and takes a token from it if there is one.
+	queryForTakeRateLimitToken = `
+	INSERT INTO schema_subscriptions.rate_limits AS b (key, tokens, allowed, updated_at)
+	VALUES ($1, $2::float8 - 1, true, now())
+	ON CONFLICT (key) DO UPDATE SET
+		tokens = LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * $3::float8)
+			- CASE WHEN LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * $3::float8) >= 1
+				THEN 1 ELSE 0 END,
+		allowed = LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * $3::float8) >= 1,
+		updated_at = now()
+	RETURNING tokens, allowed`
+
+	// queryForDeleteRateLimitsBefore removes the token buckets which were not used since the given time.
+	queryForDeleteRateLimitsBefore = `
+	DELETE FROM schema_subscriptions.rate_limits WHERE updated_at < $1`
+
+	// queryForListActiveSubscriptionStats counts the subscriptions active in the current month and sums their prices
+	// per tenant.
+	queryForListActiveSubscriptionStats = `
+	SELECT tenant_id, count(*), COALESCE(sum(price), 0) FROM schema_subscriptions.subscriptions
+	WHERE to_date(start_date, 'MM-YYYY') <= date_trunc('month', now())
+	AND (NULLIF(end_date, '') IS NULL OR to_date(end_date, 'MM-YYYY') >= date_trunc('month', now()))
+	GROUP BY tenant_id`
+
+	// queryForAddMonthlySpend adds $2 times the price of the subscription with id $1 to the monthly spend of its months
+	// covered by the rollup of its tenant. Tenants without a rollup are left alone.
+	queryForAddMonthlySpend = `
+	INSERT INTO schema_subscriptions.monthly_spend AS r (tenant_id, user_id, service_name, month, amount)
+	SELECT s.tenant_id, COALESCE(s.user_id, ''), COALESCE(s.service_name, ''), m.month, $2 * s.price
+	FROM schema_subscriptions.subscriptions s
+	JOIN schema_subscriptions.monthly_spend_coverage c ON c.tenant_id = s.tenant_id
+	CROSS JOIN schema_subscriptions.subscription_months(s.start_date, s.end_date, c.through) AS m(month)
+	WHERE s.id = $1
+	ON CONFLICT (tenant_id, user_id, service_name, month) DO UPDATE SET amount = r.amount + excluded.amount`
+
+	// queryForLockSubscriptionChanges takes the lock the changes of the subscriptions take anyway, ahead of the change.
+	queryForLockSubscriptionChanges = `LOCK TABLE schema_subscriptions.subscriptions IN ROW EXCLUSIVE MODE`
+
+	// queryForLockMonthlySpend blocks the changes of the subscriptions until the rollup is rebuilt or extended,
+	// so no change is counted twice or missed.
+	queryForLockMonthlySpend = `LOCK TABLE schema_subscriptions.subscriptions IN SHARE MODE`
+
+	// queryForDeleteMonthlySpend removes the monthly spend rollup.
+	queryForDeleteMonthlySpend = `DELETE FROM schema_subscriptions.monthly_spend`
+
+	// queryForDeleteMonthlySpendCoverage removes the coverage of the monthly spend rollup.
+	queryForDeleteMonthlySpendCoverage = `DELETE FROM schema_subscriptions.monthly_spend_coverage`
+
+	// queryForExtendMonthlySpend rolls up the months of the subscriptions through $1 which are not covered yet:
+	// those after the coverage of their tenant, or all of them if the tenant has no rollup.
+	queryForExtendMonthlySpend = `
+	INSERT INTO schema_subscriptions.monthly_spend AS r (tenant_id, user_id, service_name, month, amount)
+	SELECT s.tenant_id, COALESCE(s.user_id, ''), COALESCE(s.service_name, ''), m.month, sum(s.price)
+	FROM schema_subscriptions.subscriptions s
+	LEFT JOIN schema_subscriptions.monthly_spend_coverage c ON c.tenant_id = s.tenant_id
+	CROSS JOIN schema_subscriptions.subscription_months(s.start_date, s.end_date, $1::date) AS m(month)
+	WHERE s.tenant_id <> '*' AND (c.through IS NULL OR m.month > c.through)
+	GROUP BY 1, 2, 3, 4
+	ON CONFLICT (tenant_id, user_id, service_name, month) DO UPDATE SET amount = r.amount + excluded.amount`
+
+	// queryForExtendMonthlySpendCoverage extends the coverage of the rollup through $1 for the tenants of the subscriptions
+	// and the tenant of the transaction.
+	queryForExtendMonthlySpendCoverage = `
+	INSERT INTO schema_subscriptions.monthly_spend_coverage AS c (tenant_id, through)
+	SELECT tenant_id, $1::date FROM (
+		SELECT tenant_id FROM schema_subscriptions.subscriptions
+		UNION SELECT current_setting('app.tenant_id')
+	) t WHERE tenant_id <> '*'
+	ON CONFLICT (tenant_id) DO UPDATE SET through = excluded.through, refreshed_at = now() WHERE c.through < excluded.through`
+
+	// queryForGetMonthlySpendCoverage selects the last month covered by the rollup of the tenant of the transaction.
+	queryForGetMonthlySpendCoverage = `
+	SELECT through FROM schema_subscriptions.monthly_spend_coverage WHERE tenant_id = current_setting('app.tenant_id')`
+
+	// queryForGetMonthlySpend sums the monthly spend of the months from $3 through $4, filtered by optional user_id $1
+	// and service_name $2. Months without spend are included with zero.
+	queryForGetMonthlySpend = `
+	SELECT m.month::date, COALESCE(sum(r.amount), 0)::bigint
+	FROM generate_series($3::timestamp, $4::timestamp, interval '1 month') AS m(month)
+	LEFT JOIN schema_subscriptions.monthly_spend r
+	ON r.month = m.month::date AND ($1 = '' OR r.user_id = $1) AND ($2 = '' OR r.service_name = $2)
+	GROUP BY m.month ORDER BY m.month`
+
+	// queryForListMonthlySpendOfUsers sums the monthly spend of each of the users $1 for the months from $2 through $3.
+	// Months without spend are included with zero.
+	queryForListMonthlySpendOfUsers = `
+	SELECT k.key, m.month::date, COALESCE(sum(r.amount), 0)::bigint
+	FROM unnest($1::text[]) AS k(key)
+	CROSS JOIN generate_series($2::timestamp, $3::timestamp, interval '1 month') AS m(month)
+	LEFT JOIN schema_subscriptions.monthly_spend r ON r.month = m.month::date AND r.user_id = k.key
+	GROUP BY k.key, m.month ORDER BY k.key, m.month`
+
+	// queryForListMonthlySpendOfServices sums the monthly spend of each of the services $1 for the months from $2
+	// through $3, filtered by optional user_id $4. Months without spend are included with zero.
+	queryForListMonthlySpendOfServices = `
+	SELECT k.key, m.month::date, COALESCE(sum(r.amount), 0)::bigint
+	FROM unnest($1::text[]) AS k(key)
+	CROSS JOIN generate_series($2::timestamp, $3::timestamp, interval '1 month') AS m(month)
+	LEFT JOIN schema_subscriptions.monthly_spend r
+	ON r.month = m.month::date AND r.service_name = k.key AND ($4 = '' OR r.user_id = $4)
+	GROUP BY k.key, m.month ORDER BY k.key, m.month`
+
+	// queryForReplicaLag selects how many seconds the replica is behind the primary: zero if it has replayed all the WAL
+	// it received or is not in recovery at all, otherwise the age of the last replayed transaction.
+	queryForReplicaLag = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END::float8`
+
+	// queryForMigrationVersion selects the schema version recorded by the migrations.
+	queryForMigrationVersion = `
+	SELECT version, dirty FROM schema_migrations`
 )
@@ -0,0 +1,39 @@
+package postgresClient
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TakeRateLimitToken refills the token bucket with the given key at rate tokens per second up to capacity
+// and takes a token from it if there is one. Returns the tokens left and whether a token was taken.
+// Buckets are shared by all tenants, so the operation is not bound to a tenant.
+func (ps *PostgresService) TakeRateLimitToken(ctx context.Context, key string, capacity float64, rate float64) (tokens float64, allowed bool, err error) {
+	defer ps.observe("TakeRateLimitToken", time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	err = ps.pool.QueryRow(ctx, queryForTakeRateLimitToken, key, capacity, rate).Scan(&tokens, &allowed)
+	if err != nil {
+		return 0, false, fmt.Errorf("TakeRateLimitToken: failed to take token: %w", err)
+	}
+
+	return tokens, allowed, nil
+}
+
+// DeleteRateLimitsBefore removes the token buckets which were not used since the given time.
+func (ps *PostgresService) DeleteRateLimitsBefore(ctx context.Context, before time.Time) (err error) {
+	defer ps.observe("DeleteRateLimitsBefore", time.Now(), &err)
+
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	_, err = ps.pool.Exec(ctx, queryForDeleteRateLimitsBefore, before)
+	if err != nil {
+		return fmt.Errorf("DeleteRateLimitsBefore: failed to delete buckets: %w", err)
+	}
+
+	return nil
+}
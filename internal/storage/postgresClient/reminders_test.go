@@ -0,0 +1,87 @@
+package postgresClient
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+func TestListReminderCandidates(t *testing.T) {
+	ps := newIntegrationService(t)
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	ctx := tenant.WithTenant(context.Background(), "reminders-"+suffix)
+
+	save := func(startDate string, endDate string) int {
+		t.Helper()
+
+		id, err := ps.SaveSubscription(ctx, &api.Subscription{ServiceName: "Netflix", Price: 400, UserID: "alice", StartDate: startDate, EndDate: endDate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if err := ps.DeleteSubscription(ctx, id); err != nil {
+				t.Errorf("DeleteSubscription() error = %v", err)
+			}
+		})
+
+		return id
+	}
+
+	renewing := save("01-2024", "")
+	expiring := save("06-2024", "01-2025")
+	ending := save("06-2024", "02-2025")
+	starting := save("02-2025", "")
+	save("03-2025", "")
+	save("06-2024", "12-2024")
+
+	now := time.Date(2025, 1, 28, 12, 0, 0, 0, time.UTC)
+	deadline := now.Add(7 * 24 * time.Hour)
+	next := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	list := func(afterID int, limit int) []int {
+		t.Helper()
+
+		records, err := ps.ListReminderCandidates(ctx, afterID, now, deadline, next, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []int
+		for _, record := range records {
+			ids = append(ids, record.ID)
+		}
+
+		return ids
+	}
+
+	if got, want := list(0, 10), []int{renewing, expiring, ending, starting}; !slices.Equal(got, want) {
+		t.Fatalf("candidates = %v, want %v", got, want)
+	}
+
+	if got, want := list(expiring, 1), []int{ending}; !slices.Equal(got, want) {
+		t.Errorf("candidates after %d = %v, want %v", expiring, got, want)
+	}
+
+	// Claimed reminders are not due anymore, so the subscriptions without another one are left out.
+	claim := func(id int, kind string, period string) {
+		t.Helper()
+
+		if _, err := ps.ClaimReminder(ctx, id, kind, period); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	claim(renewing, "renewal", "02-2025")
+	claim(expiring, "expiry", "02-2025")
+	claim(ending, "expiry", "03-2025")
+
+	if got, want := list(0, 10), []int{ending, starting}; !slices.Equal(got, want) {
+		t.Errorf("candidates after the claims = %v, want %v", got, want)
+	}
+}
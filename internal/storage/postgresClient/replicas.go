@@ -0,0 +1,230 @@
+package postgresClient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// pinKey is the context key of the pin of a request to the primary.
+type pinKey struct{}
+
+// primaryKey is the context key marking the contexts whose reads always go to the primary.
+type primaryKey struct{}
+
+// readPin pins the reads of a request to the primary until the pin of an earlier write of its client expires,
+// and from the first write of the request on.
+type readPin struct {
+	until   time.Time
+	wrote   atomic.Bool
+	onWrite func(token string, expires time.Time)
+}
+
+// WithPrimary returns a copy of ctx whose reads always go to the primary, for the results which must not lag behind it,
+// such as those which are cached until a write invalidates them.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// WithReadYourWrites returns a copy of ctx which is pinned to the primary as soon as a write is made with it,
+// so the reads made with it afterwards see the write even if the replicas lag behind. It takes effect only if
+// Config.ReadYourWrites is set; the contexts of a request should derive from one made by it.
+// The pin outlives the request: on the first write, onWrite is called with a token the client should send
+// with its next requests until it expires, and token is the one it sent, if any, pinning them in turn.
+// Malformed tokens and tokens expiring later than the window of a new write are ignored.
+func WithReadYourWrites(ctx context.Context, token string, onWrite func(token string, expires time.Time)) context.Context {
+	pin := &readPin{onWrite: onWrite}
+
+	if ms, err := strconv.ParseInt(token, 10, 64); err == nil {
+		pin.until = time.UnixMilli(ms)
+	}
+
+	return context.WithValue(ctx, pinKey{}, pin)
+}
+
+// pin pins ctx to the primary if it was made by WithReadYourWrites, handing the token of the pin
+// to its client on the first write.
+func (ps *PostgresService) pin(ctx context.Context) {
+	pin, ok := ctx.Value(pinKey{}).(*readPin)
+	if !ok || pin.wrote.Swap(true) || !ps.readYourWrites || pin.onWrite == nil {
+		return
+	}
+
+	expires := time.Now().Add(ps.pinWindow)
+	pin.onWrite(strconv.FormatInt(expires.UnixMilli(), 10), expires)
+}
+
+// pinned reports whether the reads made with ctx must go to the primary.
+func (ps *PostgresService) pinned(ctx context.Context) bool {
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return true
+	}
+
+	if !ps.readYourWrites {
+		return false
+	}
+
+	pin, ok := ctx.Value(pinKey{}).(*readPin)
+	if !ok {
+		return false
+	}
+
+	if pin.wrote.Load() {
+		return true
+	}
+
+	now := time.Now()
+	return now.Before(pin.until) && !pin.until.After(now.Add(ps.pinWindow))
+}
+
+// newReplicas connects to the read replicas, sizing their pools like the pool of the primary.
+// The replicas are not reached yet, so a replica which is down does not prevent the service from starting.
+func newReplicas(ctx context.Context, config *Config) ([]*replica, error) {
+	res := make([]*replica, 0, len(config.Replicas))
+
+	for i, dsn := range config.Replicas {
+		poolConfig, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			closeReplicas(res)
+			return nil, fmt.Errorf("invalid replica %d: %w", i+1, err)
+		}
+
+		poolConfig.MaxConns = int32(config.MaxConns)
+		poolConfig.MinConns = int32(config.MinConns)
+		poolConfig.ConnConfig.Tracer = newQueryTracer()
+
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err != nil {
+			closeReplicas(res)
+			return nil, fmt.Errorf("failed to connect to replica %d: %w", i+1, err)
+		}
+
+		// Replicas are assumed healthy until the first check, so the ones failing it are logged.
+		r := &replica{name: net.JoinHostPort(poolConfig.ConnConfig.Host, fmt.Sprint(poolConfig.ConnConfig.Port)), pool: pool}
+		r.healthy.Store(true)
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// closeReplicas closes the pools of the replicas.
+func closeReplicas(replicas []*replica) {
+	for _, r := range replicas {
+		r.pool.Close()
+	}
+}
+
+// startReplicaChecks checks the health of the replicas right away and then every interval, until Close is called.
+func (ps *PostgresService) startReplicaChecks(ctx context.Context, interval time.Duration) {
+	ps.checkReplicas(ctx)
+
+	ctx, ps.stopChecks = context.WithCancel(context.Background())
+	ps.checksDone = make(chan struct{})
+
+	go func() {
+		defer close(ps.checksDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ps.checkReplicas(ctx)
+			}
+		}
+	}()
+}
+
+// checkReplicas checks the health of every replica. A replica is healthy if it answers and, if a maximum lag
+// is configured, is not behind the primary by more than it.
+func (ps *PostgresService) checkReplicas(ctx context.Context) {
+	for _, r := range ps.replicas {
+		ps.checkReplica(ctx, r)
+	}
+}
+
+// checkReplica checks the health of the replica, logging the changes of its health.
+func (ps *PostgresService) checkReplica(ctx context.Context, r *replica) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var lag float64
+
+	err := r.pool.QueryRow(ctx, queryForReplicaLag).Scan(&lag)
+	if err == nil && ps.maxLag > 0 && lag > ps.maxLag.Seconds() {
+		err = fmt.Errorf("replica is %.1fs behind the primary", lag)
+	}
+
+	healthy := err == nil
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	if healthy {
+		ps.logger.Info("replica is healthy", zap.String("replica", r.name))
+	} else {
+		ps.logger.Warn("replica is unhealthy", zap.String("replica", r.name), zap.Error(err))
+	}
+}
+
+// replica returns the next healthy replica the reads made with ctx may go to, or nil if they must go to the primary.
+func (ps *PostgresService) replica(ctx context.Context) *replica {
+	if len(ps.replicas) == 0 || ps.pinned(ctx) {
+		return nil
+	}
+
+	start := ps.next.Add(1)
+	for i := range uint64(len(ps.replicas)) {
+		r := ps.replicas[(start+i)%uint64(len(ps.replicas))]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// readInTenant is inTenant for the read-only operations: it runs fn in a read-only transaction on a healthy replica,
+// if there is one. If the replica cannot be reached, it is skipped until its next successful health check and fn runs
+// on the primary instead. Once fn has run, its errors are returned as they are, since it may have collected
+// part of its results.
+func (ps *PostgresService) readInTenant(ctx context.Context, operation string, fn func(pgx.Tx) error) (err error) {
+	r := ps.replica(ctx)
+	if r == nil {
+		return ps.inTenant(ctx, operation, fn)
+	}
+
+	defer ps.observe(operation, time.Now(), &err)
+
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	ran := false
+
+	err = begin(ctx, r.pool, tenantID, ps.tenantRole, pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		ran = true
+		return fn(tx)
+	})
+	if err == nil || ran || ctx.Err() != nil {
+		return err
+	}
+
+	if r.healthy.Swap(false) {
+		ps.logger.Warn("replica is unhealthy", zap.String("replica", r.name), zap.Error(err))
+	}
+
+	return begin(ctx, ps.pool, tenantID, ps.tenantRole, pgx.TxOptions{}, fn)
+}
@@ -0,0 +1,242 @@
+package postgresClient
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/tenant"
+)
+
+// newReplicaService returns a PostgresService with healthy replicas of the given names, which are never connected to.
+func newReplicaService(readYourWrites bool, names ...string) *PostgresService {
+	ps := &PostgresService{logger: zap.NewNop(), readYourWrites: readYourWrites, pinWindow: time.Minute}
+
+	for _, name := range names {
+		r := &replica{name: name}
+		r.healthy.Store(true)
+		ps.replicas = append(ps.replicas, r)
+	}
+
+	return ps
+}
+
+// route returns the name of the replica the reads made with ctx go to, or "primary".
+func route(ps *PostgresService, ctx context.Context) string {
+	if r := ps.replica(ctx); r != nil {
+		return r.name
+	}
+
+	return "primary"
+}
+
+func TestReplicaRouting(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		replicas  []string
+		unhealthy []int
+		ctx       context.Context
+		want      []string
+	}{
+		{
+			name: "without replicas",
+			ctx:  ctx,
+			want: []string{"primary", "primary"},
+		},
+		{
+			name:     "round robin",
+			replicas: []string{"a", "b"},
+			ctx:      ctx,
+			want:     []string{"b", "a", "b", "a"},
+		},
+		{
+			name:      "unhealthy replica is skipped",
+			replicas:  []string{"a", "b", "c"},
+			unhealthy: []int{1},
+			ctx:       ctx,
+			want:      []string{"c", "c", "a", "c"},
+		},
+		{
+			name:      "primary without healthy replicas",
+			replicas:  []string{"a", "b"},
+			unhealthy: []int{0, 1},
+			ctx:       ctx,
+			want:      []string{"primary", "primary"},
+		},
+		{
+			name:     "primary reads",
+			replicas: []string{"a", "b"},
+			ctx:      WithPrimary(ctx),
+			want:     []string{"primary", "primary"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := newReplicaService(false, tt.replicas...)
+			for _, i := range tt.unhealthy {
+				ps.replicas[i].healthy.Store(false)
+			}
+
+			for i, want := range tt.want {
+				if got := route(ps, tt.ctx); got != want {
+					t.Errorf("read %d went to %s, want %s", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadYourWrites(t *testing.T) {
+	token := func(d time.Duration) string {
+		return strconv.FormatInt(time.Now().Add(d).UnixMilli(), 10)
+	}
+
+	tests := []struct {
+		name           string
+		readYourWrites bool
+		token          string
+		write          bool
+		want           string
+		wantPin        bool
+	}{
+		{
+			name: "without a write",
+			want: "a",
+		},
+		{
+			name:  "write while disabled",
+			write: true,
+			want:  "a",
+		},
+		{
+			name:           "write",
+			readYourWrites: true,
+			write:          true,
+			want:           "primary",
+			wantPin:        true,
+		},
+		{
+			name:           "pin of an earlier write",
+			readYourWrites: true,
+			token:          token(30 * time.Second),
+			want:           "primary",
+		},
+		{
+			name:  "pin of an earlier write while disabled",
+			token: token(30 * time.Second),
+			want:  "a",
+		},
+		{
+			name:           "expired pin",
+			readYourWrites: true,
+			token:          token(-time.Second),
+			want:           "a",
+		},
+		{
+			name:           "pin beyond the window",
+			readYourWrites: true,
+			token:          token(time.Hour),
+			want:           "a",
+		},
+		{
+			name:           "malformed pin",
+			readYourWrites: true,
+			token:          "forever",
+			want:           "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := newReplicaService(tt.readYourWrites, "a")
+
+			var pins []time.Time
+			ctx := WithReadYourWrites(context.Background(), tt.token, func(token string, expires time.Time) {
+				if token != strconv.FormatInt(expires.UnixMilli(), 10) {
+					t.Errorf("pin token = %q, want the expiry %s", token, expires)
+				}
+				pins = append(pins, expires)
+			})
+
+			if tt.write {
+				ps.pin(ctx)
+				ps.pin(ctx)
+			}
+
+			if got := route(ps, ctx); got != tt.want {
+				t.Errorf("read went to %s, want %s", got, tt.want)
+			}
+
+			if !tt.wantPin {
+				if len(pins) != 0 {
+					t.Errorf("pins = %v, want none", pins)
+				}
+				return
+			}
+
+			// The client is pinned once, for the window.
+			if len(pins) != 1 || time.Until(pins[0]) <= 0 || time.Until(pins[0]) > ps.pinWindow {
+				t.Fatalf("pins = %v, want one within %s", pins, ps.pinWindow)
+			}
+
+			// The next request of the client carrying the token reads from the primary as well.
+			next := WithReadYourWrites(context.Background(), strconv.FormatInt(pins[0].UnixMilli(), 10), nil)
+			if got := route(ps, next); got != "primary" {
+				t.Errorf("read of the next request went to %s, want primary", got)
+			}
+		})
+	}
+}
+
+func TestReadInTenantFallback(t *testing.T) {
+	// Nothing listens on the port, so the connections are refused.
+	unreachable := func() *pgxpool.Pool {
+		t.Helper()
+
+		pool, err := pgxpool.New(context.Background(), "postgres://subscriptions@127.0.0.1:1/subscriptions?connect_timeout=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(pool.Close)
+
+		return pool
+	}
+
+	ps := newReplicaService(false, "a")
+	ps.pool = unreachable()
+	ps.replicas[0].pool = unreachable()
+
+	ctx := tenant.WithTenant(context.Background(), "acme")
+
+	var attempts []string
+	err := ps.readInTenant(ctx, "Test", func(pgx.Tx) error {
+		attempts = append(attempts, "ran")
+		return nil
+	})
+
+	// The read failed over to the primary, which is unreachable as well.
+	if err == nil || len(attempts) != 0 {
+		t.Fatalf("readInTenant() error = %v, attempts = %v, want the connection error of the primary", err, attempts)
+	}
+
+	if ps.replicas[0].healthy.Load() {
+		t.Error("replica is healthy after failing to connect, want it skipped until the next check")
+	}
+
+	if got := route(ps, ctx); got != "primary" {
+		t.Errorf("next read went to %s, want primary", got)
+	}
+
+	if err = ps.readInTenant(context.Background(), "Test", func(pgx.Tx) error { return nil }); !errors.Is(err, ErrTenantRequired) {
+		t.Errorf("readInTenant() without a tenant error = %v, want %v", err, ErrTenantRequired)
+	}
+}
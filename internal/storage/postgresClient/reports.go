@@ -0,0 +1,91 @@
+package postgresClient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"subscriptions/internal/api"
+)
+
+// ListSubscriptionsOfUsers returns the subscriptions of any of the given users, so they are loaded in a single query.
+func (ps *PostgresService) ListSubscriptionsOfUsers(ctx context.Context, userIDs []string) ([]*api.Subscription, error) {
+	res, err := ps.listSubscriptionsOf(ctx, "ListSubscriptionsOfUsers", queryForListSubscriptionsOfUsers, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("ListSubscriptionsOfUsers: %w", err)
+	}
+
+	return res, nil
+}
+
+// ListSubscriptionsOfServices returns the subscriptions of any of the given services, so they are loaded in a single query.
+func (ps *PostgresService) ListSubscriptionsOfServices(ctx context.Context, serviceNames []string) ([]*api.Subscription, error) {
+	res, err := ps.listSubscriptionsOf(ctx, "ListSubscriptionsOfServices", queryForListSubscriptionsOfServices, serviceNames)
+	if err != nil {
+		return nil, fmt.Errorf("ListSubscriptionsOfServices: %w", err)
+	}
+
+	return res, nil
+}
+
+// ListUserIDs returns the users having subscriptions, in alphabetical order.
+func (ps *PostgresService) ListUserIDs(ctx context.Context) ([]string, error) {
+	res, err := ps.listStrings(ctx, "ListUserIDs", queryForListUserIDs)
+	if err != nil {
+		return nil, fmt.Errorf("ListUserIDs: %w", err)
+	}
+
+	return res, nil
+}
+
+// ListServiceNames returns the services having subscriptions, in alphabetical order.
+func (ps *PostgresService) ListServiceNames(ctx context.Context) ([]string, error) {
+	res, err := ps.listStrings(ctx, "ListServiceNames", queryForListServiceNames)
+	if err != nil {
+		return nil, fmt.Errorf("ListServiceNames: %w", err)
+	}
+
+	return res, nil
+}
+
+// listSubscriptionsOf runs a listing query of the subscriptions matching any of the given values.
+func (ps *PostgresService) listSubscriptionsOf(ctx context.Context, operation string, query string, values []string) ([]*api.Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var res []*api.Subscription
+
+	err := ps.readInTenant(ctx, operation, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, values)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		res, err = scanSubscriptions(rows)
+		return err
+	})
+
+	return res, err
+}
+
+// listStrings runs a query selecting a single text column.
+func (ps *PostgresService) listStrings(ctx context.Context, operation string, query string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var res []string
+
+	err := ps.readInTenant(ctx, operation, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		res, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		return err
+	})
+
+	return res, err
+}
@@ -0,0 +1,93 @@
+package postgresClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+)
+
+// SaveRoleAssignment grants the role to the subject, filling in the time it was granted at.
+// Granting an already granted role is not an error.
+func (ps *PostgresService) SaveRoleAssignment(ctx context.Context, assignment *api.RoleAssignment) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	err := ps.inTenant(ctx, "SaveRoleAssignment", func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, queryForSaveRoleAssignment, assignment.Subject, assignment.Role).Scan(&assignment.CreatedAt)
+	})
+	if err != nil {
+		ps.logger.Error("SaveRoleAssignment: failed to save role assignment", zap.Error(err))
+		return fmt.Errorf("SaveRoleAssignment: failed to save role assignment: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRoleAssignment revokes the role from the subject.
+// If the role was not granted, returns ErrRoleAssignmentNotFound.
+func (ps *PostgresService) DeleteRoleAssignment(ctx context.Context, subject string, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	err := ps.inTenant(ctx, "DeleteRoleAssignment", func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryForDeleteRoleAssignment, subject, role)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrRoleAssignmentNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrRoleAssignmentNotFound) {
+			ps.logger.Error(ErrRoleAssignmentNotFound.Error())
+			return ErrRoleAssignmentNotFound
+		}
+		ps.logger.Error("DeleteRoleAssignment: failed to delete role assignment", zap.Error(err))
+		return fmt.Errorf("DeleteRoleAssignment: failed to delete role assignment: %w", err)
+	}
+
+	return nil
+}
+
+// ListRoleAssignments returns the role assignments of the subject, or of all subjects if it is empty.
+func (ps *PostgresService) ListRoleAssignments(ctx context.Context, subject string) ([]*api.RoleAssignment, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	res := []*api.RoleAssignment{}
+
+	err := ps.inTenant(ctx, "ListRoleAssignments", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForListRoleAssignments, subject)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			assignment := &api.RoleAssignment{}
+			err = rows.Scan(&assignment.Subject, &assignment.Role, &assignment.CreatedAt)
+			if err != nil {
+				return err
+			}
+
+			res = append(res, assignment)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		ps.logger.Error("ListRoleAssignments: failed to retrieve role assignments", zap.Error(err))
+		return nil, fmt.Errorf("ListRoleAssignments: failed to retrieve role assignments: %w", err)
+	}
+
+	return res, nil
+}
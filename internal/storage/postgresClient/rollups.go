@@ -0,0 +1,194 @@
+package postgresClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"subscriptions/internal/api"
+)
+
+// RebuildMonthlySpend recomputes the monthly spend rollup of the tenant of ctx, or of every tenant, from scratch,
+// covering the months through the given month, and returns the number of rolled up rows.
+// The changes of the subscriptions wait until the rollup is rebuilt.
+func (ps *PostgresService) RebuildMonthlySpend(ctx context.Context, through time.Time) (int64, error) {
+	res, err := ps.rollUpMonthlySpend(ctx, "RebuildMonthlySpend", through, true)
+	if err != nil {
+		return 0, fmt.Errorf("RebuildMonthlySpend: %w", err)
+	}
+
+	return res, nil
+}
+
+// ExtendMonthlySpend extends the monthly spend rollup of the tenant of ctx, or of every tenant, through the given month,
+// rolling up the tenants which have none yet, and returns the number of rolled up rows.
+func (ps *PostgresService) ExtendMonthlySpend(ctx context.Context, through time.Time) (int64, error) {
+	res, err := ps.rollUpMonthlySpend(ctx, "ExtendMonthlySpend", through, false)
+	if err != nil {
+		return 0, fmt.Errorf("ExtendMonthlySpend: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetMonthlySpendCoverage returns the last month covered by the monthly spend rollup of the tenant of ctx,
+// or false if the tenant has no rollup. Queries across all tenants are never covered.
+func (ps *PostgresService) GetMonthlySpendCoverage(ctx context.Context) (time.Time, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var through time.Time
+
+	err := ps.readInTenant(ctx, "GetMonthlySpendCoverage", func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, queryForGetMonthlySpendCoverage).Scan(&through)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("GetMonthlySpendCoverage: %w", err)
+	}
+
+	return through, true, nil
+}
+
+// GetMonthlySpend returns the monthly spend rolled up for the months from start through end,
+// filtered by userID and/or serviceName. The months must be covered by the rollup, see GetMonthlySpendCoverage.
+func (ps *PostgresService) GetMonthlySpend(ctx context.Context, userID string, serviceName string, start time.Time, end time.Time) ([]api.MonthlyCost, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var res []api.MonthlyCost
+
+	err := ps.readInTenant(ctx, "GetMonthlySpend", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForGetMonthlySpend, userID, serviceName, start, end)
+		if err != nil {
+			return err
+		}
+
+		res, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (api.MonthlyCost, error) {
+			var cost api.MonthlyCost
+			var total int64
+
+			err := row.Scan(&cost.Month.Time, &total)
+			cost.Total = int(total)
+
+			return cost, err
+		})
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetMonthlySpend: %w", err)
+	}
+
+	return res, nil
+}
+
+// ListMonthlySpendOfUsers returns the monthly spend rolled up for each of the given users for the months
+// from start through end, so the spend of many users is read in a single query.
+func (ps *PostgresService) ListMonthlySpendOfUsers(ctx context.Context, userIDs []string, start time.Time, end time.Time) (map[string][]api.MonthlyCost, error) {
+	res, err := ps.listMonthlySpendOf(ctx, "ListMonthlySpendOfUsers", queryForListMonthlySpendOfUsers, userIDs, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("ListMonthlySpendOfUsers: %w", err)
+	}
+
+	return res, nil
+}
+
+// ListMonthlySpendOfServices returns the monthly spend rolled up for each of the given services for the months
+// from start through end, filtered by userID, so the spend of many services is read in a single query.
+func (ps *PostgresService) ListMonthlySpendOfServices(ctx context.Context, serviceNames []string, userID string, start time.Time, end time.Time) (map[string][]api.MonthlyCost, error) {
+	res, err := ps.listMonthlySpendOf(ctx, "ListMonthlySpendOfServices", queryForListMonthlySpendOfServices, serviceNames, start, end, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ListMonthlySpendOfServices: %w", err)
+	}
+
+	return res, nil
+}
+
+// rollUpMonthlySpend rolls up the months of the subscriptions through the given month which are not covered yet,
+// dropping the existing rollup first if rebuild is set. It may take long, so it is not bound by the query timeout.
+func (ps *PostgresService) rollUpMonthlySpend(ctx context.Context, operation string, through time.Time, rebuild bool) (int64, error) {
+	var res int64
+
+	err := ps.inTenant(ctx, operation, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, queryForLockMonthlySpend); err != nil {
+			return err
+		}
+
+		if rebuild {
+			if _, err := tx.Exec(ctx, queryForDeleteMonthlySpend); err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec(ctx, queryForDeleteMonthlySpendCoverage); err != nil {
+				return err
+			}
+		}
+
+		tag, err := tx.Exec(ctx, queryForExtendMonthlySpend, through)
+		if err != nil {
+			return err
+		}
+
+		res = tag.RowsAffected()
+
+		_, err = tx.Exec(ctx, queryForExtendMonthlySpendCoverage, through)
+		return err
+	})
+
+	return res, err
+}
+
+// listMonthlySpendOf runs a query of the monthly spend of each of the given keys, grouping the rows by key.
+func (ps *PostgresService) listMonthlySpendOf(ctx context.Context, operation string, query string, keys []string, args ...any) (map[string][]api.MonthlyCost, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	res := make(map[string][]api.MonthlyCost)
+
+	err := ps.readInTenant(ctx, operation, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, append([]any{keys}, args...)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var key string
+			var cost api.MonthlyCost
+			var total int64
+
+			if err := rows.Scan(&key, &cost.Month.Time, &total); err != nil {
+				return err
+			}
+
+			cost.Total = int(total)
+			res[key] = append(res[key], cost)
+		}
+
+		return rows.Err()
+	})
+
+	return res, err
+}
+
+// addMonthlySpend adds sign times the price of the subscription with the given id to its rolled up months.
+// It is called within the transaction changing the subscription, with -1 before it is updated or deleted
+// and with 1 once it is saved or updated. The lock the change takes is taken first, so the rollup cannot be extended
+// between the two calls of an update.
+func addMonthlySpend(ctx context.Context, tx pgx.Tx, id int, sign int) error {
+	if _, err := tx.Exec(ctx, queryForLockSubscriptionChanges); err != nil {
+		return fmt.Errorf("failed to lock subscriptions: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, queryForAddMonthlySpend, id, sign); err != nil {
+		return fmt.Errorf("failed to update monthly spend: %w", err)
+	}
+
+	return nil
+}
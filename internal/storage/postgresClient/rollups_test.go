@@ -0,0 +1,133 @@
+package postgresClient
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestMonthlySpendMatchesTotalPrice(t *testing.T) {
+	ps := newIntegrationService(t)
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	ctx := tenant.WithTenant(context.Background(), "rollup-"+suffix)
+	alice, bob := "alice-"+suffix, "bob-"+suffix
+
+	ids := make(map[int]bool)
+	t.Cleanup(func() {
+		for id := range ids {
+			if err := ps.DeleteSubscription(ctx, id); err != nil {
+				t.Errorf("DeleteSubscription() error = %v", err)
+			}
+		}
+	})
+
+	save := func(subscription *api.Subscription) int {
+		t.Helper()
+
+		id, err := ps.SaveSubscription(ctx, subscription)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids[id] = true
+		return id
+	}
+
+	remove := func(id int) {
+		t.Helper()
+
+		if err := ps.DeleteSubscription(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+
+		delete(ids, id)
+	}
+
+	update := func(id int, subscription *api.Subscription) {
+		t.Helper()
+
+		if err := ps.UpdateSubscription(ctx, id, subscription); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	through := month(2026, time.December)
+
+	rebuild := func() {
+		t.Helper()
+
+		if _, err := ps.RebuildMonthlySpend(ctx, through); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// check compares the totals the rollup path of TotalPrice sums up with api.TotalPrice over the subscriptions.
+	check := func(phase string) {
+		t.Helper()
+
+		filters := []struct{ userID, serviceName string }{
+			{"", ""}, {alice, ""}, {bob, ""}, {"", "Netflix"}, {bob, "Netflix"}, {alice, "Yandex Plus"},
+		}
+
+		periods := []struct{ start, end time.Time }{
+			{month(2025, time.January), through},
+			{month(2025, time.June), month(2025, time.June)},
+			{month(2025, time.December), month(2026, time.March)},
+			{month(2024, time.January), month(2024, time.December)},
+		}
+
+		for _, filter := range filters {
+			subscriptions, err := ps.ListFilteredSubscriptions(ctx, filter.userID, filter.serviceName)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, period := range periods {
+				costs, err := ps.GetMonthlySpend(ctx, filter.userID, filter.serviceName, period.start, period.end)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := 0
+				for _, cost := range costs {
+					got += cost.Total
+				}
+
+				if want := api.TotalPrice(period.start, period.end, subscriptions); got != want {
+					t.Errorf("%s: monthly spend of %q %q from %s through %s = %d, want %d", phase,
+						filter.userID, filter.serviceName,
+						period.start.Format(api.ISOMonthLayout), period.end.Format(api.ISOMonthLayout), got, want)
+				}
+			}
+		}
+	}
+
+	netflix := save(&api.Subscription{ServiceName: "Netflix", Price: 400, UserID: alice, StartDate: "01-2025"})
+	spotify := save(&api.Subscription{ServiceName: "Spotify", Price: 200, UserID: alice, StartDate: "06-2025", EndDate: "03-2026"})
+
+	rebuild()
+	check("rebuilt")
+
+	// The changes after the rollup is built adjust it within their transactions.
+	save(&api.Subscription{ServiceName: "Netflix", Price: 400, UserID: bob, StartDate: "11-2025", EndDate: "02-2026"})
+	save(&api.Subscription{ServiceName: "Spotify", Price: 200, UserID: bob, StartDate: "01-2026", EndDate: "06-2027"})
+	update(spotify, &api.Subscription{ServiceName: "Spotify", Price: 250, UserID: alice, StartDate: "03-2025", EndDate: "12-2025"})
+	update(netflix, &api.Subscription{ServiceName: "Netflix", Price: 400, UserID: bob, StartDate: "01-2025"})
+
+	remove(save(&api.Subscription{ServiceName: "Yandex Plus", Price: 300, UserID: alice, StartDate: "02-2026"}))
+
+	check("changed")
+
+	// Rebuilding the rollup from scratch gives the same totals as the adjustments did.
+	rebuild()
+	check("rebuilt again")
+}
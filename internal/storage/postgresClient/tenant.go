@@ -0,0 +1,93 @@
+package postgresClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+// inTenant runs fn in a transaction bound to the tenant of ctx and reports its duration as the given operation.
+// The tenant is exposed to the row-level security policies as app.tenant_id and, if a tenant role is configured,
+// the transaction switches to it, so the policies apply even if the service connects as the owner of the tables.
+func (ps *PostgresService) inTenant(ctx context.Context, operation string, fn func(pgx.Tx) error) (err error) {
+	defer ps.observe(operation, time.Now(), &err)
+
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	return begin(ctx, ps.pool, tenantID, ps.tenantRole, pgx.TxOptions{}, fn)
+}
+
+// tenantOf returns the tenant ctx is bound to, or ErrTenantRequired if there is none.
+func tenantOf(ctx context.Context) (string, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return "", ErrTenantRequired
+	}
+
+	return tenantID, nil
+}
+
+// begin runs fn in a transaction with the given options on a connection of the pool, bound to the tenant
+// and switched to the role, if there is one.
+func begin(ctx context.Context, pool *pgxpool.Pool, tenantID string, role string, options pgx.TxOptions, fn func(pgx.Tx) error) error {
+	return pgx.BeginTxFunc(ctx, pool, options, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, queryForSetTenant, tenantID, role)
+		if err != nil {
+			return fmt.Errorf("failed to set tenant: %w", err)
+		}
+
+		return fn(tx)
+	})
+}
+
+// checkRowLevelSecurity checks that the row-level security policies apply to the tenant-bound transactions: the role
+// they run as, the tenant role or else the connected one, must be neither a superuser nor have BYPASSRLS.
+// Otherwise the tenants would silently not be isolated from each other.
+func checkRowLevelSecurity(ctx context.Context, pool *pgxpool.Pool, role string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var name string
+	var bypasses bool
+
+	err := pool.QueryRow(ctx, queryForBypassesRowLevelSecurity, role).Scan(&name, &bypasses)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("tenant role %q does not exist", role)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check tenant role: %w", err)
+	}
+
+	if bypasses {
+		return fmt.Errorf("role %q bypasses row-level security, set POSTGRES_TENANT_ROLE to a role without SUPERUSER and BYPASSRLS", name)
+	}
+
+	return nil
+}
+
+// observe reports the duration of the operation started at start to the query observer, if there is one.
+func (ps *PostgresService) observe(operation string, start time.Time, err *error) {
+	if ps.observer != nil {
+		ps.observer.ObserveQuery(operation, time.Since(start), *err)
+	}
+}
+
+// changed notifies the change observer, if there is one, of the subscriptions changed in the tenant of ctx.
+func (ps *PostgresService) changed(ctx context.Context, subscriptions ...*api.Subscription) {
+	if ps.changes == nil {
+		return
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	ps.changes.SubscriptionsChanged(tenantID, subscriptions...)
+}
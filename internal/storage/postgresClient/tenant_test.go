@@ -0,0 +1,90 @@
+package postgresClient
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/tenant"
+)
+
+// integrationMigrations is the path of the migrations applied by the integration tests.
+const integrationMigrations = "file://../../../database/migrations"
+
+// newIntegrationService connects to the database given by the POSTGRES_* variables and migrates it.
+// The test is skipped unless POSTGRES_INTEGRATION is set, so the tests do not need a database by default.
+func newIntegrationService(t *testing.T) *PostgresService {
+	t.Helper()
+
+	if os.Getenv("POSTGRES_INTEGRATION") == "" {
+		t.Skip("POSTGRES_INTEGRATION is not set")
+	}
+
+	var config Config
+	if err := cleanenv.ReadEnv(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := New(context.Background(), &config, zap.NewNop(), integrationMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ps.Close)
+
+	return ps
+}
+
+func TestRowLevelSecurity(t *testing.T) {
+	ps := newIntegrationService(t)
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	acme := tenant.WithTenant(context.Background(), "acme-"+suffix)
+	globex := tenant.WithTenant(context.Background(), "globex-"+suffix)
+
+	subscription := &api.Subscription{ServiceName: "Netflix", Price: 400, UserID: "u-" + suffix, StartDate: "01-2025"}
+
+	id, err := ps.SaveSubscription(acme, subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := ps.DeleteSubscription(acme, id); err != nil {
+			t.Errorf("DeleteSubscription() error = %v", err)
+		}
+	})
+
+	if _, err = ps.GetSubscription(acme, id); err != nil {
+		t.Fatalf("GetSubscription() of the own tenant error = %v", err)
+	}
+
+	if _, err = ps.GetSubscription(tenant.WithTenant(context.Background(), tenant.All), id); err != nil {
+		t.Fatalf("GetSubscription() of all tenants error = %v", err)
+	}
+
+	if _, err = ps.GetSubscription(globex, id); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("GetSubscription() of another tenant error = %v, want %v", err, ErrSubscriptionNotFound)
+	}
+
+	subscriptions, err := ps.ListFilteredSubscriptions(globex, subscription.UserID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscriptions) != 0 {
+		t.Errorf("ListFilteredSubscriptions() of another tenant = %d subscriptions, want none", len(subscriptions))
+	}
+
+	if err = ps.UpdateSubscription(globex, id, subscription); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("UpdateSubscription() of another tenant error = %v, want %v", err, ErrSubscriptionNotFound)
+	}
+
+	if err = ps.DeleteSubscription(globex, id); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("DeleteSubscription() of another tenant error = %v, want %v", err, ErrSubscriptionNotFound)
+	}
+}
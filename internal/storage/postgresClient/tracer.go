@@ -0,0 +1,64 @@
+package postgresClient
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans of the database queries.
+const tracerName = "subscriptions/internal/storage/postgresClient"
+
+// queryTracer implements pgx.QueryTracer, recording a span for every query executed by the pool.
+// It uses the global tracer provider, so it records nothing unless tracing is set up.
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+// newQueryTracer creates and returns a new queryTracer instance.
+func newQueryTracer() *queryTracer {
+	return &queryTracer{tracer: otel.Tracer(tracerName)}
+}
+
+// TraceQueryStart starts the span of the query, named after its statement type.
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "postgres "+statement(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBQueryText(strings.TrimSpace(data.SQL)),
+		),
+	)
+
+	return ctx
+}
+
+// TraceQueryEnd ends the span of the query, recording its error if it failed.
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+		return
+	}
+
+	span.SetAttributes(attribute.Int64("db.response.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+// statement returns the type of the SQL statement, such as SELECT or INSERT.
+func statement(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+
+	return strings.ToUpper(fields[0])
+}
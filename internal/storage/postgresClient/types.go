@@ -1,7 +1,9 @@
 package postgresClient
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -13,37 +15,186 @@ import (
 // DefaultPostgresTimeout defines the default timeout for PostgreSQL operations.
 const DefaultPostgresTimeout = 3 * time.Second
 
+// DefaultReplicaCheckInterval defines how often the health of the read replicas is checked by default.
+const DefaultReplicaCheckInterval = 10 * time.Second
+
+// DefaultReadYourWritesWindow defines by default how long the clients which have written read from the primary.
+const DefaultReadYourWritesWindow = 5 * time.Second
+
+// ErrTenantRequired indicates that the operation was requested without binding the context to a tenant.
+var ErrTenantRequired = fmt.Errorf("tenant is required")
+
 // ErrSubscriptionNotFound indicates that the subscription was not found.
 var ErrSubscriptionNotFound = fmt.Errorf("subscription was not found")
 
+// ErrEventNotFound indicates that the event was not found.
+var ErrEventNotFound = fmt.Errorf("event was not found")
+
+// ErrAPIKeyNotFound indicates that the API key was not found or has been revoked.
+var ErrAPIKeyNotFound = fmt.Errorf("api key was not found")
+
+// ErrRoleAssignmentNotFound indicates that the role assignment was not found.
+var ErrRoleAssignmentNotFound = fmt.Errorf("role assignment was not found")
+
+// ErrWebhookNotFound indicates that the webhook was not found.
+var ErrWebhookNotFound = fmt.Errorf("webhook was not found")
+
 // Config defines the configuration parameters for the PostgresService,
 // including credentials and timeout configuration.
+// TenantRole is the role the tenant-bound transactions switch to, so row-level security applies to them.
+// AutoMigrate runs the pending migrations on startup; when it is disabled, they are applied with the migrate command.
+// Replicas are the DSNs of the read replicas serving the read-only queries; the replicas lagging behind the primary
+// by more than ReplicaMaxLag, if set, or failing the health check are skipped until they catch up.
+// ReadYourWrites pins the clients which have written to the primary for ReadYourWritesWindow, which should cover
+// the lag of the replicas, so they never read stale data.
 type Config struct {
-	Host     string        `env:"POSTGRES_HOST"`
-	Port     string        `env:"POSTGRES_PORT"`
+	Host     string        `env:"POSTGRES_HOST" env-default:"localhost"`
+	Port     string        `env:"POSTGRES_PORT" env-default:"5432"`
 	User     string        `env:"POSTGRES_USER"`
-	Password string        `env:"POSTGRES_PASSWORD"`
+	Password string        `env:"POSTGRES_PASSWORD" secret:"true"`
 	Database string        `env:"POSTGRES_DATABASE"`
-	Timeout  time.Duration `env:"POSTGRES_TIMEOUT"`
-	MaxConns int           `env:"POSTGRES_MAX_CONNECTIONS"`
+	Timeout  time.Duration `env:"POSTGRES_TIMEOUT" env-default:"3s"`
+	MaxConns int           `env:"POSTGRES_MAX_CONNECTIONS" env-default:"10"`
 	MinConns int           `env:"POSTGRES_MIN_CONNECTIONS"`
+
+	TenantRole  string `env:"POSTGRES_TENANT_ROLE"`
+	AutoMigrate bool   `env:"POSTGRES_AUTO_MIGRATE" env-default:"true"`
+
+	Replicas             []string      `env:"POSTGRES_REPLICAS" secret:"true"`
+	ReplicaCheckInterval time.Duration `env:"POSTGRES_REPLICA_CHECK_INTERVAL" env-default:"10s"`
+	ReplicaMaxLag        time.Duration `env:"POSTGRES_REPLICA_MAX_LAG"`
+	ReadYourWrites       bool          `env:"POSTGRES_READ_YOUR_WRITES"`
+	ReadYourWritesWindow time.Duration `env:"POSTGRES_READ_YOUR_WRITES_WINDOW" env-default:"5s"`
 }
 
 // PostgresService implements the PostgresClient interface.
 // It provides methods for storing and retrieving subscription using a PostgreSQL database.
 type PostgresService struct {
+	pool       *pgxpool.Pool
+	logger     *zap.Logger
+	timeout    time.Duration
+	tenantRole string
+	observer   QueryObserver
+	changes    ChangeObserver
+	migration  uint
+
+	replicas       []*replica
+	next           atomic.Uint64
+	maxLag         time.Duration
+	readYourWrites bool
+	pinWindow      time.Duration
+	stopChecks     context.CancelFunc
+	checksDone     chan struct{}
+}
+
+// replica is a read replica together with the outcome of its last health check.
+type replica struct {
+	name    string
 	pool    *pgxpool.Pool
-	logger  *zap.Logger
-	timeout time.Duration
+	healthy atomic.Bool
+}
+
+// SubscriptionRecord is a stored subscription together with its database id and tenant.
+type SubscriptionRecord struct {
+	ID           int
+	TenantID     string
+	Subscription api.Subscription
+}
+
+// PendingDelivery is a claimed webhook delivery together with the endpoint and the event to send.
+type PendingDelivery struct {
+	ID       int
+	Attempts int
+	URL      string
+	Secret   string
+	Event    api.Event
+}
+
+// ActiveSubscriptionStats is the number and the total monthly price of the active subscriptions of a tenant.
+type ActiveSubscriptionStats struct {
+	TenantID     string
+	Count        int
+	MonthlyPrice int
+}
+
+// QueryObserver receives the duration and the outcome of every PostgresService operation.
+type QueryObserver interface {
+	ObserveQuery(operation string, duration time.Duration, err error)
+}
+
+// ChangeObserver is notified of the subscriptions saved, updated or deleted through PostgresService once the change
+// is committed. Updates report the subscription both before and after the change.
+type ChangeObserver interface {
+	SubscriptionsChanged(tenantID string, subscriptions ...*api.Subscription)
 }
 
 // PostgresClient defines an interface for storing and retrieving subscription in a PostgreSQL database.
+// Every operation is scoped to the tenant its context is bound to.
 type PostgresClient interface {
-	SaveSubscription(*api.Subscription) (int, error)
-	DeleteSubscription(int) error
-	GetSubscription(int) (*api.Subscription, error)
-	ListSubscriptions() ([]*api.Subscription, error)
-	UpdateSubscription(int, *api.Subscription) error
-	ListFilteredSubscriptions(string, string) ([]*api.Subscription, error)
+	SaveSubscription(context.Context, *api.Subscription) (int, error)
+	DeleteSubscription(context.Context, int) error
+	GetSubscription(context.Context, int) (*api.Subscription, error)
+	ListSubscriptions(context.Context) ([]*api.Subscription, error)
+	UpdateSubscription(context.Context, int, *api.Subscription) error
+	ListFilteredSubscriptions(context.Context, string, string) ([]*api.Subscription, error)
+	ListSubscriptionsAfter(context.Context, int, string, int) ([]*SubscriptionRecord, error)
 	Close()
 }
+
+// WebhookClient defines an interface for storing and retrieving webhooks and their deliveries in a PostgreSQL database.
+type WebhookClient interface {
+	SaveWebhook(context.Context, *api.Webhook) (int, error)
+	GetWebhook(context.Context, int) (*api.Webhook, error)
+	ListWebhooks(context.Context) ([]*api.Webhook, error)
+	UpdateWebhook(context.Context, int, *api.Webhook) error
+	DeleteWebhook(context.Context, int) error
+	ListWebhookDeliveries(context.Context, int, int) ([]*api.WebhookDelivery, error)
+}
+
+// EventClient defines an interface for reading subscription lifecycle events from the outbox.
+type EventClient interface {
+	GetEvent(context.Context, int) (*api.Event, error)
+	ListEventsAfter(context.Context, int, string, int) ([]*api.Event, error)
+	EventReplayStart(context.Context, int, time.Duration) (int, error)
+}
+
+// ReportClient defines an interface for the reporting queries spanning the subscriptions of many users or services.
+type ReportClient interface {
+	ListSubscriptionsOfUsers(context.Context, []string) ([]*api.Subscription, error)
+	ListSubscriptionsOfServices(context.Context, []string) ([]*api.Subscription, error)
+	ListUserIDs(context.Context) ([]string, error)
+	ListServiceNames(context.Context) ([]string, error)
+}
+
+// RollupClient defines an interface for maintaining and reading the monthly spend rollup, which sums the prices
+// of the subscriptions per user, service and month, so long periods are reported without reading the subscriptions.
+type RollupClient interface {
+	RebuildMonthlySpend(context.Context, time.Time) (int64, error)
+	ExtendMonthlySpend(context.Context, time.Time) (int64, error)
+	GetMonthlySpendCoverage(context.Context) (time.Time, bool, error)
+	GetMonthlySpend(context.Context, string, string, time.Time, time.Time) ([]api.MonthlyCost, error)
+	ListMonthlySpendOfUsers(context.Context, []string, time.Time, time.Time) (map[string][]api.MonthlyCost, error)
+	ListMonthlySpendOfServices(context.Context, []string, string, time.Time, time.Time) (map[string][]api.MonthlyCost, error)
+}
+
+// APIKeyClient defines an interface for storing and retrieving API keys in a PostgreSQL database.
+type APIKeyClient interface {
+	SaveAPIKey(context.Context, *api.APIKey, string) error
+	AuthenticateAPIKey(context.Context, string) (*api.APIKey, error)
+	GetAPIKey(context.Context, int) (*api.APIKey, error)
+	ListAPIKeys(context.Context) ([]*api.APIKey, error)
+	RevokeAPIKey(context.Context, int) error
+}
+
+// RoleClient defines an interface for storing and retrieving role assignments in a PostgreSQL database.
+type RoleClient interface {
+	SaveRoleAssignment(context.Context, *api.RoleAssignment) error
+	DeleteRoleAssignment(context.Context, string, string) error
+	ListRoleAssignments(context.Context, string) ([]*api.RoleAssignment, error)
+}
+
+// RateLimitClient defines an interface for keeping the rate limit token buckets in a PostgreSQL database.
+type RateLimitClient interface {
+	TakeRateLimitToken(context.Context, string, float64, float64) (float64, bool, error)
+	DeleteRateLimitsBefore(context.Context, time.Time) error
+}
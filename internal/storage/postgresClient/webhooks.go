@@ -0,0 +1,331 @@
+package postgresClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+)
+
+// SaveWebhook inserts the given webhook into the database and returns its generated ID.
+func (ps *PostgresService) SaveWebhook(ctx context.Context, webhook *api.Webhook) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var id int
+
+	ps.pin(ctx)
+
+	err := ps.inTenant(ctx, "SaveWebhook", func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, queryForSaveWebhook, webhook.URL, webhook.Secret, events(webhook)).Scan(&id)
+	})
+	if err != nil {
+		ps.logger.Error("SaveWebhook: failed to save webhook", zap.Error(err))
+		return 0, fmt.Errorf("SaveWebhook: failed to save webhook: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetWebhook returns a stored webhook by specified id.
+// If there was no webhook, returns ErrWebhookNotFound.
+func (ps *PostgresService) GetWebhook(ctx context.Context, id int) (*api.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	res := &api.Webhook{}
+
+	err := ps.readInTenant(ctx, "GetWebhook", func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, queryForGetWebhook, id).Scan(&res.ID, &res.URL, &res.Secret, &res.Events)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			ps.logger.Error(ErrWebhookNotFound.Error())
+			return nil, ErrWebhookNotFound
+		}
+		ps.logger.Error("GetWebhook: failed to retrieve webhook", zap.Error(err))
+		return nil, fmt.Errorf("GetWebhook: failed to retrieve webhook: %w", err)
+	}
+
+	return res, nil
+}
+
+// ListWebhooks returns all registered webhooks.
+func (ps *PostgresService) ListWebhooks(ctx context.Context) ([]*api.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	res := []*api.Webhook{}
+
+	err := ps.readInTenant(ctx, "ListWebhooks", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForListWebhooks)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			webhook := &api.Webhook{}
+			err = rows.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &webhook.Events)
+			if err != nil {
+				return err
+			}
+
+			res = append(res, webhook)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		ps.logger.Error("ListWebhooks: failed to retrieve webhooks", zap.Error(err))
+		return nil, fmt.Errorf("ListWebhooks: failed to retrieve webhooks: %w", err)
+	}
+
+	return res, nil
+}
+
+// UpdateWebhook updates specified webhook by given id.
+func (ps *PostgresService) UpdateWebhook(ctx context.Context, id int, webhook *api.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	ps.pin(ctx)
+
+	err := ps.inTenant(ctx, "UpdateWebhook", func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryForUpdateWebhook, id, webhook.URL, webhook.Secret, events(webhook))
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrWebhookNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			ps.logger.Error(ErrWebhookNotFound.Error())
+			return ErrWebhookNotFound
+		}
+		ps.logger.Error("UpdateWebhook: failed to update webhook", zap.Error(err))
+		return fmt.Errorf("UpdateWebhook: failed to update webhook: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes a webhook by specified id together with its delivery log.
+func (ps *PostgresService) DeleteWebhook(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	ps.pin(ctx)
+
+	err := ps.inTenant(ctx, "DeleteWebhook", func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryForDeleteWebhook, id)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrWebhookNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			ps.logger.Error(ErrWebhookNotFound.Error())
+			return ErrWebhookNotFound
+		}
+		ps.logger.Error("DeleteWebhook: failed to delete webhook", zap.Error(err), zap.Int("id", id))
+		return fmt.Errorf("DeleteWebhook: failed to delete webhook: %w", err)
+	}
+
+	return nil
+}
+
+// ListWebhookDeliveries returns up to limit latest deliveries of the webhook with the given id.
+func (ps *PostgresService) ListWebhookDeliveries(ctx context.Context, webhookID int, limit int) ([]*api.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	res := []*api.WebhookDelivery{}
+
+	err := ps.readInTenant(ctx, "ListWebhookDeliveries", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForListWebhookDeliveries, webhookID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			delivery := &api.WebhookDelivery{}
+			err = rows.Scan(
+				&delivery.ID,
+				&delivery.WebhookID,
+				&delivery.EventID,
+				&delivery.EventType,
+				&delivery.Status,
+				&delivery.Attempts,
+				&delivery.StatusCode,
+				&delivery.LastError,
+				&delivery.NextAttemptAt,
+				&delivery.DeliveredAt,
+				&delivery.CreatedAt,
+			)
+			if err != nil {
+				return err
+			}
+
+			res = append(res, delivery)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		ps.logger.Error("ListWebhookDeliveries: failed to retrieve deliveries", zap.Error(err))
+		return nil, fmt.Errorf("ListWebhookDeliveries: failed to retrieve deliveries: %w", err)
+	}
+
+	return res, nil
+}
+
+// EnqueueExpiredEvents writes subscription.expired events to the outbox for subscriptions which have ended.
+func (ps *PostgresService) EnqueueExpiredEvents(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	err := ps.inTenant(ctx, "EnqueueExpiredEvents", func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, queryForEnqueueExpiredEvents)
+		return err
+	})
+	if err != nil {
+		ps.logger.Error("EnqueueExpiredEvents: failed to enqueue events", zap.Error(err))
+		return fmt.Errorf("EnqueueExpiredEvents: failed to enqueue events: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueDeliveries creates deliveries of up to limit unprocessed outbox events for every matching webhook.
+func (ps *PostgresService) EnqueueDeliveries(ctx context.Context, limit int) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	err := ps.inTenant(ctx, "EnqueueDeliveries", func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, queryForEnqueueDeliveries, limit)
+		return err
+	})
+	if err != nil {
+		ps.logger.Error("EnqueueDeliveries: failed to enqueue deliveries", zap.Error(err))
+		return fmt.Errorf("EnqueueDeliveries: failed to enqueue deliveries: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDeliveries returns up to limit due deliveries, postponing them by lease so no other dispatcher takes them.
+func (ps *PostgresService) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*PendingDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var res []*PendingDelivery
+
+	err := ps.inTenant(ctx, "ClaimDeliveries", func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryForClaimDeliveries, limit, lease.Seconds())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			delivery := &PendingDelivery{}
+			err = rows.Scan(
+				&delivery.ID,
+				&delivery.Attempts,
+				&delivery.URL,
+				&delivery.Secret,
+				&delivery.Event.ID,
+				&delivery.Event.TenantID,
+				&delivery.Event.Type,
+				&delivery.Event.SubscriptionID,
+				&delivery.Event.Data,
+				&delivery.Event.OccurredAt,
+			)
+			if err != nil {
+				return err
+			}
+
+			res = append(res, delivery)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		ps.logger.Error("ClaimDeliveries: failed to claim deliveries", zap.Error(err))
+		return nil, fmt.Errorf("ClaimDeliveries: failed to claim deliveries: %w", err)
+	}
+
+	return res, nil
+}
+
+// CompleteDelivery marks the delivery as delivered with the given response status code.
+func (ps *PostgresService) CompleteDelivery(ctx context.Context, id int, statusCode int) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	err := ps.inTenant(ctx, "CompleteDelivery", func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, queryForCompleteDelivery, id, statusCode)
+		return err
+	})
+	if err != nil {
+		ps.logger.Error("CompleteDelivery: failed to update delivery", zap.Error(err), zap.Int("id", id))
+		return fmt.Errorf("CompleteDelivery: failed to update delivery: %w", err)
+	}
+
+	return nil
+}
+
+// FailDelivery records a failed delivery attempt. If giveUp is set, the delivery is marked as failed,
+// otherwise it is retried at nextAttempt. A zero statusCode means that no response was received.
+func (ps *PostgresService) FailDelivery(ctx context.Context, id int, statusCode int, reason string, nextAttempt time.Time, giveUp bool) error {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	status := "pending"
+	if giveUp {
+		status = "failed"
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	err := ps.inTenant(ctx, "FailDelivery", func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, queryForFailDelivery, id, status, code, reason, nextAttempt)
+		return err
+	})
+	if err != nil {
+		ps.logger.Error("FailDelivery: failed to update delivery", zap.Error(err), zap.Int("id", id))
+		return fmt.Errorf("FailDelivery: failed to update delivery: %w", err)
+	}
+
+	return nil
+}
+
+// events returns the event filter of the webhook, never nil so it is stored as an empty array.
+func events(webhook *api.Webhook) []string {
+	if webhook.Events == nil {
+		return []string{}
+	}
+
+	return webhook.Events
+}
@@ -0,0 +1,99 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/problem"
+)
+
+const (
+	// Header is the request header selecting the tenant of the requests when authentication is disabled;
+	// authenticated callers may only repeat the tenant of their credentials.
+	Header = "X-Tenant-ID"
+
+	// All is the pseudo tenant of background jobs, which lets them access the data of every tenant.
+	// It is only ever set by the code of the jobs and never accepted from requests or credentials.
+	All = "*"
+)
+
+// validID matches the tenant ids accepted from requests.
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Config defines the tenant resolution settings.
+// Default is the tenant of requests which specify none; such requests are rejected if it is empty.
+type Config struct {
+	Default string `env:"TENANT_DEFAULT"`
+}
+
+// tenantKey is the context key under which the tenant id is stored.
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx bound to the given tenant.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// Valid reports whether tenantID is acceptable as the tenant of a request.
+func Valid(tenantID string) bool {
+	return validID.MatchString(tenantID)
+}
+
+// FromContext returns the tenant ctx is bound to.
+func FromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(string)
+	return tenantID, ok
+}
+
+// Middleware binds every request to a tenant, see Resolve. Rejected requests are answered with the problem of Resolve.
+func Middleware(config *Config, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx, err := Resolve(config, r.Context(), r.Header.Get(Header))
+			if err != nil {
+				problem.Write(logger, w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Resolve returns a copy of ctx bound to the tenant of the caller. The tenant bound by the credentials of the caller wins,
+// and a conflicting requested tenant is rejected with 403; otherwise the requested tenant is used, falling back
+// to the default one. Callers which end up without a tenant are rejected with 400. Neither a bound nor a requested
+// tenant may be All. The errors are *problem.Error values.
+func Resolve(config *Config, ctx context.Context, requested string) (context.Context, error) {
+	if requested != "" && !Valid(requested) {
+		return nil, problem.New(problem.CodeInvalidRequest, "invalid tenant id")
+	}
+
+	if bound, ok := FromContext(ctx); ok {
+		if !Valid(bound) {
+			return nil, problem.New(problem.CodeForbidden, "credentials are not bound to a valid tenant")
+		}
+
+		if requested != "" && requested != bound {
+			return nil, problem.New(problem.CodeForbidden, "credentials are bound to another tenant")
+		}
+
+		return ctx, nil
+	}
+
+	tenantID := requested
+	if tenantID == "" {
+		tenantID = config.Default
+	}
+
+	if tenantID == "" {
+		return nil, problem.New(problem.CodeInvalidRequest, "missing tenant id")
+	}
+
+	return WithTenant(ctx, tenantID), nil
+}
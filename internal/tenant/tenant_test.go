@@ -0,0 +1,164 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/problem"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		bound     *string
+		requested string
+		fallback  string
+		code      problem.Code
+		tenantID  string
+	}{
+		{
+			name:      "requested tenant",
+			requested: "acme",
+			fallback:  "default",
+			tenantID:  "acme",
+		},
+		{
+			name:     "default tenant",
+			fallback: "default",
+			tenantID: "default",
+		},
+		{
+			name: "no tenant",
+			code: problem.CodeInvalidRequest,
+		},
+		{
+			name:      "invalid requested tenant",
+			requested: "acme corp",
+			fallback:  "default",
+			code:      problem.CodeInvalidRequest,
+		},
+		{
+			name:      "requested tenant is too long",
+			requested: strings.Repeat("a", 65),
+			code:      problem.CodeInvalidRequest,
+		},
+		{
+			name:      "requested all tenants",
+			requested: All,
+			fallback:  "default",
+			code:      problem.CodeInvalidRequest,
+		},
+		{
+			name:     "bound tenant",
+			bound:    ptr("acme"),
+			fallback: "default",
+			tenantID: "acme",
+		},
+		{
+			name:      "bound tenant requested again",
+			bound:     ptr("acme"),
+			requested: "acme",
+			tenantID:  "acme",
+		},
+		{
+			name:      "bound to another tenant",
+			bound:     ptr("acme"),
+			requested: "globex",
+			code:      problem.CodeForbidden,
+		},
+		{
+			name:     "bound to all tenants",
+			bound:    ptr(All),
+			fallback: "default",
+			code:     problem.CodeForbidden,
+		},
+		{
+			name:      "bound to all tenants requesting another",
+			bound:     ptr(All),
+			requested: "acme",
+			code:      problem.CodeForbidden,
+		},
+		{
+			name:     "bound to no tenant",
+			bound:    ptr(""),
+			fallback: "default",
+			code:     problem.CodeForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.bound != nil {
+				ctx = WithTenant(ctx, *tt.bound)
+			}
+
+			ctx, err := Resolve(&Config{Default: tt.fallback}, ctx, tt.requested)
+			if tt.code != "" {
+				var perr *problem.Error
+				if !errors.As(err, &perr) || perr.Code != tt.code {
+					t.Fatalf("Resolve() error = %v, want code %s", err, tt.code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+
+			if tenantID, _ := FromContext(ctx); tenantID != tt.tenantID {
+				t.Errorf("FromContext() = %q, want %q", tenantID, tt.tenantID)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var got string
+	handler := Middleware(&Config{Default: "default"}, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		status   int
+		tenantID string
+	}{
+		{name: "header", header: "acme", status: http.StatusOK, tenantID: "acme"},
+		{name: "no header", status: http.StatusOK, tenantID: "default"},
+		{name: "all tenants", header: All, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/subscriptions", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+
+			if got != tt.tenantID {
+				t.Errorf("tenant = %q, want %q", got, tt.tenantID)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,168 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Provider owns the tracer provider and the exporter set up by New.
+type Provider struct {
+	provider *sdktrace.TracerProvider
+	closer   io.Closer
+}
+
+// New sets up the W3C trace context propagation and, if tracing is enabled, the global tracer provider
+// exporting the spans with the configured exporter. Shutdown must be called to flush the pending spans.
+func New(ctx context.Context, config *Config, logger *zap.Logger) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !config.Enabled {
+		return &Provider{}, nil
+	}
+
+	if config.ServiceName == "" {
+		config.ServiceName = DefaultServiceName
+	}
+
+	if config.Exporter == "" {
+		config.Exporter = ExporterOTLP
+	}
+
+	if config.SampleRatio == 0 {
+		config.SampleRatio = DefaultSampleRatio
+	}
+
+	p := &Provider{}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch config.Exporter {
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if config.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err = otlptracehttp.New(ctx, options...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		if config.File == "" {
+			return nil, fmt.Errorf("New: file exporter requires TRACING_FILE")
+		}
+
+		var file *os.File
+		file, err = os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("New: cannot open trace file: %w", err)
+		}
+
+		p.closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, fmt.Errorf("New: unknown trace exporter: %s", config.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("New: cannot create trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+		resource.WithAttributes(semconv.ServiceName(config.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("New: cannot create resource: %w", err)
+	}
+
+	p.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(p.provider)
+
+	logger.Info("tracing enabled", zap.String("exporter", config.Exporter))
+
+	return p, nil
+}
+
+// Shutdown flushes the pending spans and releases the exporter.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+
+	if err := p.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("Shutdown: %w", err)
+	}
+
+	if p.closer != nil {
+		return p.closer.Close()
+	}
+
+	return nil
+}
+
+// Middleware continues the trace of the caller given by the W3C traceparent header, or starts a new one,
+// and records a server span per request named after the chi route pattern of its handler.
+func Middleware(next http.Handler) http.Handler {
+	tracer := otel.Tracer(tracerName)
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(r.RemoteAddr),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+
+	return http.HandlerFunc(fn)
+}
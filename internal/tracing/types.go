@@ -0,0 +1,35 @@
+package tracing
+
+const (
+	// ExporterOTLP sends the spans to an OpenTelemetry collector over OTLP/HTTP.
+	ExporterOTLP = "otlp"
+
+	// ExporterStdout writes the spans to the standard output, intended for local testing.
+	ExporterStdout = "stdout"
+
+	// ExporterFile writes the spans to the file specified by File, intended for local testing.
+	ExporterFile = "file"
+
+	// DefaultServiceName defines the service name the spans are reported under.
+	DefaultServiceName = "subscriptions"
+
+	// DefaultSampleRatio defines the share of the traces started by the service which are recorded.
+	DefaultSampleRatio = 1.0
+
+	// tracerName identifies the spans of the HTTP requests.
+	tracerName = "subscriptions/internal/tracing"
+)
+
+// Config defines the tracing settings.
+// Endpoint is the host:port of the OTLP collector; if it is empty, the standard OTEL_EXPORTER_OTLP_* variables apply.
+// SampleRatio is the share of the traces started by the service which are recorded; incoming requests
+// follow the sampling decision of their caller.
+type Config struct {
+	Enabled     bool    `env:"TRACING_ENABLED"`
+	Exporter    string  `env:"TRACING_EXPORTER" env-default:"otlp"`
+	ServiceName string  `env:"TRACING_SERVICE_NAME" env-default:"subscriptions"`
+	Endpoint    string  `env:"TRACING_OTLP_ENDPOINT"`
+	Insecure    bool    `env:"TRACING_OTLP_INSECURE"`
+	File        string  `env:"TRACING_FILE"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+}
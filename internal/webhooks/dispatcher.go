@@ -0,0 +1,240 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/storage/postgresClient"
+	"subscriptions/internal/tenant"
+)
+
+// Dispatcher moves subscription lifecycle events from the outbox to the registered webhooks.
+// Every delivery is signed and retried with exponential backoff until it succeeds or runs out of attempts.
+type Dispatcher struct {
+	storage        Storage
+	client         *http.Client
+	logger         *zap.Logger
+	interval       time.Duration
+	expiryInterval time.Duration
+	batchSize      int
+	maxAttempts    int
+	backoff        time.Duration
+	maxBackoff     time.Duration
+
+	// expiredAt is when the events of the expired subscriptions were last written to the outbox.
+	expiredAt time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates and returns a new Dispatcher instance, applying defaults to the unset config fields.
+func New(config *Config, storage Storage, logger *zap.Logger) *Dispatcher {
+	if config.Interval == 0 {
+		config.Interval = DefaultInterval
+	}
+
+	if config.ExpiryInterval == 0 {
+		config.ExpiryInterval = DefaultExpiryInterval
+	}
+
+	if config.BatchSize == 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+
+	if config.Backoff == 0 {
+		config.Backoff = DefaultBackoff
+	}
+
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+
+	return &Dispatcher{
+		storage:        storage,
+		client:         &http.Client{Timeout: config.Timeout},
+		logger:         logger,
+		interval:       config.Interval,
+		expiryInterval: config.ExpiryInterval,
+		batchSize:      config.BatchSize,
+		maxAttempts:    config.MaxAttempts,
+		backoff:        config.Backoff,
+		maxBackoff:     config.MaxBackoff,
+	}
+}
+
+// Start runs the dispatcher loop in background until Stop is called or ctx is done.
+func (d *Dispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := d.RunOnce(ctx); err != nil {
+				d.logger.Error("Dispatcher: run failed", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the dispatcher loop and waits for the deliveries in flight to finish.
+func (d *Dispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+
+	d.wg.Wait()
+}
+
+// RunOnce enqueues expired events once per expiry interval, fans out outbox events to webhooks and sends
+// the due deliveries of all tenants. It must not be called concurrently.
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	ctx = tenant.WithTenant(ctx, tenant.All)
+
+	if now := time.Now(); now.Sub(d.expiredAt) >= d.expiryInterval {
+		if err := d.storage.EnqueueExpiredEvents(ctx); err != nil {
+			return fmt.Errorf("RunOnce: %w", err)
+		}
+		d.expiredAt = now
+	}
+
+	if err := d.storage.EnqueueDeliveries(ctx, d.batchSize); err != nil {
+		return fmt.Errorf("RunOnce: %w", err)
+	}
+
+	deliveries, err := d.storage.ClaimDeliveries(ctx, d.batchSize, d.client.Timeout*time.Duration(d.batchSize+1))
+	if err != nil {
+		return fmt.Errorf("RunOnce: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		d.deliver(ctx, delivery)
+	}
+
+	return nil
+}
+
+// deliver sends a single delivery and records the outcome.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *postgresClient.PendingDelivery) {
+	logger := d.logger.With(
+		zap.Int("delivery_id", delivery.ID),
+		zap.Int("event_id", delivery.Event.ID),
+		zap.String("url", delivery.URL),
+	)
+
+	statusCode, err := d.send(ctx, delivery)
+	if err == nil {
+		if err = d.storage.CompleteDelivery(ctx, delivery.ID, statusCode); err != nil {
+			logger.Error("Dispatcher: cannot complete delivery", zap.Error(err))
+		}
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	giveUp := attempts >= d.maxAttempts
+	nextAttempt := time.Now().Add(Backoff(d.backoff, d.maxBackoff, attempts))
+
+	logger.Warn("Dispatcher: delivery attempt failed",
+		zap.Int("attempt", attempts),
+		zap.Bool("give_up", giveUp),
+		zap.Error(err),
+	)
+
+	if err = d.storage.FailDelivery(ctx, delivery.ID, statusCode, err.Error(), nextAttempt, giveUp); err != nil {
+		logger.Error("Dispatcher: cannot record failed delivery", zap.Error(err))
+	}
+}
+
+// send posts the signed event to the webhook and returns the response status code.
+func (d *Dispatcher) send(ctx context.Context, delivery *postgresClient.PendingDelivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, fmt.Errorf("cannot encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("cannot create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("cannot send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the "sha256=<hex>" HMAC-SHA256 signature of "<timestamp>.<body>" keyed by secret.
+// Receivers should recompute it and compare with hmac.Equal.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before the retry following the given attempt: base doubled per attempt, capped at max.
+func Backoff(base time.Duration, max time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,193 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"subscriptions/internal/api"
+	"subscriptions/internal/storage/postgresClient"
+)
+
+// failure is a failed delivery attempt recorded by fakeStorage.
+type failure struct {
+	statusCode  int
+	message     string
+	nextAttempt time.Time
+	giveUp      bool
+}
+
+// fakeStorage hands out a single delivery until it is completed or given up, counting its failed attempts.
+type fakeStorage struct {
+	delivery  postgresClient.PendingDelivery
+	completed int
+	failures  []failure
+}
+
+func (f *fakeStorage) EnqueueExpiredEvents(context.Context) error {
+	return nil
+}
+
+func (f *fakeStorage) EnqueueDeliveries(context.Context, int) error {
+	return nil
+}
+
+func (f *fakeStorage) ClaimDeliveries(context.Context, int, time.Duration) ([]*postgresClient.PendingDelivery, error) {
+	if f.completed != 0 || (len(f.failures) > 0 && f.failures[len(f.failures)-1].giveUp) {
+		return nil, nil
+	}
+
+	delivery := f.delivery
+	delivery.Attempts = len(f.failures)
+
+	return []*postgresClient.PendingDelivery{&delivery}, nil
+}
+
+func (f *fakeStorage) CompleteDelivery(_ context.Context, _ int, statusCode int) error {
+	f.completed = statusCode
+	return nil
+}
+
+func (f *fakeStorage) FailDelivery(_ context.Context, _ int, statusCode int, message string, nextAttempt time.Time, giveUp bool) error {
+	f.failures = append(f.failures, failure{statusCode: statusCode, message: message, nextAttempt: nextAttempt, giveUp: giveUp})
+	return nil
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"subscription.created"}`)
+
+	want := "sha256=ee768dda2453bd0f15c8a1daf0d7d32d64e52835cabcc4789e2f92dc5bc16ed4"
+	if got := Sign("whsec_test", "1700000000", body); got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+
+	// The timestamp is signed too, so a captured delivery cannot be replayed with another one.
+	if got := Sign("whsec_test", "1700000001", body); got == want {
+		t.Errorf("Sign() with another timestamp = %q, want another signature", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 10 * time.Second},
+		{attempt: 2, want: 20 * time.Second},
+		{attempt: 3, want: 40 * time.Second},
+		{attempt: 4, want: time.Minute},
+		{attempt: 30, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(10*time.Second, time.Minute, tt.attempt); got != tt.want {
+			t.Errorf("Backoff() of attempt %d = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestDeliveries(t *testing.T) {
+	config := Config{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 3 * time.Second, Timeout: time.Second}
+	schedule := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+
+	tests := []struct {
+		name          string
+		responses     []int
+		wantCompleted int
+		wantFailures  []int
+		wantGiveUp    bool
+	}{
+		{
+			name:          "first attempt succeeds",
+			responses:     []int{http.StatusOK},
+			wantCompleted: http.StatusOK,
+		},
+		{
+			name:          "retried until it succeeds",
+			responses:     []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusNoContent},
+			wantCompleted: http.StatusNoContent,
+			wantFailures:  []int{http.StatusInternalServerError, http.StatusBadGateway},
+		},
+		{
+			name:         "given up after the last attempt",
+			responses:    []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusNotFound},
+			wantFailures: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusNotFound},
+			wantGiveUp:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &fakeStorage{delivery: postgresClient.PendingDelivery{
+				ID:     7,
+				Secret: "whsec_test",
+				Event:  api.Event{ID: 3, Type: "subscription.created", SubscriptionID: 1},
+			}}
+
+			var mu sync.Mutex
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				body, _ := io.ReadAll(r.Body)
+				if got, want := r.Header.Get(SignatureHeader), Sign("whsec_test", r.Header.Get(TimestampHeader), body); got != want {
+					t.Errorf("signature = %q, want %q", got, want)
+				}
+
+				if r.Header.Get(DeliveryHeader) != "7" || r.Header.Get(EventHeader) != "subscription.created" {
+					t.Errorf("headers = %v, want the delivery 7 of a subscription.created event", r.Header)
+				}
+
+				w.WriteHeader(tt.responses[min(requests, len(tt.responses)-1)])
+				requests++
+			}))
+			defer server.Close()
+			storage.delivery.URL = server.URL
+
+			config := config
+			d := New(&config, storage, zap.NewNop())
+
+			// Every run sends the delivery at most once, and nothing is sent once it is completed or given up.
+			for range len(tt.responses) + 1 {
+				if err := d.RunOnce(context.Background()); err != nil {
+					t.Fatalf("RunOnce() error = %v", err)
+				}
+			}
+
+			if requests != len(tt.responses) {
+				t.Errorf("requests = %d, want %d", requests, len(tt.responses))
+			}
+
+			if storage.completed != tt.wantCompleted {
+				t.Errorf("completed with %d, want %d", storage.completed, tt.wantCompleted)
+			}
+
+			if len(storage.failures) != len(tt.wantFailures) {
+				t.Fatalf("failures = %+v, want %d", storage.failures, len(tt.wantFailures))
+			}
+
+			for i, f := range storage.failures {
+				if f.statusCode != tt.wantFailures[i] || f.message != "unexpected status code: "+strconv.Itoa(tt.wantFailures[i]) {
+					t.Errorf("failure %d = %+v, want status code %d", i+1, f, tt.wantFailures[i])
+				}
+
+				// The next attempt follows the backoff schedule of the attempt.
+				if delay := time.Until(f.nextAttempt); delay > schedule[i] || delay < schedule[i]-time.Second/2 {
+					t.Errorf("failure %d retried in %s, want %s", i+1, delay, schedule[i])
+				}
+
+				if giveUp := tt.wantGiveUp && i == len(storage.failures)-1; f.giveUp != giveUp {
+					t.Errorf("failure %d give up = %v, want %v", i+1, f.giveUp, giveUp)
+				}
+			}
+		})
+	}
+}
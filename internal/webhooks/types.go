@@ -0,0 +1,64 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"subscriptions/internal/storage/postgresClient"
+)
+
+const (
+	// DefaultInterval defines how often the dispatcher polls the outbox and pending deliveries.
+	DefaultInterval = 5 * time.Second
+
+	// DefaultExpiryInterval defines how often the dispatcher writes the events of the expired subscriptions to the outbox.
+	DefaultExpiryInterval = time.Hour
+
+	// DefaultBatchSize defines how many events and deliveries are processed per poll.
+	DefaultBatchSize = 100
+
+	// DefaultMaxAttempts defines how many times a delivery is attempted before it is marked as failed.
+	DefaultMaxAttempts = 8
+
+	// DefaultBackoff defines the delay before the first retry, doubled after every failed attempt.
+	DefaultBackoff = 10 * time.Second
+
+	// DefaultMaxBackoff caps the delay between retries.
+	DefaultMaxBackoff = time.Hour
+
+	// DefaultTimeout defines the maximum duration of a single delivery request.
+	DefaultTimeout = 10 * time.Second
+
+	// SignatureHeader carries the HMAC-SHA256 signature of "<timestamp>.<body>" keyed by the webhook secret.
+	SignatureHeader = "X-Webhook-Signature"
+
+	// TimestampHeader carries the unix time the delivery was signed at.
+	TimestampHeader = "X-Webhook-Timestamp"
+
+	// EventHeader carries the event type of the delivery.
+	EventHeader = "X-Webhook-Event"
+
+	// DeliveryHeader carries the id of the delivery, which stays the same across retries.
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// Config defines the webhook dispatcher settings.
+type Config struct {
+	Enabled        bool          `env:"WEBHOOKS_ENABLED"`
+	Interval       time.Duration `env:"WEBHOOKS_INTERVAL" env-default:"5s"`
+	ExpiryInterval time.Duration `env:"WEBHOOKS_EXPIRY_INTERVAL" env-default:"1h"`
+	BatchSize      int           `env:"WEBHOOKS_BATCH_SIZE" env-default:"100"`
+	MaxAttempts    int           `env:"WEBHOOKS_MAX_ATTEMPTS" env-default:"8"`
+	Backoff        time.Duration `env:"WEBHOOKS_BACKOFF" env-default:"10s"`
+	MaxBackoff     time.Duration `env:"WEBHOOKS_MAX_BACKOFF" env-default:"1h"`
+	Timeout        time.Duration `env:"WEBHOOKS_TIMEOUT" env-default:"10s"`
+}
+
+// Storage defines the storage operations required by the Dispatcher.
+type Storage interface {
+	EnqueueExpiredEvents(context.Context) error
+	EnqueueDeliveries(context.Context, int) error
+	ClaimDeliveries(context.Context, int, time.Duration) ([]*postgresClient.PendingDelivery, error)
+	CompleteDelivery(context.Context, int, int) error
+	FailDelivery(context.Context, int, int, string, time.Time, bool) error
+}